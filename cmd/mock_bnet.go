@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/sbraitsch/plotter/internal/middleware"
+	"github.com/sbraitsch/plotter/internal/mockbnet"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mockBnetPort     string
+	mockBnetFixtures string
+)
+
+// mockBnetCmd serves canned Battle.net responses for local development
+var mockBnetCmd = &cobra.Command{
+	Use:   "mock-bnet",
+	Short: "Serve a fake Battle.net for local development",
+	Long: `Serve canned Battle.net OAuth, userinfo, profile, character and roster responses.
+	Point the server at it with BNET_OAUTH_URL and BNET_API_URL to run the whole login to lock flow offline.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var fixtures fs.FS = mockbnet.DefaultFixtures()
+		if mockBnetFixtures != "" {
+			fixtures = os.DirFS(mockBnetFixtures)
+		}
+
+		srv, err := mockbnet.NewServer(fixtures)
+		if err != nil {
+			log.Fatalf("Failed to load fixtures: %v", err)
+		}
+
+		base := fmt.Sprintf("http://localhost:%s", mockBnetPort)
+		log.Printf("Mock Battle.net listening on port :%s\n", mockBnetPort)
+		log.Printf("Start the server with BNET_OAUTH_URL=%s BNET_API_URL=%s\n", base, base)
+		if err := http.ListenAndServe(":"+mockBnetPort, middleware.LoggingMiddleware(srv.Routes())); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	mockBnetCmd.Flags().StringVar(&mockBnetPort, "port", "9090", "port to listen on")
+	mockBnetCmd.Flags().StringVar(&mockBnetFixtures, "fixtures", "", "fixture directory with users.json and API responses by path (default: built-in accounts)")
+	rootCmd.AddCommand(mockBnetCmd)
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/sbraitsch/plotter/internal/model"
+	"github.com/spf13/cobra"
+)
+
+var (
+	optimizeIn        string
+	optimizeOut       string
+	optimizePlots     string
+	optimizeObjective string
+	optimizeSeed      int64
+	optimizeStable    bool
+)
+
+// optimizeCmd solves a downloaded community file without database or Battle.net access
+var optimizeCmd = &cobra.Command{
+	Use:   "optimize",
+	Short: "Optimize a downloaded community_data.json offline",
+	Long: `Optimize a community_data.json as produced by /community/download.
+	The result is written in the same format, so it can be uploaded again.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		data := &model.FullCommunityData{}
+		if err := readJSON(optimizeIn, data); err != nil {
+			log.Fatalf("Failed to read community data: %v", err)
+		}
+
+		if optimizePlots != "" {
+			data.Plots = nil
+			if err := readJSON(optimizePlots, &data.Plots); err != nil {
+				log.Fatalf("Failed to read plot catalog: %v", err)
+			}
+		}
+		if len(data.Plots) == 0 {
+			log.Fatal("No plot catalog in input, pass one with --plots")
+		}
+
+		options := model.OptimizeOptions{Stable: optimizeStable}
+		if optimizeObjective != "" {
+			objective, err := model.ParseObjective(optimizeObjective)
+			if err != nil {
+				log.Fatal(err)
+			}
+			options.Objective = objective
+		}
+
+		community := data.CommunityData()
+		if optimizeSeed != 0 {
+			// member order decides between equally good solutions
+			rng := rand.New(rand.NewSource(optimizeSeed))
+			rng.Shuffle(len(community.Members), func(i, j int) {
+				community.Members[i], community.Members[j] = community.Members[j], community.Members[i]
+			})
+		}
+
+		result := community.Optimize(options)
+
+		byBattletag := make(map[string]model.Assignment, len(result.Assignments))
+		for _, a := range result.Assignments {
+			byBattletag[a.Battletag] = a
+		}
+		for i, member := range data.Members {
+			data.Members[i].Assignment = byBattletag[member.Assignment.Battletag]
+			data.Members[i].Assignment.Battletag = member.Assignment.Battletag
+			data.Members[i].Assignment.Character = member.Assignment.Character
+		}
+
+		if optimizeOut != "" {
+			if err := writeJSON(optimizeOut, data); err != nil {
+				log.Fatalf("Failed to write assignments: %v", err)
+			}
+		}
+
+		printSummary(result)
+	},
+}
+
+func readJSON(path string, v any) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func writeJSON(path string, v any) error {
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0o644)
+}
+
+func printSummary(result *model.OptimizationResult) {
+	assignments := append([]model.Assignment(nil), result.Assignments...)
+	sort.Slice(assignments, func(i, j int) bool {
+		if assignments[i].Neighborhood != assignments[j].Neighborhood {
+			return assignments[i].Neighborhood < assignments[j].Neighborhood
+		}
+		return assignments[i].Plot < assignments[j].Plot
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NEIGHBORHOOD\tPLOT\tRANK\tSOURCE\tBATTLETAG\tCHARACTER")
+	for _, a := range assignments {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%s\t%s\t%s\n", a.Neighborhood, a.Plot, a.Score, a.Source, a.Battletag, a.Character)
+	}
+	w.Flush()
+
+	stats := result.Stats
+	fmt.Printf("\nobjective %s: %d/%d members placed, rank sum %d, mean %.2f, worst %d, unranked %d\n",
+		result.Objective, stats.Assigned, stats.Members, stats.RankSum, stats.MeanRank, stats.MaxRank, stats.Unranked)
+
+	for _, move := range result.Moves {
+		fmt.Printf("moved %s from %d:%d to %d:%d: %s\n",
+			move.Battletag, move.FromNeighborhood, move.FromPlot, move.ToNeighborhood, move.ToPlot, move.Reason)
+	}
+}
+
+func init() {
+	optimizeCmd.Flags().StringVar(&optimizeIn, "in", "community_data.json", "community data as downloaded from /community/download")
+	optimizeCmd.Flags().StringVar(&optimizeOut, "out", "", "where to write the optimized community data")
+	optimizeCmd.Flags().StringVar(&optimizePlots, "plots", "", "plot catalog as served by /plots, overrides the one in --in")
+	optimizeCmd.Flags().StringVar(&optimizeObjective, "objective", "", "sum, minmax, lexminmax or squares (default: the community's setting)")
+	optimizeCmd.Flags().Int64Var(&optimizeSeed, "seed", 0, "shuffle members with this seed to explore tie-breaks, 0 keeps the file order")
+	optimizeCmd.Flags().BoolVar(&optimizeStable, "stable", false, "penalize moving members away from the assignments in --in")
+	rootCmd.AddCommand(optimizeCmd)
+}
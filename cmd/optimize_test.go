@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected optimize results in testdata")
+
+// TestOptimizeFixture runs the optimize command on checked-in communities and compares
+// the written files with the expected ones. Run with -update after an intended change.
+// The contested community is solved differently by minmax and by a shuffled member order,
+// so its results must differ from the plain solve.
+func TestOptimizeFixture(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		args []string
+	}{
+		{name: "optimized", in: "community_data", args: []string{"--stable=false", "--objective", "", "--seed", "0"}},
+		{name: "stable", in: "community_data", args: []string{"--stable", "--objective", "", "--seed", "0"}},
+		{name: "contested", in: "contested_data", args: []string{"--stable=false", "--objective", "sum", "--seed", "0"}},
+		{name: "minmax", in: "contested_data", args: []string{"--stable=false", "--objective", "minmax", "--seed", "0"}},
+		{name: "seeded", in: "contested_data", args: []string{"--stable=false", "--objective", "sum", "--seed", "1"}},
+	}
+
+	results := make(map[string][]byte, len(cases))
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "out.json")
+			args := append([]string{"optimize", "--in", filepath.Join("testdata", c.in+".json"), "--out", out}, c.args...)
+			rootCmd.SetArgs(args)
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			results[c.name] = got
+			expected := filepath.Join("testdata", c.name+".json")
+			if *update {
+				if err := os.WriteFile(expected, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(expected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("optimize %v differs from %s:\n%s", c.args, expected, got)
+			}
+		})
+	}
+
+	for _, name := range []string{"minmax", "seeded"} {
+		if bytes.Equal(results[name], results["contested"]) {
+			t.Errorf("%s solved the contested community like the plain sum, the flag had no effect", name)
+		}
+	}
+}
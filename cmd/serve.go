@@ -13,6 +13,7 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/sbraitsch/plotter/internal/api"
 	"github.com/sbraitsch/plotter/internal/config"
+	"github.com/sbraitsch/plotter/internal/service"
 	"github.com/sbraitsch/plotter/internal/storage"
 	"github.com/spf13/cobra"
 )
@@ -32,6 +33,10 @@ var serveCmd = &cobra.Command{
 		storage.RunMigrations(cfg.DbUrl)
 
 		srv := api.NewServer(pool, cfg)
+		store := storage.NewStorageClient(pool)
+		service.StartTokenRefresher(ctx, store, srv.Bnet, service.TOKEN_REFRESH_INTERVAL)
+		service.StartRosterSyncer(ctx, service.NewRosterService(store, srv.Bnet), service.ROSTER_SYNC_INTERVAL)
+		service.StartDeadlineScheduler(ctx, service.NewCommunityService(store, srv.Bnet), service.DEADLINE_CHECK_INTERVAL)
 		addr := fmt.Sprintf(":%s", cfg.Port)
 
 		log.Printf("Server listening on port %s\n", addr)
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/sbraitsch/plotter/internal/config"
+	"github.com/sbraitsch/plotter/internal/service"
+	"github.com/sbraitsch/plotter/internal/service/oauth"
+	"github.com/sbraitsch/plotter/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+// syncRostersCmd runs the roster sync the server otherwise runs in the background
+var syncRostersCmd = &cobra.Command{
+	Use:   "sync-rosters",
+	Short: "Sync guild ranks and membership from Battle.net",
+	Long: `Re-fetch the guild roster of every community with members.
+	Ranks are updated and members no longer in the guild are flagged as departed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		_ = godotenv.Load()
+		cfg := config.Load()
+		ctx := context.Background()
+
+		pool := storage.ConnectWithRetry(ctx, cfg.DbUrl, 10, 2*time.Second)
+		defer pool.Close()
+
+		storage.RunMigrations(cfg.DbUrl)
+
+		bnet := oauth.NewProvider(cfg.ClientId, cfg.ClientSecret, os.Getenv("REDIRECT_URL"), cfg.Bnet)
+		reports, err := service.NewRosterService(storage.NewStorageClient(pool), bnet).SyncAll(ctx)
+		if err != nil {
+			log.Fatalf("Failed to sync rosters: %v", err)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "GUILD\tBATTLETAG\tCHAR\tCHANGE\tRANK")
+		for _, report := range reports {
+			guild := fmt.Sprintf("%s-%s (%s)", report.Name, report.Realm, report.Region)
+			if report.Error != "" {
+				fmt.Fprintf(tw, "%s\t\t\tfailed: %s\t\n", guild, report.Error)
+				continue
+			}
+			if len(report.Changes) == 0 {
+				fmt.Fprintf(tw, "%s\t\t\tunchanged\t\n", guild)
+			}
+			for _, change := range report.Changes {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d -> %d\n",
+					guild, change.Battletag, change.Character, change.Kind, change.OldRank, change.NewRank)
+			}
+		}
+		tw.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncRostersCmd)
+}
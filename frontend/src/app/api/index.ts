@@ -1,18 +1,36 @@
+// the version of each community as last read, edits send it back so they can't overwrite
+// changes made in the meantime
+const versions = new Map<string, string>();
+
 export async function fetchWithAuth<T = void>(
   url: string,
   options: RequestInit = {},
 ): Promise<T> {
   const userId = localStorage.getItem("session_token");
+  const communityId = localStorage.getItem("community_id");
+  const version = versions.get(communityId ?? "");
+  const isEdit = (options.method ?? "GET") !== "GET";
   const headers: HeadersInit = {
     "Content-Type": "application/json",
     ...(options.headers || {}),
     ...(userId ? { "X-Token": userId } : {}),
+    ...(communityId ? { "X-Community": communityId } : {}),
+    ...(isEdit && version ? { "If-Match": version } : {}),
   };
 
   const res = await fetch(url, { ...options, headers });
 
+  // after a conflict the page shows outdated data, edits stay refused until it is loaded again
+  const etag = res.headers.get("ETag");
+  if (etag && res.status !== 409) {
+    versions.set(communityId ?? "", etag);
+  }
+
   if (!res.ok) {
     const errorText = await res.text();
+    if (res.status === 401) {
+      redirectIfReauthRequired(errorText);
+    }
     throw new Error(errorText || res.statusText);
   }
 
@@ -23,6 +41,22 @@ export async function fetchWithAuth<T = void>(
   return (await res.json()) as T;
 }
 
+// the Battle.net token behind the session is gone, only a new login brings it back
+function redirectIfReauthRequired(body: string) {
+  try {
+    const { error, loginUrl } = JSON.parse(body);
+    if (error !== "reauth_required" || !loginUrl) return;
+    const login = new URL(loginUrl);
+    login.searchParams.set(
+      "returnTo",
+      window.location.pathname + window.location.search,
+    );
+    window.location.href = login.toString();
+  } catch {
+    // plain text errors are handled by the caller
+  }
+}
+
 export const BASE_URL =
   typeof window !== "undefined" && window.location.hostname === "localhost"
     ? "http://localhost:8080"
@@ -1,4 +1,5 @@
 import { BASE_URL, fetchWithAuth } from ".";
+import { CommunityStatus } from "./validate";
 
 export interface Assignment {
   btag: string;
@@ -7,6 +8,10 @@ export interface Assignment {
   score: number;
 }
 
+export interface OptimizationResult {
+  assignments: Assignment[];
+}
+
 export interface OverwriteAssignment {
   btag: string;
   char: string;
@@ -16,8 +21,10 @@ export interface OverwriteAssignment {
 export async function getOptimizedAssignments(): Promise<Assignment[]> {
   try {
     const url = `${BASE_URL}/community/optimize`;
-    const data = await fetchWithAuth<Assignment[]>(url);
-    return data;
+    const data = await fetchWithAuth<OptimizationResult>(url, {
+      method: "POST",
+    });
+    return data.assignments;
   } catch (err) {
     throw new Error("Optimizer failed");
   }
@@ -35,6 +42,16 @@ export async function finalizeAssignments(): Promise<void> {
   }
 }
 
+export async function setCommunityStatus(
+  status: CommunityStatus,
+): Promise<void> {
+  const url = `${BASE_URL}/community/status`;
+  return fetchWithAuth(url, {
+    method: "PUT",
+    body: JSON.stringify({ status }),
+  });
+}
+
 export async function downloadAssignmentData(): Promise<void> {
   try {
     const userId = localStorage.getItem("session_token");
@@ -102,10 +119,10 @@ export async function overwriteSingleAssignment(
 export async function optimizeAndLock(): Promise<Assignment[]> {
   try {
     const url = `${BASE_URL}/community/lock`;
-    const data = await fetchWithAuth<Assignment[]>(url, {
+    const data = await fetchWithAuth<OptimizationResult | undefined>(url, {
       method: "POST",
     });
-    return data;
+    return data?.assignments ?? [];
   } catch (err) {
     throw new Error("Optimizer failed");
   }
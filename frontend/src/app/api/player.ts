@@ -1,4 +1,5 @@
 import { BASE_URL, fetchWithAuth } from "./index";
+import { JoinRequest } from "./requests";
 
 interface CommunityData {
   members: PlayerData[];
@@ -24,6 +25,8 @@ export interface PlotEntry {
 export interface CommunitySettings {
   officerRank: number;
   memberRank: number;
+  joinRequests: boolean;
+  preferenceDeadline?: string;
 }
 
 export async function getCommunityData(): Promise<PlayerData[]> {
@@ -41,6 +44,40 @@ export async function getCommunitySettings(): Promise<CommunitySettings> {
   return data;
 }
 
+export interface Character {
+  name: string;
+  rank: number;
+}
+
+export interface JoinedCommunity {
+  char: string;
+  characters: Character[];
+  request?: JoinRequest;
+}
+
+export async function setCharacter(char: string): Promise<string> {
+  const url = `${BASE_URL}/user/character`;
+  return fetchWithAuth<string>(url, {
+    method: "POST",
+    body: JSON.stringify({ char }),
+  });
+}
+
+export async function setPreferenceDeadline(
+  deadline: string | null,
+): Promise<void> {
+  const url = `${BASE_URL}/community/deadline`;
+  return fetchWithAuth(url, {
+    method: "PUT",
+    body: JSON.stringify({ deadline }),
+  });
+}
+
+export async function leaveCommunity(): Promise<void> {
+  const url = `${BASE_URL}/community/leave`;
+  return fetchWithAuth(url, { method: "POST" });
+}
+
 export async function updatePlayerData(
   update: PlayerUpdate,
 ): Promise<PlayerData[]> {
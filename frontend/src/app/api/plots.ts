@@ -0,0 +1,24 @@
+import { BASE_URL, fetchWithAuth } from "./index";
+
+export interface Plot {
+  id: number;
+  label: string;
+  xCoord: number;
+  yCoord: number;
+  size: string;
+  type: string;
+  tags: string[];
+}
+
+let catalog: Promise<Plot[]> | undefined;
+
+// the catalog only changes with a deployment, so it is loaded once per page
+export function getPlots(): Promise<Plot[]> {
+  if (!catalog) {
+    catalog = fetchWithAuth<Plot[]>(`${BASE_URL}/plots`).catch((err) => {
+      catalog = undefined;
+      throw err;
+    });
+  }
+  return catalog;
+}
@@ -0,0 +1,22 @@
+import { BASE_URL, fetchWithAuth } from "./index";
+
+export interface JoinRequest {
+  battletag: string;
+  char?: string;
+  rank?: number;
+  status: "pending" | "approved" | "denied";
+  requestedAt: string;
+}
+
+export async function getJoinRequests(): Promise<JoinRequest[]> {
+  const url = `${BASE_URL}/community/requests`;
+  return fetchWithAuth<JoinRequest[]>(url);
+}
+
+export async function decideJoinRequest(
+  battletag: string,
+  approve: boolean,
+): Promise<void> {
+  const url = `${BASE_URL}/community/requests/${encodeURIComponent(battletag)}/${approve ? "approve" : "deny"}`;
+  return fetchWithAuth(url, { method: "POST" });
+}
@@ -0,0 +1,53 @@
+import { BASE_URL, fetchWithAuth } from "./index";
+import { Assignment } from "./optimizer";
+
+export interface AssignmentRevision {
+  revision: number;
+  source: "lock" | "upload" | "edit" | "restore" | "removal";
+  createdBy?: string;
+  restoredFrom?: number;
+  createdAt: string;
+  assignments?: Assignment[];
+}
+
+export interface RevisionChange {
+  btag: string;
+  char: string;
+  kind: "added" | "removed" | "moved";
+  // "neighborhood:plot"
+  from?: string;
+  to?: string;
+}
+
+export interface RevisionDiff {
+  from: number;
+  to: number;
+  changes: RevisionChange[];
+}
+
+export async function getRevisions(): Promise<AssignmentRevision[]> {
+  const url = `${BASE_URL}/community/revisions`;
+  return fetchWithAuth<AssignmentRevision[]>(url);
+}
+
+export async function getRevision(
+  revision: number,
+): Promise<AssignmentRevision> {
+  const url = `${BASE_URL}/community/revisions/${revision}`;
+  return fetchWithAuth<AssignmentRevision>(url);
+}
+
+export async function diffRevisions(
+  from: number,
+  to: number,
+): Promise<RevisionDiff> {
+  const url = `${BASE_URL}/community/revisions/diff?from=${from}&to=${to}`;
+  return fetchWithAuth<RevisionDiff>(url);
+}
+
+export async function restoreRevision(
+  revision: number,
+): Promise<AssignmentRevision> {
+  const url = `${BASE_URL}/community/revisions/${revision}/restore`;
+  return fetchWithAuth<AssignmentRevision>(url, { method: "POST" });
+}
@@ -0,0 +1,35 @@
+import { BASE_URL, fetchWithAuth } from "./index";
+import { Region } from "./validate";
+
+export interface RosterChange {
+  battletag: string;
+  char: string;
+  kind: "rank" | "departed" | "returned";
+  oldRank: number;
+  newRank: number;
+}
+
+export interface RosterSyncReport {
+  communityId: string;
+  name: string;
+  realm: string;
+  region: Region;
+  syncedAt: string;
+  members: number;
+  changes: RosterChange[];
+  error?: string;
+}
+
+export async function syncRoster(): Promise<RosterSyncReport> {
+  const url = `${BASE_URL}/community/sync`;
+  return fetchWithAuth<RosterSyncReport>(url, { method: "POST" });
+}
+
+// block keeps the member from joining again for two weeks
+export async function removeMember(
+  battletag: string,
+  block = false,
+): Promise<void> {
+  const url = `${BASE_URL}/community/members/${encodeURIComponent(battletag)}?block=${block}`;
+  return fetchWithAuth(url, { method: "DELETE" });
+}
@@ -0,0 +1,25 @@
+import { BASE_URL, fetchWithAuth } from "./index";
+
+export interface Session {
+  id: number;
+  userAgent: string;
+  createdAt: string;
+  lastSeenAt: string;
+  expiresAt: string;
+  current: boolean;
+}
+
+export async function getSessions(): Promise<Session[]> {
+  const url = `${BASE_URL}/user/sessions`;
+  return fetchWithAuth<Session[]>(url);
+}
+
+export async function logout(): Promise<void> {
+  const url = `${BASE_URL}/auth/logout`;
+  await fetchWithAuth(url, { method: "POST" });
+}
+
+export async function logoutEverywhere(): Promise<void> {
+  const url = `${BASE_URL}/auth/logout-all`;
+  await fetchWithAuth(url, { method: "POST" });
+}
@@ -5,17 +5,50 @@ export interface ValidateResponse {
   char: string;
   note: string;
   isAdmin: boolean;
+  departed: boolean;
+  characters: string[];
+  memberships: Membership[];
+  region: Region;
+  bnet: BnetTokenStatus;
   community: Community;
 }
 
+export interface BnetTokenStatus {
+  expiry: string;
+  refreshable: boolean;
+  reauthRequired: boolean;
+}
+
+export interface Membership {
+  community: Community;
+  char: string;
+  isAdmin: boolean;
+  departed: boolean;
+}
+
+export type Region = "eu" | "us" | "kr" | "tw";
+
 export type Community = {
   id: string;
   name: string;
   realm: string;
-  locked: boolean;
-  finalized: boolean;
+  region: Region;
+  status: CommunityStatus;
+  preferences: PreferenceWindow;
 };
 
+export type CommunityStatus =
+  | "collecting"
+  | "locked"
+  | "finalized"
+  | "archived";
+
+export interface PreferenceWindow {
+  status: "open" | "closed";
+  deadline?: string;
+  secondsLeft: number;
+}
+
 export async function validateSession(): Promise<ValidateResponse> {
   const url = `${BASE_URL}/user/validate`;
   const data = await fetchWithAuth<ValidateResponse>(url);
@@ -15,7 +15,10 @@ function AuthSuccessInner() {
     }
 
     localStorage.setItem("session_token", token);
-    router.replace("/");
+
+    // the backend only hands back paths on this site
+    const returnTo = searchParams.get("returnTo");
+    router.replace(returnTo?.startsWith("/") ? returnTo : "/");
   }, [router, searchParams]);
 
   return <div>Logging in...</div>;
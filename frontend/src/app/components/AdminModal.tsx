@@ -1,13 +1,32 @@
 import { useState, ChangeEvent, useEffect } from "react";
 import "@/styles/AdminModal.css";
 import { getCommunitySettings } from "../api/player";
+import {
+  decideJoinRequest,
+  getJoinRequests,
+  JoinRequest,
+} from "../api/requests";
 
 interface AdminModalProps {
   isOpen: boolean;
   onClose: () => void;
-  onSubmit: (admin: number, member: number) => void;
+  onSubmit: (
+    admin: number,
+    member: number,
+    joinRequests: boolean,
+    deadline?: string | null,
+  ) => void;
 }
 
+// datetime-local inputs take local time without a zone
+const toLocalInput = (iso?: string) => {
+  if (!iso) return "";
+  const date = new Date(iso);
+  return new Date(date.getTime() - date.getTimezoneOffset() * 60000)
+    .toISOString()
+    .slice(0, 16);
+};
+
 export default function AdminModal({
   isOpen,
   onClose,
@@ -15,14 +34,23 @@ export default function AdminModal({
 }: AdminModalProps) {
   const [adminValue, setAdminValue] = useState("");
   const [memberValue, setMemberValue] = useState("");
+  const [joinRequests, setJoinRequests] = useState(false);
+  const [deadline, setDeadline] = useState("");
+  const [savedDeadline, setSavedDeadline] = useState("");
+  const [requests, setRequests] = useState<JoinRequest[]>([]);
 
   useEffect(() => {
     if (!isOpen) return;
     async function fetchData() {
       try {
-        const { officerRank, memberRank } = await getCommunitySettings();
+        const { officerRank, memberRank, joinRequests, preferenceDeadline } =
+          await getCommunitySettings();
         setAdminValue(officerRank.toString());
         setMemberValue(memberRank.toString());
+        setJoinRequests(joinRequests);
+        setDeadline(toLocalInput(preferenceDeadline));
+        setSavedDeadline(toLocalInput(preferenceDeadline));
+        setRequests(await getJoinRequests());
       } catch (err: any) {
         console.error(err);
       }
@@ -44,7 +72,23 @@ export default function AdminModal({
     const admin = Number(adminValue);
     const member = Number(memberValue);
     if (!isNaN(admin) && !isNaN(member)) {
-      onSubmit(admin, member);
+      // resending an unchanged deadline would lock the community again
+      const changed =
+        deadline === savedDeadline
+          ? undefined
+          : deadline
+            ? new Date(deadline).toISOString()
+            : null;
+      onSubmit(admin, member, joinRequests, changed);
+    }
+  };
+
+  const handleDecision = async (battletag: string, approve: boolean) => {
+    try {
+      await decideJoinRequest(battletag, approve);
+      setRequests((prev) => prev.filter((r) => r.battletag !== battletag));
+    } catch (err: any) {
+      console.error(err);
     }
   };
 
@@ -76,6 +120,51 @@ export default function AdminModal({
             min={0}
           />
         </div>
+        <div className="modal-field">
+          <label htmlFor="requests">
+            <input
+              id="requests"
+              type="checkbox"
+              checked={joinRequests}
+              onChange={(e) => setJoinRequests(e.target.checked)}
+            />{" "}
+            ...or ask an officer to let them in
+          </label>
+        </div>
+        <div className="modal-field">
+          <label htmlFor="deadline">Preferences close and lock on: </label>
+          <input
+            id="deadline"
+            type="datetime-local"
+            value={deadline}
+            onChange={(e) => setDeadline(e.target.value)}
+          />
+        </div>
+        {requests.length > 0 && (
+          <div className="modal-field">
+            <label>Waiting to join:</label>
+            {requests.map((r) => (
+              <div key={r.battletag} className="join-request">
+                <span>
+                  {r.char || r.battletag}
+                  {r.rank !== undefined ? ` (rank ${r.rank})` : " (not in guild)"}
+                </span>
+                <button
+                  className="btn"
+                  onClick={() => handleDecision(r.battletag, true)}
+                >
+                  Approve
+                </button>
+                <button
+                  className="btn"
+                  onClick={() => handleDecision(r.battletag, false)}
+                >
+                  Deny
+                </button>
+              </div>
+            ))}
+          </div>
+        )}
 
         <button className="btn submit-btn" onClick={handleSubmit}>
           Submit
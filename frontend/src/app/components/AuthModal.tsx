@@ -3,18 +3,47 @@
 import React, { useState, FormEvent } from "react";
 import "@/styles/AuthModal.css";
 import { BASE_URL } from "../api";
+import { Region } from "../api/validate";
+
+const REGIONS: { value: Region; label: string }[] = [
+  { value: "eu", label: "Europe" },
+  { value: "us", label: "Americas" },
+  { value: "kr", label: "Korea" },
+  { value: "tw", label: "Taiwan" },
+];
 
 const AuthModal: React.FC = () => {
   const [loading, setLoading] = useState(false);
+  const [region, setRegion] = useState<Region>(() =>
+    typeof window === "undefined"
+      ? "eu"
+      : ((localStorage.getItem("region") as Region) ?? "eu"),
+  );
 
   const handleLogin = () => {
-    const url = `${BASE_URL}/auth/bnet/login`;
+    localStorage.setItem("region", region);
+    const params = new URLSearchParams({
+      region,
+      returnTo: window.location.pathname + window.location.search,
+    });
+    const url = `${BASE_URL}/auth/bnet/login?${params}`;
     setLoading(true);
     window.location.href = url;
   };
 
   return (
     <div className="bnet-login-container">
+      <select
+        className="bnet-region-select"
+        value={region}
+        onChange={(e) => setRegion(e.target.value as Region)}
+      >
+        {REGIONS.map((r) => (
+          <option key={r.value} value={r.value}>
+            {r.label}
+          </option>
+        ))}
+      </select>
       <button onClick={handleLogin} className="bnet-login-btn">
         <img src="/bnet.svg" alt="Battle.net" className="bnet-icon" />
         Log in with Battle.net
@@ -4,16 +4,18 @@ import React, { useState, useEffect } from "react";
 import "@/styles/CommunitySelection.css";
 import { BASE_URL, fetchWithAuth } from "../api";
 import { useAuth } from "../context/AuthContext";
+import { CommunityStatus, Region } from "../api/validate";
+import { JoinedCommunity } from "../api/player";
 
 interface CommunityResponse {
   id: string;
   name: string;
   realm: string;
-  locked: boolean;
-  finalized: boolean;
+  region: Region;
+  status: CommunityStatus;
 }
 const CommunitySelection: React.FC = () => {
-  const { setUser } = useAuth();
+  const { user, switchCommunity, setJoining } = useAuth();
   const [options, setOptions] = useState<CommunityResponse[]>([]);
   const [selected, setSelected] = useState<CommunityResponse | undefined>(
     undefined,
@@ -21,6 +23,7 @@ const CommunitySelection: React.FC = () => {
 
   const [loading, setLoading] = useState<boolean>(false);
   const [error, setError] = useState<string | null>(null);
+  const [requested, setRequested] = useState<string | null>(null);
 
   useEffect(() => {
     const fetchOptions = async () => {
@@ -49,27 +52,22 @@ const CommunitySelection: React.FC = () => {
   const handleSubmit = async (com: CommunityResponse) => {
     localStorage.setItem("showInfoModal", "yurr");
     try {
-      const char = await fetchWithAuth<string>(
+      const joined = await fetchWithAuth<JoinedCommunity>(
         `${BASE_URL}/community/join/${com.id}`,
         {
           method: "POST",
         },
       );
 
-      setUser((prev) => {
-        if (!prev) return prev;
-        return {
-          ...prev,
-          char: char,
-          community: {
-            id: com.id,
-            name: com.name,
-            realm: com.realm,
-            locked: com.locked,
-            finalized: com.finalized,
-          },
-        };
-      });
+      // below the member rank an officer has to let you in first
+      if (joined.request) {
+        setRequested(
+          `Your request to join ${com.name} is waiting for an officer. Come back once it is approved.`,
+        );
+        return;
+      }
+
+      await switchCommunity(com.id);
     } catch (err: unknown) {
       if (err instanceof Error) {
         setError(err.message);
@@ -83,23 +81,29 @@ const CommunitySelection: React.FC = () => {
     <div className="bnet-list-wrapper">
       <h2 className="bnet-list-title">Choose your Community</h2>
       <p className="bnet-list-subtitle">
-        Don't fuck this up. You won't be able to change it later.
+        Your plot preferences are kept separately for every community you join.
       </p>
 
       {loading && <p>Loading...</p>}
       {error && <p className="error">{error}</p>}
+      {requested && <p className="bnet-list-subtitle">{requested}</p>}
 
       {!loading && !error && (
         <ul className="bnet-list">
-          {options.map((opt) => (
-            <li
-              key={opt.id}
-              className={`bnet-list-item ${selected?.id === opt.id ? "selected" : ""}`}
-              onClick={() => handleSelect(opt)}
-            >
-              {opt.name}
-            </li>
-          ))}
+          {options
+            .filter(
+              (opt) =>
+                !user?.memberships?.some((m) => m.community.id === opt.id),
+            )
+            .map((opt) => (
+              <li
+                key={opt.id}
+                className={`bnet-list-item ${selected?.id === opt.id ? "selected" : ""}`}
+                onClick={() => handleSelect(opt)}
+              >
+                {opt.name}
+              </li>
+            ))}
         </ul>
       )}
       <div className="spacer"></div>
@@ -112,6 +116,11 @@ const CommunitySelection: React.FC = () => {
       >
         Continue
       </button>
+      {user?.community.id && (
+        <button className="bnet-submit-btn" onClick={() => setJoining(false)}>
+          Back
+        </button>
+      )}
     </div>
   );
 };
@@ -1,6 +1,13 @@
 import React, { useState, useEffect } from "react";
 import "@/styles/ControlPanel.css";
-import { PlayerData, PlayerUpdate, updatePlayerData } from "../api/player";
+import {
+  PlayerData,
+  PlayerUpdate,
+  leaveCommunity,
+  setCharacter,
+  setPreferenceDeadline,
+  updatePlayerData,
+} from "../api/player";
 import {
   CloudUpload,
   Lock,
@@ -16,6 +23,10 @@ import {
   Upload,
   NotebookPen,
   MapPinCheck,
+  Archive,
+  LogOut,
+  DoorOpen,
+  UsersRound,
 } from "lucide-react";
 import PlotGrid from "./PlotGrid";
 import { useAuth, User } from "../context/AuthContext";
@@ -30,8 +41,16 @@ import {
   getOptimizedAssignments,
   optimizeAndLock,
   overwriteAssignments,
+  setCommunityStatus,
 } from "../api/optimizer";
-import { deslugRealm } from "../utils";
+import { syncRoster } from "../api/roster";
+import {
+  deslugRealm,
+  formatCountdown,
+  isFinalized,
+  isLocked,
+  preferencesClosed,
+} from "../utils";
 import InfoModal from "./InfoModal";
 
 interface ControlPanelProps {
@@ -57,22 +76,36 @@ export default function ControlPanel({
   contextDirty,
   assignment,
 }: ControlPanelProps) {
-  const { setUser } = useAuth();
+  const { setUser, logout, validateKnownUser, switchCommunity, setJoining } =
+    useAuth();
   const [showNotification, setShowNotification] = useState(false);
   const [isAdminModalOpen, setIsAdminModalOpen] = useState(false);
   const [isClearModalOpen, setIsClearModalOpen] = useState(false);
   const [isInfoModalOpen, setIsInfoModalOpen] = useState(false);
   const [notificationContent, setNotificationContent] = useState("");
-  const [isPreviewing, setIsPreviewing] = useState(user?.community.locked);
+  const [isPreviewing, setIsPreviewing] = useState(isLocked(user?.community));
   const [note, setNote] = useState(user?.note || "");
   const [noteEdited, setNoteEdited] = useState(false);
 
-  const handleAdminModalSubmit = async (admin: number, member: number) => {
+  const handleAdminModalSubmit = async (
+    admin: number,
+    member: number,
+    joinRequests: boolean,
+    deadline?: string | null,
+  ) => {
     try {
       await fetchWithAuth(`${BASE_URL}/community/config`, {
         method: "POST",
-        body: JSON.stringify({ adminRank: admin, memberRank: member }),
+        body: JSON.stringify({
+          adminRank: admin,
+          memberRank: member,
+          joinRequests,
+        }),
       });
+      if (deadline !== undefined) {
+        await setPreferenceDeadline(deadline);
+        await validateKnownUser();
+      }
       setNotificationContent("Community guidelines updated.");
     } catch (err) {
       setNotificationContent("Error updating community guidelines.");
@@ -105,6 +138,13 @@ export default function ControlPanel({
     setNoteEdited(true);
   };
 
+  // re-render every minute so the deadline countdown keeps running
+  const [, setTick] = useState(0);
+  useEffect(() => {
+    const timer = setInterval(() => setTick((t) => t + 1), 60000);
+    return () => clearInterval(timer);
+  }, []);
+
   useEffect(() => {
     if (localStorage.getItem("showInfoModal")) {
       setIsInfoModalOpen(true);
@@ -112,10 +152,10 @@ export default function ControlPanel({
   });
 
   useEffect(() => {
-    if (!isPreviewing && !user?.community.locked) {
+    if (!isPreviewing && !isLocked(user?.community)) {
       updatePlotAssignments([]);
       setIsPreviewing(false);
-    } else if (isPreviewing && !user?.community.locked) {
+    } else if (isPreviewing && !isLocked(user?.community)) {
       async function getAssigments() {
         const results = await getOptimizedAssignments();
         updatePlotAssignments(results);
@@ -131,7 +171,7 @@ export default function ControlPanel({
             ...prev,
             community: {
               ...prev.community,
-              finalized: !prev.community.finalized,
+              status: isFinalized(prev.community) ? "locked" : "finalized",
             },
           }
         : prev,
@@ -139,6 +179,20 @@ export default function ControlPanel({
     finalizeAssignments();
   };
 
+  const archive = async () => {
+    const status =
+      user?.community.status === "archived" ? "finalized" : "archived";
+    await setCommunityStatus(status);
+    setUser((prev) =>
+      prev ? { ...prev, community: { ...prev.community, status } } : prev,
+    );
+    setNotificationContent(
+      status === "archived" ? "Community archived." : "Community unarchived.",
+    );
+    setShowNotification(true);
+    setTimeout(() => setShowNotification(false), 5000);
+  };
+
   const triggerDownload = async () => {
     downloadAssignmentData();
   };
@@ -171,9 +225,9 @@ export default function ControlPanel({
   };
 
   const lockCommunity = async () => {
-    if (user?.community.finalized) return;
+    if (isFinalized(user?.community)) return;
     const results = await optimizeAndLock();
-    if (!user?.community.locked) {
+    if (!isLocked(user?.community)) {
       updatePlotAssignments(results);
       setNotificationContent(
         "Displaying optimized assignments. Community locked.",
@@ -189,7 +243,7 @@ export default function ControlPanel({
             ...prev,
             community: {
               ...prev.community,
-              locked: !prev.community.locked,
+              status: isLocked(prev.community) ? "collecting" : "locked",
             },
           }
         : prev,
@@ -216,25 +270,106 @@ export default function ControlPanel({
     setTimeout(() => setShowNotification(false), 5000);
   };
 
+  const switchCharacter = async (name: string) => {
+    try {
+      const char = await setCharacter(name);
+      setUser((prev) => (prev ? { ...prev, char } : prev));
+    } catch (err) {
+      setNotificationContent("Error switching character.");
+      setShowNotification(true);
+      setTimeout(() => setShowNotification(false), 5000);
+    }
+  };
+
+  const handleLeave = async () => {
+    if (!confirm(`Leave ${user?.community.name}? Your preferences are deleted.`))
+      return;
+    try {
+      await leaveCommunity();
+      localStorage.removeItem("community_id");
+      await validateKnownUser();
+    } catch (err) {
+      setNotificationContent("Error leaving community.");
+      setShowNotification(true);
+      setTimeout(() => setShowNotification(false), 5000);
+    }
+  };
+
+  const handleRosterSync = async () => {
+    try {
+      const report = await syncRoster();
+      const departed = report.changes.filter((c) => c.kind === "departed");
+      setNotificationContent(
+        `Roster synced: ${report.changes.length} changes, ${departed.length} departed.`,
+      );
+      await validateKnownUser();
+    } catch (err) {
+      setNotificationContent("Error syncing guild roster.");
+    }
+
+    setShowNotification(true);
+    setTimeout(() => setShowNotification(false), 5000);
+  };
+
   return (
     <>
       <div className="info-panel">
         <div className="btag-tile">
           <div className="btag-label">{user?.battletag}</div>
-          <div className="btag-value">{user?.char}</div>
-          <div className="btag-community">&lt;{user?.community.name}&gt;</div>
+          {user && user.characters?.length > 1 ? (
+            <select
+              className="btag-value"
+              value={user.char}
+              onChange={(e) => switchCharacter(e.target.value)}
+            >
+              {user.characters.map((name) => (
+                <option key={name} value={name}>
+                  {name}
+                </option>
+              ))}
+            </select>
+          ) : (
+            <div className="btag-value">{user?.char}</div>
+          )}
+          <select
+            className="btag-community"
+            value={user?.community.id}
+            onChange={(e) =>
+              e.target.value
+                ? switchCommunity(e.target.value)
+                : setJoining(true)
+            }
+          >
+            {user?.memberships?.map((m) => (
+              <option key={m.community.id} value={m.community.id}>
+                &lt;{m.community.name}&gt;
+              </option>
+            ))}
+            <option value="">Join another community</option>
+          </select>
         </div>
         <div className="btn-group">
           {user?.isAdmin && (
             <>
-              {user?.community.locked && (
+              {isLocked(user?.community) && (
                 <>
-                  <button
-                    className={`admin-btn ${user.community.finalized ? "btn-on" : ""}`}
-                    onClick={finalize}
-                  >
-                    <MapPinCheck />
-                  </button>
+                  {user.community.status !== "archived" && (
+                    <button
+                      className={`admin-btn ${isFinalized(user.community) ? "btn-on" : ""}`}
+                      onClick={finalize}
+                    >
+                      <MapPinCheck />
+                    </button>
+                  )}
+                  {isFinalized(user.community) && (
+                    <button
+                      className={`admin-btn ${user.community.status === "archived" ? "btn-on" : ""}`}
+                      title="Archive community"
+                      onClick={archive}
+                    >
+                      <Archive />
+                    </button>
+                  )}
                   <button className="admin-btn" onClick={triggerDownload}>
                     <Download />
                   </button>
@@ -243,16 +378,19 @@ export default function ControlPanel({
                   </button>
                 </>
               )}
+              <button className="admin-btn" onClick={handleRosterSync}>
+                <UsersRound />
+              </button>
               <button
-                className={`admin-btn ${user.community.locked ? "btn-on" : ""}`}
+                className={`admin-btn ${isLocked(user.community) ? "btn-on" : ""}`}
                 onClick={lockCommunity}
               >
-                {user?.community.locked ? <Lock /> : <Unlock />}
+                {isLocked(user?.community) ? <Lock /> : <Unlock />}
               </button>
             </>
           )}
 
-          {!user?.community.locked && !isPreviewing && (
+          {!isLocked(user?.community) && !isPreviewing && (
             <button
               className="admin-btn"
               onClick={() => setIsClearModalOpen(true)}
@@ -266,6 +404,20 @@ export default function ControlPanel({
           >
             <Info />
           </button>
+          <button
+            className="admin-btn"
+            title="Log out (shift-click to log out on every device)"
+            onClick={(e) => logout(e.shiftKey)}
+          >
+            <LogOut />
+          </button>
+          <button
+            className="admin-btn"
+            title="Leave community"
+            onClick={handleLeave}
+          >
+            <DoorOpen />
+          </button>
           {showAdminPanel && (
             <button
               className="admin-btn"
@@ -275,7 +427,7 @@ export default function ControlPanel({
             </button>
           )}
         </div>
-        {user?.community.finalized ? (
+        {isFinalized(user?.community) ? (
           <div className="lock-notice-container">
             <div className="lock-notice-content">
               <img src="house_sold.png" alt="Locked" className="lock-icon" />
@@ -287,17 +439,33 @@ export default function ControlPanel({
               </span>
             </div>
           </div>
-        ) : user?.community.locked ? (
+        ) : isLocked(user?.community) ? (
           <div className="lock-notice-container greyed">
             This community is currently locked. No further changes to plot
             prioritization can be made at this time.
           </div>
+        ) : user && preferencesClosed(user.community) ? (
+          <div className="lock-notice-container greyed">
+            The deadline for preferences has passed. The community will be
+            locked shortly.
+          </div>
         ) : (
-          <PlotGrid player={playerData} updatePlayerPlot={updatePlayerPlot} />
+          <>
+            {user?.community.preferences?.deadline && (
+              <div className="lock-notice-container deadline-notice">
+                Preferences close in{" "}
+                {formatCountdown(user.community.preferences.deadline)}
+              </div>
+            )}
+            <PlotGrid
+              player={playerData}
+              updatePlayerPlot={updatePlayerPlot}
+            />
+          </>
         )}
 
         <div className="toggle-group">
-          {!user?.community.locked && (
+          {!isLocked(user?.community) && (
             <>
               <button
                 className={`toggle-wrapper ${!isPreviewing ? (targetedMode ? "active-btn" : "") : "locked-btn"}`}
@@ -328,7 +496,7 @@ export default function ControlPanel({
                 <button
                   className={`toggle-wrapper ${isPreviewing ? "active-btn" : ""}`}
                   onClick={togglePreview}
-                  disabled={user?.community.locked}
+                  disabled={isLocked(user?.community)}
                 >
                   <span className="toggle-label">Preview:</span>
                   <label className="toggle">
@@ -337,7 +505,7 @@ export default function ControlPanel({
                       type="checkbox"
                       checked={isPreviewing}
                       onChange={togglePreview}
-                      disabled={user?.community.locked}
+                      disabled={isLocked(user?.community)}
                     />
                     <div className="toggle-switch">
                       <div className="toggle-thumb">
@@ -354,7 +522,7 @@ export default function ControlPanel({
             </>
           )}
         </div>
-        {!user?.community.locked ? (
+        {!isLocked(user?.community) ? (
           <div className="textarea-wrapper">
             <textarea
               id="info"
@@ -372,7 +540,7 @@ export default function ControlPanel({
           <div className="spacer"></div>
         )}
 
-        {!user?.community.locked && (
+        {!isLocked(user?.community) && (
           <button
             className="btn"
             disabled={!contextDirty && !noteEdited}
@@ -41,12 +41,11 @@ export function updateBadgeStyles(map: Map, player: PlayerData | undefined) {
 
   if (!vectorLayer) return;
 
-  vectorLayer
-    .getSource()
-    ?.getFeatures()
-    .forEach((feature) => {
-      feature.setStyle(createBadgeStyle(feature, player, getGradientColor));
-    });
+  const features = vectorLayer.getSource()?.getFeatures() ?? [];
+  const color = (index: number) => getGradientColor(index, features.length);
+  features.forEach((feature) => {
+    feature.setStyle(createBadgeStyle(feature, player, color));
+  });
 }
 
 export function createBadgeStyle(
@@ -54,7 +53,7 @@ export function createBadgeStyle(
   player: PlayerData | undefined,
   getGradientColor: (index: number) => string,
 ): Style[] {
-  const pinId = (feature.get("id") as number) + 1;
+  const pinId = feature.get("plot") as number;
   const prioritized = player?.plotData[pinId];
 
   if (prioritized === undefined) {
@@ -95,14 +94,11 @@ export function createAssignmentBadge(map: Map, assignments: Assignment[]) {
 
   if (!vectorLayer) return;
 
-  vectorLayer
-    .getSource()
-    ?.getFeatures()
-    .forEach((feature) => {
-      feature.setStyle(
-        createAssignmentStyle(feature, assignments, getGradientColor),
-      );
-    });
+  const features = vectorLayer.getSource()?.getFeatures() ?? [];
+  const color = (index: number) => getGradientColor(index, features.length);
+  features.forEach((feature) => {
+    feature.setStyle(createAssignmentStyle(feature, assignments, color));
+  });
 }
 
 export function createAssignmentStyle(
@@ -110,7 +106,7 @@ export function createAssignmentStyle(
   assignments: Assignment[],
   getGradientColor: (index: number) => string,
 ): Style[] {
-  const pinId = (feature.get("id") as number) + 1;
+  const pinId = feature.get("plot") as number;
   const ass = assignments.find((ass) => ass.plot === pinId);
 
   if (ass === undefined) {
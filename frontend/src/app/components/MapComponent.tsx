@@ -13,12 +13,16 @@ import { getCenter } from "ol/extent";
 import "ol/ol.css";
 import { Projection } from "ol/proj.js";
 import Static from "ol/source/ImageStatic.js";
-import { PlotData, plotData } from "../data/PlotData";
 
 import "@/styles/MapStyles.css";
 import { getCommunityData, PlayerData, buildPlotMap } from "../api/player";
 import { useAuth } from "../context/AuthContext";
-import { getLowestFreePriority } from "../utils";
+import {
+  getLowestFreePriority,
+  isFinalized,
+  isLocked,
+  preferencesClosed,
+} from "../utils";
 import ControlPanel from "./ControlPanel";
 import {
   BASE_STYLE,
@@ -27,6 +31,7 @@ import {
 } from "./Features";
 import MapHoverPopup from "./Tooltip";
 import { Assignment, getAssignedPlots } from "../api/optimizer";
+import { getPlots, Plot } from "../api/plots";
 import TargetedModal from "./TargetedModal";
 import AdminOverwriteModal from "./AdminOverwriteModal";
 
@@ -68,8 +73,8 @@ export default function MapComponent() {
   };
 
   const { user } = useAuth();
-  const lockedRef = useRef(user?.community.locked ?? false);
-  const finalizedRef = useRef(user?.community.finalized ?? false);
+  const lockedRef = useRef(isLocked(user?.community));
+  const finalizedRef = useRef(isFinalized(user?.community));
 
   const [targetedMode, setTargetedMode] = useState(false);
   const targetedRef = useRef(targetedMode);
@@ -77,6 +82,7 @@ export default function MapComponent() {
   const [playerData, setPlayerData] = useState<PlayerData[]>([]);
   const [plotAssignments, setPlotAssignments] = useState<Assignment[]>([]);
   const [mapReady, setMapReady] = useState<boolean>(false);
+  const [plots, setPlots] = useState<Plot[]>([]);
 
   const playerRef = useRef<PlayerData | undefined>(undefined);
   const player = playerData?.find(
@@ -101,12 +107,12 @@ export default function MapComponent() {
 
   useEffect(() => {
     playerRef.current = player;
-    lockedRef.current = user?.community.locked || plotAssignments?.length > 0;
-    finalizedRef.current = user?.community.finalized || false;
+    lockedRef.current =
+      isLocked(user?.community) || plotAssignments?.length > 0;
+    finalizedRef.current = isFinalized(user?.community);
     rerenderFeatures();
   }, [
-    user?.community.locked,
-    user?.community.finalized,
+    user?.community.status,
     playerData,
     plotAssignments,
   ]);
@@ -126,7 +132,11 @@ export default function MapComponent() {
   };
 
   const forcePlotUpdate = (plotId: number, value: number) => {
-    if (user?.community.locked) return;
+    if (
+      isLocked(user?.community) ||
+      (user && preferencesClosed(user.community))
+    )
+      return;
     setPlayerData((prev) =>
       prev.map((p) => {
         if (p.battletag !== user?.battletag) return p;
@@ -152,7 +162,11 @@ export default function MapComponent() {
   };
 
   const updatePlayerPlot = (plotId: number, value: number) => {
-    if (user?.community.locked) return;
+    if (
+      isLocked(user?.community) ||
+      (user && preferencesClosed(user.community))
+    )
+      return;
     if (playerRef.current?.plotData[plotId]) {
       setPlayerData((prev) =>
         prev.map((p) =>
@@ -186,14 +200,15 @@ export default function MapComponent() {
   useEffect(() => {
     async function fetchData() {
       try {
+        setPlots(await getPlots());
         const data = await getCommunityData();
         setPlayerData(data);
         playerRef.current = data?.find(
           (player) => player.battletag === user?.battletag,
         );
         if (
-          user?.community.finalized ||
-          (user?.isAdmin && user.community.locked)
+          isFinalized(user?.community) ||
+          (user?.isAdmin && isLocked(user.community))
         ) {
           const data = await getAssignedPlots();
           setPlotAssignments(data);
@@ -212,6 +227,7 @@ export default function MapComponent() {
     if (
       !mapRef.current ||
       mapInstanceRef.current ||
+      plots.length == 0 ||
       (!playerRef.current && plotAssignments.length == 0)
     ) {
       return;
@@ -220,7 +236,7 @@ export default function MapComponent() {
     const plotMap = buildPlotMap(playerData);
 
     const vectorSource = new VectorSource({
-      features: plotData.map((plot, index) => {
+      features: plots.map((plot, index) => {
         const feature = new Feature({
           geometry: new Point([plot.xCoord, plot.yCoord]),
           name: plot.label,
@@ -292,7 +308,7 @@ export default function MapComponent() {
         });
       }
     });
-  }, [playerData, plotAssignments]);
+  }, [plots, playerData, plotAssignments]);
 
   return (
     <div className="component-style">
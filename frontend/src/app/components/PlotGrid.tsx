@@ -1,7 +1,8 @@
 import { PlayerData } from "../api/player";
 import "@/styles/PlotGrid.css";
-import { getGradientColor, getLowestFreePriority, TOTAL_PLOTS } from "../utils";
-import React, { useState } from "react";
+import { getGradientColor, getLowestFreePriority } from "../utils";
+import React, { useEffect, useState } from "react";
+import { getPlots, Plot } from "../api/plots";
 import { fetchWithAuth, BASE_URL } from "../api";
 
 interface PlotGridProps {
@@ -10,6 +11,12 @@ interface PlotGridProps {
 }
 
 export default function PlotGrid({ player, updatePlayerPlot }: PlotGridProps) {
+  const [plots, setPlots] = useState<Plot[]>([]);
+
+  useEffect(() => {
+    getPlots().then(setPlots).catch(console.error);
+  }, []);
+
   const plotIdToPriority = player
     ? Object.entries(player.plotData).reduce<Record<number, number>>(
         (acc, [plotId, plotPriority]) => {
@@ -22,10 +29,10 @@ export default function PlotGrid({ player, updatePlayerPlot }: PlotGridProps) {
 
   return (
     <div className="plot-grid">
-      {Array.from({ length: TOTAL_PLOTS }, (_, i) => {
-        const plotId = i + 1;
+      {plots.map((plot) => {
+        const plotId = plot.id;
         const priority = plotIdToPriority[plotId];
-        const bgColor = plotId ? getGradientColor(priority) : undefined;
+        const bgColor = getGradientColor(priority, plots.length);
 
         return (
           <div
@@ -1,7 +1,8 @@
 "use client";
 
 import React, { createContext, useContext, useEffect, useState } from "react";
-import { Community, validateSession } from "../api/validate";
+import { Community, Membership, validateSession } from "../api/validate";
+import { logout as endSession, logoutEverywhere } from "../api/session";
 
 export type User = {
   battletag: string;
@@ -9,6 +10,8 @@ export type User = {
   note: string;
   community: Community;
   isAdmin: boolean;
+  characters: string[];
+  memberships: Membership[];
 };
 
 interface AuthContextType {
@@ -16,6 +19,10 @@ interface AuthContextType {
   user: User | undefined;
   setUser: React.Dispatch<React.SetStateAction<User | undefined>>;
   validateKnownUser: () => Promise<void>;
+  switchCommunity: (communityId: string) => Promise<void>;
+  joining: boolean;
+  setJoining: React.Dispatch<React.SetStateAction<boolean>>;
+  logout: (everywhere?: boolean) => Promise<void>;
   loading: boolean;
 }
 
@@ -27,6 +34,7 @@ export const AuthProvider: React.FC<{ children: React.ReactNode }> = ({
   const [isKnown, setIsKnown] = useState(false);
   const [user, setUser] = useState<User | undefined>(undefined);
   const [loading, setLoading] = useState(true);
+  const [joining, setJoining] = useState(false);
 
   useEffect(() => {
     const token = localStorage.getItem("session_token");
@@ -52,9 +60,38 @@ export const AuthProvider: React.FC<{ children: React.ReactNode }> = ({
     }
   };
 
+  // every request after this is scoped to the chosen membership
+  const switchCommunity = async (communityId: string) => {
+    localStorage.setItem("community_id", communityId);
+    setJoining(false);
+    await validateKnownUser();
+  };
+
+  const logout = async (everywhere = false) => {
+    try {
+      await (everywhere ? logoutEverywhere() : endSession());
+    } catch (err) {
+      console.log(err);
+    }
+    localStorage.removeItem("session_token");
+    localStorage.removeItem("community_id");
+    setUser(undefined);
+    setIsKnown(false);
+  };
+
   return (
     <AuthContext.Provider
-      value={{ isKnown, user, setUser, validateKnownUser, loading }}
+      value={{
+        isKnown,
+        user,
+        setUser,
+        validateKnownUser,
+        switchCommunity,
+        joining,
+        setJoining,
+        logout,
+        loading,
+      }}
     >
       {children}
     </AuthContext.Provider>
@@ -1,129 +0,0 @@
-export interface PlotData {
-  id: number;
-  xCoord: number;
-  yCoord: number;
-  label: string;
-  potentialBuyers: string[];
-}
-
-export const plotData: PlotData[] = [
-    { id: 1, xCoord: 1258, yCoord: 1898, label: "Plot 1", potentialBuyers: [] },
-    { id: 2, xCoord: 1242, yCoord: 1782, label: "Plot 2", potentialBuyers: [] },
-    { id: 3, xCoord: 1244, yCoord: 1680, label: "Plot 3", potentialBuyers: [] },
-    { id: 4, xCoord: 1098, yCoord: 1762, label: "Plot 4", potentialBuyers: [] },
-    { id: 5, xCoord: 1042, yCoord: 1698, label: "Plot 5", potentialBuyers: [] },
-    { id: 6, xCoord: 1017, yCoord: 1619, label: "Plot 6", potentialBuyers: [] },
-    { id: 7, xCoord: 987, yCoord: 1532, label: "Plot 7", potentialBuyers: [] },
-    { id: 8, xCoord: 1283, yCoord: 1542, label: "Plot 8", potentialBuyers: [] },
-    { id: 9, xCoord: 1392, yCoord: 1583, label: "Plot 9", potentialBuyers: [] },
-    { id: 10, xCoord: 1355, yCoord: 1496, label: "Plot 10", potentialBuyers: [] },
-
-    { id: 11, xCoord: 1606, yCoord: 1714, label: "Plot 11", potentialBuyers: [] },
-    { id: 12, xCoord: 1542, yCoord: 1624, label: "Plot 12", potentialBuyers: [] },
-    { id: 13, xCoord: 1622, yCoord: 1608, label: "Plot 13", potentialBuyers: [] },
-    { id: 14, xCoord: 1711, yCoord: 1548, label: "Plot 14", potentialBuyers: [] },
-    { id: 15, xCoord: 1753, yCoord: 1716, label: "Plot 15", potentialBuyers: [] },
-    { id: 16, xCoord: 1824, yCoord: 1794, label: "Plot 16", potentialBuyers: [] },
-    { id: 17, xCoord: 1915, yCoord: 1783, label: "Plot 17", potentialBuyers: [] },
-    { id: 18, xCoord: 1922, yCoord: 1714, label: "Plot 18", potentialBuyers: [] },
-    { id: 19, xCoord: 1840, yCoord: 1676, label: "Plot 19", potentialBuyers: [] },
-    { id: 20, xCoord: 1827, yCoord: 1448, label: "Plot 20", potentialBuyers: [] },
-
-    { id: 21, xCoord: 1906, yCoord: 1421, label: "Plot 21", potentialBuyers: [] },
-    { id: 22, xCoord: 1800, yCoord: 1372, label: "Plot 22", potentialBuyers: [] },
-    { id: 23, xCoord: 1473, yCoord: 1329, label: "Plot 23", potentialBuyers: [] },
-    { id: 24, xCoord: 1527, yCoord: 1285, label: "Plot 24", potentialBuyers: [] },
-    { id: 25, xCoord: 1443, yCoord: 1191, label: "Plot 25", potentialBuyers: [] },
-    { id: 26, xCoord: 1326, yCoord: 1133, label: "Plot 26", potentialBuyers: [] },
-    { id: 27, xCoord: 1256, yCoord: 1132, label: "Plot 27", potentialBuyers: [] },
-    { id: 28, xCoord: 1375, yCoord: 1047, label: "Plot 28", potentialBuyers: [] },
-    { id: 29, xCoord: 1407, yCoord: 928, label: "Plot 29", potentialBuyers: [] },
-    { id: 30, xCoord: 1496, yCoord: 1067, label: "Plot 30", potentialBuyers: [] },
-
-    { id: 31, xCoord: 1634, yCoord: 1096, label: "Plot 31", potentialBuyers: [] },
-    { id: 32, xCoord: 1654, yCoord: 1018, label: "Plot 32", potentialBuyers: [] },
-    { id: 33, xCoord: 1718, yCoord: 940, label: "Plot 33", potentialBuyers: [] },
-    { id: 34, xCoord: 1873, yCoord: 902, label: "Plot 34", potentialBuyers: [] },
-    { id: 35, xCoord: 1968, yCoord: 991, label: "Plot 35", potentialBuyers: [] },
-    { id: 36, xCoord: 2068, yCoord: 834, label: "Plot 36", potentialBuyers: [] },
-    { id: 37, xCoord: 2140, yCoord: 955, label: "Plot 37", potentialBuyers: [] },
-    { id: 38, xCoord: 2246, yCoord: 770, label: "Plot 38", potentialBuyers: [] },
-    { id: 39, xCoord: 2342, yCoord: 710, label: "Plot 39", potentialBuyers: [] },
-    { id: 40, xCoord: 2304, yCoord: 903, label: "Plot 40", potentialBuyers: [] },
-
-    { id: 41, xCoord: 2304, yCoord: 998, label: "Plot 41", potentialBuyers: [] },
-    { id: 42, xCoord: 2391, yCoord: 1093, label: "Plot 42", potentialBuyers: [] },
-    { id: 43, xCoord: 2545, yCoord: 1041, label: "Plot 43", potentialBuyers: [] },
-    { id: 44, xCoord: 1970, yCoord: 1144, label: "Plot 44", potentialBuyers: [] },
-    { id: 45, xCoord: 2028, yCoord: 1243, label: "Plot 45", potentialBuyers: [] },
-    { id: 46, xCoord: 2030, yCoord: 1306, label: "Plot 46", potentialBuyers: [] },
-    { id: 47, xCoord: 2254, yCoord: 1296, label: "Plot 47", potentialBuyers: [] },
-    { id: 48, xCoord: 2339, yCoord: 1290, label: "Plot 48", potentialBuyers: [] },
-    { id: 49, xCoord: 2497, yCoord: 1336, label: "Plot 49", potentialBuyers: [] },
-    { id: 50, xCoord: 2552, yCoord: 1429, label: "Plot 50", potentialBuyers: [] },
-
-    { id: 51, xCoord: 2437, yCoord: 1443, label: "Plot 51", potentialBuyers: [] },
-    { id: 52, xCoord: 2371, yCoord: 1541, label: "Plot 52", potentialBuyers: [] },
-    { id: 53, xCoord: 2522, yCoord: 1550, label: "Plot 53", potentialBuyers: [] },
-];
-
-export const plots: [number, number, string][] = [
-    [1258, 1898, "Plot 1"],
-    [1242, 1782, "Plot 2"],
-    [1244, 1680, "Plot 3"],
-    [1098, 1762, "Plot 4"],
-    [1042, 1698, "Plot 5"],
-    [1017, 1619, "Plot 6"],
-    [987, 1532, "Plot 7"],
-    [1283, 1542, "Plot 8"],
-    [1392, 1583, "Plot 9"],
-    [1355, 1496, "Plot 10"],
-
-    [1606, 1714, "Plot 11"],
-    [1542, 1624, "Plot 12"],
-    [1622, 1608, "Plot 13"],
-    [1711, 1548, "Plot 14"],
-    [1753, 1716, "Plot 15"],
-    [1824, 1794, "Plot 16"],
-    [1915, 1783, "Plot 17"],
-    [1922, 1714, "Plot 18"],
-    [1840, 1676, "Plot 19"],
-    [1827, 1448, "Plot 20"],
-
-    [1906, 1421, "Plot 21"],
-    [1800, 1372, "Plot 22"],
-    [1473, 1329, "Plot 23"],
-    [1527, 1285, "Plot 24"],
-    [1443, 1191, "Plot 25"],
-    [1326, 1133, "Plot 26"],
-    [1256, 1132, "Plot 27"],
-    [1375, 1047, "Plot 28"],
-    [1407, 928, "Plot 29"],
-    [1496, 1067, "Plot 30"],
-
-    [1634, 1096, "Plot 31"],
-    [1654, 1018, "Plot 32"],
-    [1718, 940, "Plot 33"],
-    [1873, 902, "Plot 34"],
-    [1968, 991, "Plot 35"],
-    [2068, 834, "Plot 36"],
-    [2140, 955, "Plot 37"],
-    [2246, 770, "Plot 38"],
-    [2342, 710, "Plot 39"],
-    [2304, 903, "Plot 40"],
-
-    [2304, 998, "Plot 41"],
-    [2391, 1093, "Plot 42"],
-    [2545, 1041, "Plot 43"],
-    [1970, 1144, "Plot 44"],
-    [2028, 1243, "Plot 45"],
-    [2030, 1306, "Plot 46"],
-    [2254, 1296, "Plot 47"],
-    [2339, 1290, "Plot 48"],
-    [2497, 1336, "Plot 49"],
-    [2552, 1429, "Plot 50"],
-
-    [2437, 1443, "Plot 51"],
-    [2371, 1541, "Plot 52"],
-    [2522, 1550, "Plot 53"],
-  ];
\ No newline at end of file
@@ -14,12 +14,12 @@ export default function Home() {
 }
 
 const HomeContent: React.FC = () => {
-  const { isKnown, user, loading } = useAuth();
+  const { isKnown, user, loading, joining } = useAuth();
 
   if (loading) return null;
 
   if (!isKnown) return <AuthModal />;
-  if (!user?.community.id) return <CommunitySelection />;
+  if (!user?.community.id || joining) return <CommunitySelection />;
 
   const mainContainerStyle: React.CSSProperties = {
     minHeight: "100vh",
@@ -37,7 +37,7 @@ const HomeContent: React.FC = () => {
   return (
     <div style={mainContainerStyle}>
       <div style={contentWrapperStyle}>
-        <MapComponent />
+        <MapComponent key={user.community.id} />
       </div>
     </div>
   );
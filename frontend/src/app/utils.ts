@@ -1,9 +1,8 @@
 import { PlayerData } from "./api/player";
+import { Community } from "./api/validate";
 
-export const TOTAL_PLOTS = 53;
-
-export const getGradientColor = (index: number) => {
-  const hue = 120 - ((index - 1) / (TOTAL_PLOTS - 1)) * 120;
+export const getGradientColor = (index: number, total: number) => {
+  const hue = 120 - ((index - 1) / Math.max(total - 1, 1)) * 120;
   return `hsl(${hue}, 70%, 60%)`;
 };
 
@@ -18,6 +17,31 @@ export const getLowestFreePriority = (player: PlayerData) => {
   return priority;
 };
 
+// isLocked is true once assignments were made, also for finalized and archived communities
+export const isLocked = (community?: Community): boolean =>
+  !!community && community.status !== "collecting";
+
+export const isFinalized = (community?: Community): boolean =>
+  community?.status === "finalized" || community?.status === "archived";
+
+// preferencesClosed checks the deadline itself, so the page closes when the countdown runs out
+export const preferencesClosed = (community: Community): boolean => {
+  const deadline = community.preferences?.deadline;
+  return !!deadline && new Date(deadline).getTime() <= Date.now();
+};
+
+export const formatCountdown = (deadline: string): string => {
+  const minutes = Math.max(
+    0,
+    Math.floor((new Date(deadline).getTime() - Date.now()) / 60000),
+  );
+  const days = Math.floor(minutes / 1440);
+  const hours = Math.floor((minutes % 1440) / 60);
+  if (days > 0) return `${days}d ${hours}h`;
+  if (hours > 0) return `${hours}h ${minutes % 60}m`;
+  return `${minutes}m`;
+};
+
 export const deslugRealm = (slug: string): string => {
   return slug
     .split("-")
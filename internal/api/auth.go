@@ -1,13 +1,17 @@
 package api
 
 import (
-	"errors"
+	"crypto/subtle"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"github.com/sbraitsch/plotter/internal/model"
 	"github.com/sbraitsch/plotter/internal/service"
 	"github.com/sbraitsch/plotter/internal/service/oauth"
 	"github.com/sbraitsch/plotter/internal/storage"
@@ -16,15 +20,23 @@ import (
 
 type AuthAPI interface {
 	Routes(tmw func(http.Handler) http.Handler) chi.Router
+	SessionRoutes(tmw func(http.Handler) http.Handler) chi.Router
 }
 
 type authAPIImpl struct {
-	service  service.UserService
-	oauthCfg *oauth2.Config
+	service    service.UserService
+	bnet       *oauth.Provider
+	logins     *loginStore
+	cookiePath string
 }
 
-func NewAuthAPI(storage *storage.StorageClient, cfg *oauth2.Config) AuthAPI {
-	return &authAPIImpl{service: service.NewUserService(storage), oauthCfg: cfg}
+func NewAuthAPI(storage storage.Store, bnet *oauth.Provider) AuthAPI {
+	return &authAPIImpl{
+		service:    service.NewUserService(storage, bnet),
+		bnet:       bnet,
+		logins:     newLoginStore(),
+		cookiePath: loginCookiePath(bnet.Config(model.DEFAULT_REGION).RedirectURL),
+	}
 }
 
 func (api *authAPIImpl) Routes(tmw func(http.Handler) http.Handler) chi.Router {
@@ -37,36 +49,128 @@ func (api *authAPIImpl) Routes(tmw func(http.Handler) http.Handler) chi.Router {
 	return r
 }
 
+// SessionRoutes end sessions, independent of the provider they were opened with.
+func (api *authAPIImpl) SessionRoutes(tmw func(http.Handler) http.Handler) chi.Router {
+	r := chi.NewRouter()
+	r.Use(tmw)
+
+	r.Post("/logout", api.logout)
+	r.Post("/logout-all", api.logoutEverywhere)
+
+	return r
+}
+
+func (api *authAPIImpl) logout(w http.ResponseWriter, r *http.Request) {
+	if err := api.service.Logout(r.Context()); err != nil {
+		http.Error(w, "Failed to end session", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *authAPIImpl) logoutEverywhere(w http.ResponseWriter, r *http.Request) {
+	if err := api.service.LogoutEverywhere(r.Context()); err != nil {
+		http.Error(w, "Failed to end sessions", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (api *authAPIImpl) battleNetLogin(w http.ResponseWriter, r *http.Request) {
-	url := api.oauthCfg.AuthCodeURL("state")
-	http.Redirect(w, r, url, http.StatusFound)
+	region, err := model.ParseRegion(r.URL.Query().Get("region"))
+	if err != nil {
+		renderErrorPage(w, http.StatusBadRequest, "Unknown region", "Pick one of the listed Battle.net regions and try again.")
+		return
+	}
+	returnTo, err := parseReturnTo(r.URL.Query().Get("returnTo"))
+	if err != nil {
+		renderErrorPage(w, http.StatusBadRequest, "Invalid login link", "The page to return to after the login is not part of Plotter.")
+		return
+	}
+
+	api.redirectToLogin(w, r, region, returnTo)
+}
+
+// redirectToLogin starts the authorization code flow. The state is bound to this browser
+// by a cookie and to the PKCE verifier on the server.
+func (api *authAPIImpl) redirectToLogin(w http.ResponseWriter, r *http.Request, region model.Region, returnTo string) {
+	state, login, err := api.logins.start(region, returnTo)
+	if err != nil {
+		log.Printf("Failed to start login: %v", err)
+		renderErrorPage(w, http.StatusInternalServerError, "Login unavailable", "The login could not be started. Please try again.")
+		return
+	}
+
+	cfg := api.bnet.Config(region)
+	http.SetCookie(w, &http.Cookie{
+		Name:     LOGIN_STATE_COOKIE,
+		Value:    state,
+		Path:     api.cookiePath,
+		MaxAge:   int(LOGIN_STATE_TTL.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(cfg.RedirectURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	authURL := cfg.AuthCodeURL(state, oauth2.S256ChallengeOption(login.verifier))
+	http.Redirect(w, r, authURL, http.StatusFound)
 }
 
 func (api *authAPIImpl) battleNetCallback(w http.ResponseWriter, r *http.Request) {
-	code := r.URL.Query().Get("code")
+	query := r.URL.Query()
+	state := query.Get("state")
+
+	cookie, cookieErr := r.Cookie(LOGIN_STATE_COOKIE)
+	http.SetCookie(w, &http.Cookie{Name: LOGIN_STATE_COOKIE, Path: api.cookiePath, MaxAge: -1, HttpOnly: true})
+
+	if oauthErr := query.Get("error"); oauthErr != "" {
+		api.logins.finish(state)
+		if oauthErr == "access_denied" {
+			renderErrorPage(w, http.StatusBadRequest, "Login cancelled", "Battle.net access was not granted, so you were not logged in.")
+			return
+		}
+		log.Printf("Battle.net rejected the login: %s %s", oauthErr, query.Get("error_description"))
+		renderErrorPage(w, http.StatusBadGateway, "Login failed", "Battle.net rejected the login. Please try again.")
+		return
+	}
+
+	if cookieErr != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		renderErrorPage(w, http.StatusBadRequest, "Invalid login",
+			"This login was not started in this browser. Please start the login again from Plotter.")
+		return
+	}
+	login, ok := api.logins.finish(state)
+	if !ok {
+		renderErrorPage(w, http.StatusBadRequest, "Login expired", "The login took too long or was already used. Please log in again.")
+		return
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		renderErrorPage(w, http.StatusBadRequest, "Invalid login", "Battle.net did not return an authorization code.")
+		return
+	}
 
-	sessionToken, err := api.service.RegisterUser(code, api.oauthCfg)
+	sessionToken, err := api.service.RegisterUser(code, login.verifier, r.UserAgent(), login.region)
 
 	if err != nil {
-		http.Error(w, "Error creating new user.", http.StatusInternalServerError)
+		renderErrorPage(w, http.StatusBadGateway, "Login failed", "Your Battle.net account could not be verified. Please try again.")
 		return
 	}
 
-	frontendURL := fmt.Sprintf("%s/auth/success?token=%s", os.Getenv("FRONTEND_URL"), sessionToken)
+	params := url.Values{"token": {sessionToken}}
+	if login.returnTo != "" {
+		params.Set("returnTo", login.returnTo)
+	}
+	frontendURL := fmt.Sprintf("%s/auth/success?%s", os.Getenv("FRONTEND_URL"), params.Encode())
 	http.Redirect(w, r, frontendURL, http.StatusSeeOther)
 }
 
 func (api *authAPIImpl) listAvailableCommunities(w http.ResponseWriter, r *http.Request) {
 	list, err := api.service.ListAvailableCommunities(r.Context())
-	if err != nil {
-		var tokenErr *oauth.TokenExpiredError
-		if ok := errors.As(err, &tokenErr); ok {
-			url := api.oauthCfg.AuthCodeURL("state")
-			http.Redirect(w, r, url, http.StatusFound)
-			return
-		}
+	if renderReauthRequired(w, r, api.bnet, err) {
+		return
 	}
-
 	if err != nil {
 		http.Error(w, "Failed to get guild data", http.StatusInternalServerError)
 		return
@@ -2,14 +2,20 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/sbraitsch/plotter/internal/middleware"
 	"github.com/sbraitsch/plotter/internal/model"
 	"github.com/sbraitsch/plotter/internal/service"
+	"github.com/sbraitsch/plotter/internal/service/oauth"
 	"github.com/sbraitsch/plotter/internal/storage"
 )
 
@@ -19,10 +25,16 @@ type CommunityAPI interface {
 
 type communityAPIImpl struct {
 	service service.CommunityService
+	rosters service.RosterService
+	bnet    *oauth.Provider
 }
 
-func NewCommunityAPI(storage *storage.StorageClient) CommunityAPI {
-	return &communityAPIImpl{service: service.NewCommunityService(storage)}
+func NewCommunityAPI(storage storage.Store, bnet *oauth.Provider) CommunityAPI {
+	return &communityAPIImpl{
+		service: service.NewCommunityService(storage, bnet),
+		rosters: service.NewRosterService(storage, bnet),
+		bnet:    bnet,
+	}
 }
 
 func (api *communityAPIImpl) Routes(tmw, amw func(http.Handler) http.Handler) chi.Router {
@@ -32,19 +44,45 @@ func (api *communityAPIImpl) Routes(tmw, amw func(http.Handler) http.Handler) ch
 		user.Use(tmw)
 		user.Get("/", api.getCommunityData)
 		user.Post("/join/{id}", api.joinCommunity)
+		user.Post("/leave", api.leaveCommunity)
 		user.Get("/assignments", api.getAssignments)
+		user.Get("/assignments/explain", api.explainAssignments)
 	})
 
 	r.Group(func(admin chi.Router) {
 		admin.Use(amw)
-		admin.Post("/finalize", api.finalizeCommunity)
-		admin.Get("/optimize", api.runOptimizer)
-		admin.Post("/assignments", api.setSingleAssignment)
-		admin.Post("/lock", api.toggleCommunityLock)
-		admin.Post("/config", api.setCommunitySettings)
+		// a run is recorded for every optimization, so it can't be a GET
+		admin.Post("/optimize", api.runOptimizer)
+		admin.Get("/runs", api.getOptimizationRuns)
+		admin.Post("/sync", api.syncRoster)
 		admin.Get("/config", api.getCommunitySettings)
 		admin.Get("/download", api.downloadCommunityData)
-		admin.Post("/upload", api.uploadCommunityData)
+		admin.Get("/pins", api.getPlotConstraints)
+		admin.Get("/requests", api.getJoinRequests)
+		admin.Get("/audit", api.getAuditEvents)
+		admin.Get("/revisions", api.getRevisions)
+		admin.Get("/revisions/diff", api.diffRevisions)
+		admin.Get("/revisions/{revision}", api.getRevision)
+		admin.Post("/requests/{battletag}/approve", api.approveJoinRequest)
+		admin.Post("/requests/{battletag}/deny", api.denyJoinRequest)
+
+		// edits of assignments, settings and status have to name the version they are based on
+		admin.Group(func(edit chi.Router) {
+			edit.Use(api.requireVersion)
+			edit.Post("/finalize", api.finalizeCommunity)
+			edit.Post("/assignments", api.setSingleAssignment)
+			edit.Post("/lock", api.toggleCommunityLock)
+			edit.Put("/status", api.setCommunityStatus)
+			edit.Post("/config", api.setCommunitySettings)
+			edit.Put("/deadline", api.setPreferenceDeadline)
+			edit.Post("/upload", api.uploadCommunityData)
+			edit.Post("/pins", api.pinMember)
+			edit.Delete("/pins/{battletag}", api.unpinMember)
+			edit.Delete("/members/{battletag}", api.removeMember)
+			edit.Post("/revisions/{revision}/restore", api.restoreRevision)
+			edit.Post("/reserved", api.reservePlot)
+			edit.Delete("/reserved/{neighborhood}/{plot}", api.releasePlot)
+		})
 	})
 
 	return r
@@ -52,6 +90,9 @@ func (api *communityAPIImpl) Routes(tmw, amw func(http.Handler) http.Handler) ch
 
 func (api *communityAPIImpl) finalizeCommunity(w http.ResponseWriter, r *http.Request) {
 	err := api.service.FinalizeCommunity(r.Context())
+	if renderStatusConflict(w, r, err) {
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to finalize community data", http.StatusInternalServerError)
 		return
@@ -61,6 +102,11 @@ func (api *communityAPIImpl) finalizeCommunity(w http.ResponseWriter, r *http.Re
 }
 
 func (api *communityAPIImpl) getCommunityData(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CtxUser).(*model.User)
+	if !api.setETag(w, r, user.Community.Id) {
+		return
+	}
+
 	community, err := api.service.GetCommunityData(r.Context())
 	if err != nil {
 		http.Error(w, "Failed to retrieve community data", http.StatusInternalServerError)
@@ -72,22 +118,126 @@ func (api *communityAPIImpl) getCommunityData(w http.ResponseWriter, r *http.Req
 
 func (api *communityAPIImpl) joinCommunity(w http.ResponseWriter, r *http.Request) {
 	communityId := chi.URLParam(r, "id")
-	joinedChar, err := api.service.JoinCommunity(r.Context(), communityId)
+	joined, err := api.service.JoinCommunity(r.Context(), communityId)
+	if renderReauthRequired(w, r, api.bnet, err) || renderStatusConflict(w, r, err) {
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to join community: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	render.JSON(w, r, joinedChar)
+	if joined.Request != nil {
+		render.Status(r, http.StatusAccepted)
+	}
+	render.JSON(w, r, joined)
+}
+
+func (api *communityAPIImpl) leaveCommunity(w http.ResponseWriter, r *http.Request) {
+	err := api.service.LeaveCommunity(r.Context())
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Not a member of any community", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to leave community", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// removeMember takes a member out of the community, ?block=true keeps them from joining again for a while.
+func (api *communityAPIImpl) removeMember(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CtxUser).(*model.User)
+	battletag, err := url.PathUnescape(chi.URLParam(r, "battletag"))
+	if err != nil {
+		http.Error(w, "Invalid battletag", http.StatusBadRequest)
+		return
+	}
+	block := r.URL.Query().Get("block") == "true"
+
+	err = api.service.RemoveMember(r.Context(), user.Community.Id, battletag, block)
+	if renderStatusConflict(w, r, err) {
+		return
+	}
+	if errors.Is(err, service.ErrRemoveSelf) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, storage.ErrOutranked) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Member not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to remove member", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (api *communityAPIImpl) getJoinRequests(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CtxUser).(*model.User)
+
+	requests, err := api.service.GetJoinRequests(r.Context(), user.Community.Id)
+	if err != nil {
+		log.Printf("Failed to get join requests: %v", err)
+		http.Error(w, "Error getting join requests", http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, requests)
+}
+
+func (api *communityAPIImpl) approveJoinRequest(w http.ResponseWriter, r *http.Request) {
+	api.decideJoinRequest(w, r, true)
+}
+
+func (api *communityAPIImpl) denyJoinRequest(w http.ResponseWriter, r *http.Request) {
+	api.decideJoinRequest(w, r, false)
+}
+
+func (api *communityAPIImpl) decideJoinRequest(w http.ResponseWriter, r *http.Request, approve bool) {
+	user := r.Context().Value(middleware.CtxUser).(*model.User)
+	battletag, err := url.PathUnescape(chi.URLParam(r, "battletag"))
+	if err != nil {
+		http.Error(w, "Invalid battletag", http.StatusBadRequest)
+		return
+	}
+
+	err = api.service.DecideJoinRequest(r.Context(), user.Community.Id, battletag, approve)
+	if renderStatusConflict(w, r, err) {
+		return
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Join request not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to answer join request", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
 }
 
 func (api *communityAPIImpl) runOptimizer(w http.ResponseWriter, r *http.Request) {
-	community, err := api.service.GetCommunityData(r.Context())
+	options, err := optimizeOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	optimized, err := api.service.Optimize(r.Context(), options)
 	if err != nil {
 		http.Error(w, "Failed to run optimizer", http.StatusInternalServerError)
 		return
 	}
-	optimized := community.Optimize()
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(optimized); err != nil {
@@ -97,12 +247,59 @@ func (api *communityAPIImpl) runOptimizer(w http.ResponseWriter, r *http.Request
 
 func (api *communityAPIImpl) toggleCommunityLock(w http.ResponseWriter, r *http.Request) {
 	user := r.Context().Value(middleware.CtxUser).(*model.User)
-	assignments, err := api.service.ToggleCommunityLock(r.Context(), user)
+	options, err := optimizeOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := api.service.ToggleCommunityLock(r.Context(), user, options)
+	if renderStatusConflict(w, r, err) {
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to lock community.", http.StatusInternalServerError)
+		return
 	}
-	if assignments != nil {
-		render.JSON(w, r, assignments)
+	if result != nil {
+		render.JSON(w, r, result)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (api *communityAPIImpl) setCommunityStatus(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CtxUser).(*model.User)
+	req := &model.StatusRequest{}
+
+	if err := render.Decode(r, req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	status, err := model.ParseCommunityStatus(req.Status)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	options, err := optimizeOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := api.service.SetCommunityStatus(r.Context(), user, status, options)
+	if renderStatusConflict(w, r, err) {
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to set community status: %v", err)
+		http.Error(w, "Error setting community status", http.StatusInternalServerError)
+		return
+	}
+	if result != nil {
+		render.JSON(w, r, result)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -110,6 +307,9 @@ func (api *communityAPIImpl) toggleCommunityLock(w http.ResponseWriter, r *http.
 
 func (api *communityAPIImpl) getAssignments(w http.ResponseWriter, r *http.Request) {
 	user := r.Context().Value(middleware.CtxUser).(*model.User)
+	if !api.setETag(w, r, user.Community.Id) {
+		return
+	}
 	assignments, err := api.service.GetAssignments(r.Context(), user.Community.Id)
 
 	if err != nil {
@@ -120,6 +320,25 @@ func (api *communityAPIImpl) getAssignments(w http.ResponseWriter, r *http.Reque
 	render.JSON(w, r, assignments)
 }
 
+func (api *communityAPIImpl) explainAssignments(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CtxUser).(*model.User)
+
+	// officers get the whole report, members only their own entry
+	battletag := user.Battletag
+	if user.CommunityRank <= user.Community.OfficerRank {
+		battletag = ""
+	}
+
+	explanations, err := api.service.ExplainAssignments(r.Context(), battletag)
+	if err != nil {
+		log.Printf("Failed to explain assignments: %v", err)
+		http.Error(w, "Failed to explain plot assignments", http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, explanations)
+}
+
 func (api *communityAPIImpl) setSingleAssignment(w http.ResponseWriter, r *http.Request) {
 	user := r.Context().Value(middleware.CtxUser).(*model.User)
 	req := &model.SingleAssignmentRequest{}
@@ -129,6 +348,13 @@ func (api *communityAPIImpl) setSingleAssignment(w http.ResponseWriter, r *http.
 	}
 	err := api.service.SetAssignment(r.Context(), req, user.Community.Id)
 
+	if renderStatusConflict(w, r, err) {
+		return
+	}
+	if errors.Is(err, model.ErrInvalidNeighborhood) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to set plot assignment", http.StatusInternalServerError)
 		return
@@ -146,13 +372,33 @@ func (api *communityAPIImpl) setCommunitySettings(w http.ResponseWriter, r *http
 		return
 	}
 
-	if req.AdminRank < 0 || req.MemberRank < 0 {
+	if req.Objective != nil {
+		if _, err := model.ParseObjective(string(*req.Objective)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.RankWeighting != nil {
+		if err := req.RankWeighting.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.AdminRank < 0 || req.MemberRank < 0 ||
+		(req.NeighborWeight != nil && *req.NeighborWeight < 0) ||
+		(req.MovePenalty != nil && *req.MovePenalty < 0) ||
+		(req.Neighborhoods != nil && (*req.Neighborhoods < 1 || *req.Neighborhoods > model.MAX_NEIGHBORHOODS)) {
 		http.Error(w, "Nice try.", http.StatusBadRequest)
 		return
 	}
 
 	err := api.service.SetCommunitySettings(r.Context(), user.Community.Id, req)
 
+	if renderStatusConflict(w, r, err) {
+		return
+	}
 	if err != nil {
 		log.Printf("Failed to update community settings: %v", err)
 		http.Error(w, "Error updating community settings", http.StatusInternalServerError)
@@ -164,6 +410,9 @@ func (api *communityAPIImpl) setCommunitySettings(w http.ResponseWriter, r *http
 
 func (api *communityAPIImpl) getCommunitySettings(w http.ResponseWriter, r *http.Request) {
 	user := r.Context().Value(middleware.CtxUser).(*model.User)
+	if !api.setETag(w, r, user.Community.Id) {
+		return
+	}
 
 	settings, err := api.service.GetCommunitySettings(r.Context(), user.Community.Id)
 
@@ -176,6 +425,29 @@ func (api *communityAPIImpl) getCommunitySettings(w http.ResponseWriter, r *http
 	render.JSON(w, r, settings)
 }
 
+func (api *communityAPIImpl) setPreferenceDeadline(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CtxUser).(*model.User)
+	req := &model.DeadlineRequest{}
+
+	if err := render.Decode(r, req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err := api.service.SetPreferenceDeadline(r.Context(), user.Community.Id, req.Deadline)
+
+	if renderStatusConflict(w, r, err) {
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to set preference deadline: %v", err)
+		http.Error(w, "Error setting preference deadline", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
 func (api *communityAPIImpl) downloadCommunityData(w http.ResponseWriter, r *http.Request) {
 	data, err := api.service.DownloadCommunityData(r.Context())
 
@@ -208,6 +480,13 @@ func (api *communityAPIImpl) uploadCommunityData(w http.ResponseWriter, r *http.
 
 	assignments, err := api.service.UploadCommunityData(r.Context(), req)
 
+	if renderStatusConflict(w, r, err) {
+		return
+	}
+	if errors.Is(err, model.ErrInvalidNeighborhood) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		log.Printf("Failed to overwrite community assignments: %v", err)
 		http.Error(w, "Error setting community data", http.StatusInternalServerError)
@@ -216,3 +495,285 @@ func (api *communityAPIImpl) uploadCommunityData(w http.ResponseWriter, r *http.
 
 	render.JSON(w, r, assignments)
 }
+
+func (api *communityAPIImpl) getPlotConstraints(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CtxUser).(*model.User)
+	if !api.setETag(w, r, user.Community.Id) {
+		return
+	}
+
+	constraints, err := api.service.GetPlotConstraints(r.Context(), user.Community.Id)
+	if err != nil {
+		log.Printf("Failed to get plot constraints: %v", err)
+		http.Error(w, "Error getting pinned and reserved plots", http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, constraints)
+}
+
+func (api *communityAPIImpl) pinMember(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CtxUser).(*model.User)
+	req := &model.Pin{}
+
+	if err := render.Decode(r, req); err != nil || req.Battletag == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err := api.service.PinMember(r.Context(), user.Community.Id, req)
+	if renderStatusConflict(w, r, err) {
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to pin member: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (api *communityAPIImpl) unpinMember(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CtxUser).(*model.User)
+	battletag, err := url.PathUnescape(chi.URLParam(r, "battletag"))
+	if err != nil {
+		http.Error(w, "Invalid battletag", http.StatusBadRequest)
+		return
+	}
+
+	err = api.service.UnpinMember(r.Context(), user.Community.Id, battletag)
+	if renderStatusConflict(w, r, err) {
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to unpin member", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (api *communityAPIImpl) reservePlot(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CtxUser).(*model.User)
+	req := &model.ReservedPlot{}
+
+	if err := render.Decode(r, req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err := api.service.ReservePlot(r.Context(), user.Community.Id, req)
+	if renderStatusConflict(w, r, err) {
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to reserve plot: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (api *communityAPIImpl) releasePlot(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CtxUser).(*model.User)
+	neighborhood, err := strconv.Atoi(chi.URLParam(r, "neighborhood"))
+	if err != nil {
+		http.Error(w, "Invalid neighborhood", http.StatusBadRequest)
+		return
+	}
+	plot, err := strconv.Atoi(chi.URLParam(r, "plot"))
+	if err != nil {
+		http.Error(w, "Invalid plot", http.StatusBadRequest)
+		return
+	}
+
+	err = api.service.ReleasePlot(r.Context(), user.Community.Id, neighborhood, plot)
+	if renderStatusConflict(w, r, err) {
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to release plot", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (api *communityAPIImpl) getAuditEvents(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CtxUser).(*model.User)
+	filter, err := auditFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events, err := api.service.GetAuditEvents(r.Context(), user.Community.Id, filter)
+	if err != nil {
+		log.Printf("Failed to get audit events: %v", err)
+		http.Error(w, "Error getting audit events", http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, events)
+}
+
+func (api *communityAPIImpl) getRevisions(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CtxUser).(*model.User)
+
+	revisions, err := api.service.GetRevisions(r.Context(), user.Community.Id)
+	if err != nil {
+		log.Printf("Failed to get revisions: %v", err)
+		http.Error(w, "Error getting revisions", http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, revisions)
+}
+
+func (api *communityAPIImpl) getRevision(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CtxUser).(*model.User)
+	revision, err := strconv.Atoi(chi.URLParam(r, "revision"))
+	if err != nil {
+		http.Error(w, "Invalid revision", http.StatusBadRequest)
+		return
+	}
+
+	found, err := api.service.GetRevision(r.Context(), user.Community.Id, revision)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Revision not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get revision %d: %v", revision, err)
+		http.Error(w, "Error getting revision", http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, found)
+}
+
+// diffRevisions shows who moved between two revisions, e.g. ?from=3&to=5.
+func (api *communityAPIImpl) diffRevisions(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CtxUser).(*model.User)
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil {
+		http.Error(w, "Invalid from revision", http.StatusBadRequest)
+		return
+	}
+	to, err := strconv.Atoi(r.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, "Invalid to revision", http.StatusBadRequest)
+		return
+	}
+
+	diff, err := api.service.DiffRevisions(r.Context(), user.Community.Id, from, to)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Revision not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to diff revisions %d and %d: %v", from, to, err)
+		http.Error(w, "Error comparing revisions", http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, diff)
+}
+
+func (api *communityAPIImpl) restoreRevision(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CtxUser).(*model.User)
+	revision, err := strconv.Atoi(chi.URLParam(r, "revision"))
+	if err != nil {
+		http.Error(w, "Invalid revision", http.StatusBadRequest)
+		return
+	}
+
+	restored, err := api.service.RestoreRevision(r.Context(), user.Community.Id, revision)
+	if renderStatusConflict(w, r, err) {
+		return
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Revision not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, model.ErrInvalidNeighborhood) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to restore revision", http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, restored)
+}
+
+// auditFilter reads the audit filter from the query string,
+// e.g. ?target=Name%231234&action=assignment_set&since=2025-01-01T00:00:00Z&limit=50.
+func auditFilter(r *http.Request) (*model.AuditFilter, error) {
+	query := r.URL.Query()
+	filter := &model.AuditFilter{
+		Actor:  query.Get("actor"),
+		Target: query.Get("target"),
+		Action: model.AuditAction(query.Get("action")),
+	}
+	for param, into := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if raw := query.Get(param); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q, expected RFC 3339", param, raw)
+			}
+			*into = t
+		}
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("invalid limit %q", raw)
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+// optimizeOptions reads the optimizer settings from the query string,
+// e.g. ?mode=stable&objective=minmax.
+func optimizeOptions(r *http.Request) (model.OptimizeOptions, error) {
+	options := model.OptimizeOptions{
+		Stable: r.URL.Query().Get("mode") == model.OPTIMIZE_MODE_STABLE,
+	}
+	if raw := r.URL.Query().Get("objective"); raw != "" {
+		objective, err := model.ParseObjective(raw)
+		if err != nil {
+			return options, err
+		}
+		options.Objective = objective
+	}
+	return options, nil
+}
+
+func (api *communityAPIImpl) syncRoster(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CtxUser).(*model.User)
+
+	report, err := api.rosters.SyncCommunity(r.Context(), user.Community.Id)
+	if err != nil {
+		log.Printf("Failed to sync roster: %v", err)
+		http.Error(w, "Failed to sync guild roster: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	render.JSON(w, r, report)
+}
+
+func (api *communityAPIImpl) getOptimizationRuns(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(middleware.CtxUser).(*model.User)
+
+	runs, err := api.service.GetOptimizationRuns(r.Context(), user.Community.Id)
+	if err != nil {
+		log.Printf("Failed to get optimization runs: %v", err)
+		http.Error(w, "Error getting optimization runs", http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, runs)
+}
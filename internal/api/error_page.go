@@ -0,0 +1,42 @@
+package api
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+)
+
+var errorPage = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Plotter - {{.Title}}</title>
+<style>
+body { font-family: sans-serif; background: #0c1629; color: #e6e6e6; display: flex; justify-content: center; align-items: center; height: 100vh; margin: 0; }
+main { max-width: 32rem; text-align: center; }
+a { color: #009ae4; }
+</style>
+</head>
+<body>
+<main>
+<h2>{{.Title}}</h2>
+<p>{{.Message}}</p>
+<p><a href="{{.Back}}">Back to Plotter</a></p>
+</main>
+</body>
+</html>`))
+
+// renderErrorPage answers browser navigations, where a plain text error would strand the user on the API host.
+func renderErrorPage(w http.ResponseWriter, status int, title, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	err := errorPage.Execute(w, struct{ Title, Message, Back string }{
+		Title:   title,
+		Message: message,
+		Back:    os.Getenv("FRONTEND_URL") + "/",
+	})
+	if err != nil {
+		log.Printf("Failed to render error page: %v", err)
+	}
+}
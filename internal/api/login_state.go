@@ -0,0 +1,102 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sbraitsch/plotter/internal/model"
+	"golang.org/x/oauth2"
+)
+
+const (
+	LOGIN_STATE_COOKIE = "plotter_oauth_state"
+	LOGIN_STATE_TTL    = 10 * time.Minute
+)
+
+// pendingLogin is everything the callback needs to finish a login the browser started.
+type pendingLogin struct {
+	region   model.Region
+	verifier string
+	returnTo string
+	expires  time.Time
+}
+
+// loginStore holds pending logins by their OAuth state. Each state can be redeemed once.
+type loginStore struct {
+	mu      sync.Mutex
+	pending map[string]pendingLogin
+}
+
+func newLoginStore() *loginStore {
+	return &loginStore{pending: make(map[string]pendingLogin)}
+}
+
+func (s *loginStore) start(region model.Region, returnTo string) (string, pendingLogin, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", pendingLogin{}, fmt.Errorf("failed to generate login state: %w", err)
+	}
+	state := base64.RawURLEncoding.EncodeToString(raw)
+	login := pendingLogin{
+		region:   region,
+		verifier: oauth2.GenerateVerifier(),
+		returnTo: returnTo,
+		expires:  time.Now().Add(LOGIN_STATE_TTL),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, p := range s.pending {
+		if now.After(p.expires) {
+			delete(s.pending, key)
+		}
+	}
+	s.pending[state] = login
+	return state, login, nil
+}
+
+func (s *loginStore) finish(state string) (pendingLogin, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	login, exists := s.pending[state]
+	delete(s.pending, state)
+	if !exists || time.Now().After(login.expires) {
+		return pendingLogin{}, false
+	}
+	return login, true
+}
+
+// parseReturnTo only accepts paths on the frontend, so the login can't be turned into an open redirect.
+func parseReturnTo(returnTo string) (string, error) {
+	if returnTo == "" {
+		return "", nil
+	}
+	u, err := url.Parse(returnTo)
+	if err != nil || u.Scheme != "" || u.Host != "" || !strings.HasPrefix(u.Path, "/") ||
+		strings.HasPrefix(returnTo, "//") || strings.Contains(returnTo, "\\") {
+		return "", fmt.Errorf("invalid return path %q", returnTo)
+	}
+	return returnTo, nil
+}
+
+// loginCookiePath is where the login lives for the browser, next to the callback. Behind a
+// proxy that is under the proxy's prefix, so it is taken from the redirect URL.
+func loginCookiePath(redirectURL string) string {
+	u, err := url.Parse(redirectURL)
+	if err != nil {
+		return "/"
+	}
+	path := strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), "/callback")
+	if path == "" {
+		return "/"
+	}
+	return path
+}
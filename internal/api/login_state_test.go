@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sbraitsch/plotter/internal/model"
+	"github.com/sbraitsch/plotter/internal/service/oauth"
+)
+
+func TestLoginCookiePath(t *testing.T) {
+	cases := map[string]string{
+		"http://localhost:8080/auth/bnet/callback":              "/auth/bnet",
+		"https://plotter.sbraitsch.dev/api/auth/bnet/callback":  "/api/auth/bnet",
+		"https://plotter.sbraitsch.dev/api/auth/bnet/callback/": "/api/auth/bnet",
+		"https://plotter.sbraitsch.dev/callback":                "/",
+		"https://plotter.sbraitsch.dev":                         "/",
+	}
+	for redirectURL, want := range cases {
+		if got := loginCookiePath(redirectURL); got != want {
+			t.Errorf("loginCookiePath(%q) = %q, want %q", redirectURL, got, want)
+		}
+	}
+}
+
+func TestLoginStateCookieBehindPrefix(t *testing.T) {
+	bnet := oauth.NewProvider("client", "secret", "https://plotter.sbraitsch.dev/api/auth/bnet/callback", model.DEFAULT_BNET_ENDPOINTS)
+	api := NewAuthAPI(nil, bnet).(*authAPIImpl)
+
+	w := httptest.NewRecorder()
+	api.battleNetLogin(w, httptest.NewRequest(http.MethodGet, "/auth/bnet/login", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Path != "/api/auth/bnet" {
+		t.Fatalf("want the state cookie under the proxy prefix, got %v", cookies)
+	}
+
+	callback := httptest.NewRequest(http.MethodGet, "/auth/bnet/callback?error=access_denied", nil)
+	w = httptest.NewRecorder()
+	api.battleNetCallback(w, callback)
+	cleared := w.Result().Cookies()
+	if len(cleared) != 1 || cleared[0].Path != "/api/auth/bnet" || cleared[0].MaxAge >= 0 {
+		t.Errorf("want the state cookie cleared under the same path, got %v", cleared)
+	}
+}
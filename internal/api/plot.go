@@ -0,0 +1,42 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/sbraitsch/plotter/internal/service"
+	"github.com/sbraitsch/plotter/internal/storage"
+)
+
+type PlotAPI interface {
+	Routes() chi.Router
+}
+
+type plotAPIImpl struct {
+	service service.PlotService
+}
+
+func NewPlotAPI(storage storage.Store) PlotAPI {
+	return &plotAPIImpl{service: service.NewPlotService(storage)}
+}
+
+func (api *plotAPIImpl) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", api.listPlots)
+
+	return r
+}
+
+func (api *plotAPIImpl) listPlots(w http.ResponseWriter, r *http.Request) {
+	plots, err := api.service.GetPlots(r.Context())
+	if err != nil {
+		log.Printf("Failed to get plot catalog: %v", err)
+		http.Error(w, "Failed to get plot catalog", http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, plots)
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/sbraitsch/plotter/internal/service/oauth"
+)
+
+// ReauthResponse tells the frontend to send the browser through the login again.
+// A redirect would be followed by fetch and end up as an opaque cross-origin error.
+type ReauthResponse struct {
+	Error    string `json:"error"`
+	LoginURL string `json:"loginUrl"`
+}
+
+// renderReauthRequired answers with 401 if err means the Battle.net token is gone for good.
+func renderReauthRequired(w http.ResponseWriter, r *http.Request, bnet *oauth.Provider, err error) bool {
+	var tokenErr *oauth.TokenExpiredError
+	if !errors.As(err, &tokenErr) {
+		return false
+	}
+	render.Status(r, http.StatusUnauthorized)
+	render.JSON(w, r, ReauthResponse{
+		Error:    "reauth_required",
+		LoginURL: bnet.LoginURL(tokenErr.Region, ""),
+	})
+	return true
+}
@@ -7,9 +7,10 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"golang.org/x/oauth2"
 
 	"github.com/sbraitsch/plotter/internal/middleware"
+	"github.com/sbraitsch/plotter/internal/model"
+	"github.com/sbraitsch/plotter/internal/service/oauth"
 	"github.com/sbraitsch/plotter/internal/storage"
 )
 
@@ -18,37 +19,28 @@ type Config struct {
 	Port         string
 	ClientId     string
 	ClientSecret string
+	Bnet         model.BnetEndpoints
 }
 
 type Server struct {
-	DB    *pgxpool.Pool
-	Oauth *oauth2.Config
+	DB   *pgxpool.Pool
+	Bnet *oauth.Provider
 }
 
 func NewServer(db *pgxpool.Pool, cfg Config) Server {
-	bnetOAuthConfig := &oauth2.Config{
-		ClientID:     cfg.ClientId,
-		ClientSecret: cfg.ClientSecret,
-		RedirectURL:  os.Getenv("REDIRECT_URL"),
-		Endpoint: oauth2.Endpoint{
-			AuthURL:  "https://eu.battle.net/oauth/authorize",
-			TokenURL: "https://eu.battle.net/oauth/token",
-		},
-		Scopes: []string{"wow.profile"},
-	}
-
-	return Server{DB: db, Oauth: bnetOAuthConfig}
-
+	bnet := oauth.NewProvider(cfg.ClientId, cfg.ClientSecret, os.Getenv("REDIRECT_URL"), cfg.Bnet)
+	return Server{DB: db, Bnet: bnet}
 }
 
 func (s *Server) Router() http.Handler {
 	r := chi.NewRouter()
 
+	// PUT sets the preference deadline and the community status
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://plotter.sbraitsch.dev", "http://localhost:3000"}, // Production
-		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Token", "If-Match", middleware.COMMUNITY_HEADER},
+		ExposedHeaders:   []string{"Link", "ETag"},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
@@ -58,9 +50,10 @@ func (s *Server) Router() http.Handler {
 	storageClient := storage.NewStorageClient(s.DB)
 	tokenMiddleware := middleware.TokenAuth(storageClient)
 	adminMiddleware := middleware.AdminAuth(storageClient)
-	userAPI := NewUserAPI(storageClient)
-	communityAPI := NewCommunityAPI(storageClient)
-	authApi := NewAuthAPI(storageClient, s.Oauth)
+	userAPI := NewUserAPI(storageClient, s.Bnet)
+	communityAPI := NewCommunityAPI(storageClient, s.Bnet)
+	authApi := NewAuthAPI(storageClient, s.Bnet)
+	plotAPI := NewPlotAPI(storageClient)
 
 	r.Route("/user", func(r chi.Router) {
 		r.Use(tokenMiddleware)
@@ -71,8 +64,13 @@ func (s *Server) Router() http.Handler {
 		r.Mount("/", communityAPI.Routes(tokenMiddleware, adminMiddleware))
 	})
 
+	r.Route("/plots", func(r chi.Router) {
+		r.Mount("/", plotAPI.Routes())
+	})
+
 	r.Route("/auth", func(r chi.Router) {
 		r.Mount("/bnet", authApi.Routes(tokenMiddleware))
+		r.Mount("/", authApi.SessionRoutes(tokenMiddleware))
 	})
 
 	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) {
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/sbraitsch/plotter/internal/model"
+)
+
+// StatusConflictResponse tells the frontend which status of the community refused a change.
+type StatusConflictResponse struct {
+	Error  string                `json:"error"`
+	Status model.CommunityStatus `json:"status"`
+}
+
+// renderStatusConflict answers with 409 if err means the community's status doesn't allow the change.
+func renderStatusConflict(w http.ResponseWriter, r *http.Request, err error) bool {
+	var statusErr *model.StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	render.Status(r, http.StatusConflict)
+	render.JSON(w, r, StatusConflictResponse{
+		Error:  statusErr.Error(),
+		Status: statusErr.Status,
+	})
+	return true
+}
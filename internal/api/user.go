@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
@@ -8,6 +9,7 @@ import (
 	"github.com/go-chi/render"
 	"github.com/sbraitsch/plotter/internal/model"
 	"github.com/sbraitsch/plotter/internal/service"
+	"github.com/sbraitsch/plotter/internal/service/oauth"
 	"github.com/sbraitsch/plotter/internal/storage"
 )
 
@@ -19,8 +21,8 @@ type userAPIImpl struct {
 	service service.UserService
 }
 
-func NewUserAPI(storage *storage.StorageClient) UserAPI {
-	return &userAPIImpl{service: service.NewUserService(storage)}
+func NewUserAPI(storage storage.Store, bnet *oauth.Provider) UserAPI {
+	return &userAPIImpl{service: service.NewUserService(storage, bnet)}
 }
 
 func (api *userAPIImpl) Routes() chi.Router {
@@ -28,10 +30,23 @@ func (api *userAPIImpl) Routes() chi.Router {
 
 	r.Get("/validate", api.validate)
 	r.Post("/update", api.updatePlayerData)
+	r.Post("/character", api.setCharacter)
+	r.Get("/sessions", api.listSessions)
 
 	return r
 }
 
+func (api *userAPIImpl) listSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := api.service.GetSessions(r.Context())
+	if err != nil {
+		log.Printf("Failed to list sessions: %v", err)
+		http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, sessions)
+}
+
 func (api *userAPIImpl) validate(w http.ResponseWriter, r *http.Request) {
 	player, err := api.service.Validate(r.Context())
 	if err != nil {
@@ -43,6 +58,26 @@ func (api *userAPIImpl) validate(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, player)
 }
 
+func (api *userAPIImpl) setCharacter(w http.ResponseWriter, r *http.Request) {
+	req := &model.CharacterRequest{}
+
+	if err := render.Decode(r, req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	char, err := api.service.SetCharacter(r.Context(), req.Char)
+	if renderStatusConflict(w, r, err) {
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to switch character: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	render.JSON(w, r, char)
+}
+
 func (api *userAPIImpl) updatePlayerData(w http.ResponseWriter, r *http.Request) {
 	req := &model.PlayerUpdateRequest{}
 
@@ -51,13 +86,39 @@ func (api *userAPIImpl) updatePlayerData(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := api.service.SetNote(r.Context(), req.Note); err != nil {
+	err := api.service.SetNote(r.Context(), req.Note)
+	if renderStatusConflict(w, r, err) {
+		return
+	}
+	if err != nil {
 		http.Error(w, "Failed to update player note", http.StatusInternalServerError)
 		return
 	}
 
+	if req.Neighbors != nil {
+		err := api.service.SetNeighbors(r.Context(), req.Neighbors)
+		if renderStatusConflict(w, r, err) {
+			return
+		}
+		if errors.Is(err, service.ErrPreferencesClosed) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to update neighbor wishes: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	updated, err := api.service.UpdateMappings(r.Context(), req.PlotData)
 
+	if renderStatusConflict(w, r, err) {
+		return
+	}
+	if errors.Is(err, service.ErrPreferencesClosed) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to update player data", http.StatusInternalServerError)
 		return
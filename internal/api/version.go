@@ -0,0 +1,134 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/render"
+	"github.com/sbraitsch/plotter/internal/middleware"
+	"github.com/sbraitsch/plotter/internal/model"
+	"github.com/sbraitsch/plotter/internal/storage"
+)
+
+// VersionConflictResponse carries the current state of a community an outdated edit was refused for.
+type VersionConflictResponse struct {
+	Error string `json:"error"`
+	*model.CommunityState
+}
+
+func etag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// parseIfMatch reads the version an edit was based on. "*" matches any version.
+func parseIfMatch(header string) (*int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return nil, nil
+	}
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid If-Match %q", header)
+	}
+	return &version, nil
+}
+
+// setETag tags a response with the version of the community it was read from.
+// It has to run before the read, so the tag is never newer than the data.
+func (api *communityAPIImpl) setETag(w http.ResponseWriter, r *http.Request, communityId string) bool {
+	version, err := api.service.GetVersion(r.Context(), communityId)
+	if err != nil {
+		http.Error(w, "Failed to read community version", http.StatusInternalServerError)
+		return false
+	}
+	w.Header().Set("ETag", etag(version))
+	return true
+}
+
+// requireVersion only lets an edit through if it is based on the current version of the community.
+// The version is compared and moved inside the transaction of every change the edit makes, so an
+// edit that fails leaves it alone. Outdated edits are answered with 409 and the current state, the
+// response to a successful one carries the new version.
+func (api *communityAPIImpl) requireVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(middleware.CtxUser).(*model.User)
+		header := r.Header.Get("If-Match")
+		if header == "" {
+			http.Error(w, "If-Match header required", http.StatusPreconditionRequired)
+			return
+		}
+		expected, err := parseIfMatch(header)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// spares outdated edits the work, the transactions of the edit have the final word
+		version, err := api.service.GetVersion(r.Context(), user.Community.Id)
+		if err != nil {
+			http.Error(w, "Failed to check community version", http.StatusInternalServerError)
+			return
+		}
+		if expected != nil && *expected != version {
+			api.renderVersionConflict(w, r, user.Community.Id)
+			return
+		}
+
+		ctx, claim := storage.WithVersionClaim(r.Context(), expected)
+		vw := &versionedWriter{ResponseWriter: w, claim: claim}
+		next.ServeHTTP(vw, r.WithContext(ctx))
+		if claim.Conflicted {
+			api.renderVersionConflict(w, r, user.Community.Id)
+		}
+	})
+}
+
+// versionedWriter tags the response of an edit with the version it left the community at.
+// The response of an edit that lost to another one is dropped for the conflict.
+type versionedWriter struct {
+	http.ResponseWriter
+	claim       *storage.VersionClaim
+	wroteHeader bool
+}
+
+func (w *versionedWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if w.claim.Conflicted {
+		return
+	}
+	if w.claim.Version != 0 {
+		w.Header().Set("ETag", etag(w.claim.Version))
+	} else if w.claim.Expected != nil {
+		w.Header().Set("ETag", etag(*w.claim.Expected))
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *versionedWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.claim.Conflicted {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (api *communityAPIImpl) renderVersionConflict(w http.ResponseWriter, r *http.Request, communityId string) {
+	state, err := api.service.GetCommunityState(r.Context(), communityId)
+	if err != nil {
+		log.Printf("Failed to read state of community %s: %v", communityId, err)
+		http.Error(w, storage.ErrVersionConflict.Error(), http.StatusConflict)
+		return
+	}
+	w.Header().Set("ETag", etag(state.Version))
+	render.Status(r, http.StatusConflict)
+	render.JSON(w, r, VersionConflictResponse{
+		Error:          storage.ErrVersionConflict.Error(),
+		CommunityState: state,
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/sbraitsch/plotter/internal/api"
+	"github.com/sbraitsch/plotter/internal/model"
 )
 
 func Load() api.Config {
@@ -13,6 +14,10 @@ func Load() api.Config {
 		Port:         os.Getenv("PORT"),
 		ClientId:     os.Getenv("CLIENT_ID"),
 		ClientSecret: os.Getenv("CLIENT_SECRET"),
+		Bnet: model.BnetEndpoints{
+			OAuthURL: os.Getenv("BNET_OAUTH_URL"),
+			APIURL:   os.Getenv("BNET_API_URL"),
+		},
 	}
 
 	if cfg.DbUrl == "" {
@@ -21,6 +26,12 @@ func Load() api.Config {
 	if cfg.Port == "" {
 		cfg.Port = "8080"
 	}
+	if cfg.Bnet.OAuthURL == "" {
+		cfg.Bnet.OAuthURL = model.DEFAULT_BNET_ENDPOINTS.OAuthURL
+	}
+	if cfg.Bnet.APIURL == "" {
+		cfg.Bnet.APIURL = model.DEFAULT_BNET_ENDPOINTS.APIURL
+	}
 
 	return cfg
 }
@@ -2,10 +2,10 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 
-	"github.com/jackc/pgx/v5"
 	"github.com/sbraitsch/plotter/internal/model"
 	"github.com/sbraitsch/plotter/internal/storage"
 )
@@ -14,9 +14,11 @@ type contextKey string
 
 const (
 	CtxUser contextKey = "user"
+	// COMMUNITY_HEADER selects which of the user's communities a request acts in
+	COMMUNITY_HEADER = "X-Community"
 )
 
-func TokenAuth(storage *storage.StorageClient) func(http.Handler) http.Handler {
+func TokenAuth(store storage.Store) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			token := r.Header.Get("X-Token")
@@ -24,10 +26,11 @@ func TokenAuth(storage *storage.StorageClient) func(http.Handler) http.Handler {
 				http.Error(w, "missing token", http.StatusUnauthorized)
 				return
 			}
-			user, err := storage.GetUserByToken(r.Context(), token)
+			communityId := r.Header.Get(COMMUNITY_HEADER)
+			user, err := store.GetUserByToken(r.Context(), token, communityId)
 
 			if err != nil {
-				if err == pgx.ErrNoRows {
+				if errors.Is(err, storage.ErrNotFound) {
 					http.Error(w, "unauthorized", http.StatusUnauthorized)
 					return
 				}
@@ -36,6 +39,16 @@ func TokenAuth(storage *storage.StorageClient) func(http.Handler) http.Handler {
 				return
 			}
 
+			if communityId != "" && user.Community.Id != communityId {
+				http.Error(w, "forbidden: not a member of this community", http.StatusForbidden)
+				return
+			}
+
+			// sliding expiry, a failed renewal only shortens the session
+			if err := store.TouchSession(r.Context(), user.SessionId); err != nil {
+				log.Printf("Failed to renew session of %s: %v", user.Battletag, err)
+			}
+
 			ctx := context.WithValue(r.Context(), CtxUser, user)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -43,12 +56,13 @@ func TokenAuth(storage *storage.StorageClient) func(http.Handler) http.Handler {
 	}
 }
 
-func AdminAuth(storage *storage.StorageClient) func(http.Handler) http.Handler {
-	tokenAuth := TokenAuth(storage)
+func AdminAuth(store storage.Store) func(http.Handler) http.Handler {
+	tokenAuth := TokenAuth(store)
 	return func(next http.Handler) http.Handler {
 		return tokenAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			user, ok := r.Context().Value(CtxUser).(*model.User)
-			if !ok || (user.CommunityRank > user.Community.OfficerRank) {
+			// officers who left the guild lose their rights with the next roster sync
+			if !ok || user.Departed || (user.CommunityRank > user.Community.OfficerRank) {
 				http.Error(w, "forbidden: admin only", http.StatusForbidden)
 				return
 			}
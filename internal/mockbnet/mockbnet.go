@@ -0,0 +1,226 @@
+// Package mockbnet stands in for the Battle.net OAuth and profile APIs so the
+// login, guild discovery and join flows run locally without network access.
+package mockbnet
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+//go:embed fixtures
+var embedded embed.FS
+
+// DefaultFixtures has three accounts in a single guild on blackmoore.
+func DefaultFixtures() fs.FS {
+	fixtures, err := fs.Sub(embedded, "fixtures")
+	if err != nil {
+		log.Fatalf("Embedded fixtures missing: %v", err)
+	}
+	return fixtures
+}
+
+// User is an account listed in users.json.
+type User struct {
+	Battletag string          `json:"battletag"`
+	Profile   json.RawMessage `json:"profile"`
+}
+
+// Server answers from a fixture tree. users.json lists the accounts, every
+// other API response is read from the file mirroring its path, e.g.
+// profile/wow/character/{realm}/{name}.json or data/wow/guild/{realm}/{guild}/roster.json.
+type Server struct {
+	fixtures fs.FS
+	users    map[string]User
+	order    []string
+
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+func NewServer(fixtures fs.FS) (*Server, error) {
+	raw, err := fs.ReadFile(fixtures, "users.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users.json: %w", err)
+	}
+	var users []User
+	if err := json.Unmarshal(raw, &users); err != nil {
+		return nil, fmt.Errorf("failed to parse users.json: %w", err)
+	}
+	if len(users) == 0 {
+		return nil, fmt.Errorf("users.json lists no accounts")
+	}
+
+	s := &Server{fixtures: fixtures, users: make(map[string]User), tokens: make(map[string]string)}
+	for _, u := range users {
+		s.users[u.Battletag] = u
+		s.order = append(s.order, u.Battletag)
+	}
+	return s, nil
+}
+
+func (s *Server) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/oauth/authorize", s.authorize)
+	r.Post("/oauth/token", s.token)
+	r.Get("/oauth/userinfo", s.userinfo)
+	r.Get("/profile/user/wow", s.profile)
+	r.Get("/profile/wow/character/{realm}/{name}", s.fixture)
+	r.Get("/data/wow/guild/{realm}/{guild}/roster", s.fixture)
+
+	return r
+}
+
+var accountPicker = template.Must(template.New("accounts").Parse(`<!DOCTYPE html>
+<html><body>
+<h3>Mock Battle.net: log in as</h3>
+<ul>{{range .}}<li><a href="{{.URL}}">{{.Battletag}}</a></li>{{end}}</ul>
+</body></html>`))
+
+// authorize skips the login form. With several accounts it lists them, picking one
+// redirects back with the battletag as the authorization code.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	battletag := query.Get("user")
+	if battletag == "" && len(s.order) == 1 {
+		battletag = s.order[0]
+	}
+
+	if battletag == "" {
+		type choice struct{ Battletag, URL string }
+		choices := make([]choice, 0, len(s.order))
+		for _, btag := range s.order {
+			q := r.URL.Query()
+			q.Set("user", btag)
+			choices = append(choices, choice{Battletag: btag, URL: r.URL.Path + "?" + q.Encode()})
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		accountPicker.Execute(w, choices)
+		return
+	}
+
+	if _, exists := s.users[battletag]; !exists {
+		http.Error(w, "unknown account", http.StatusNotFound)
+		return
+	}
+
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirect.String() == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	params := redirect.Query()
+	params.Set("code", battletag)
+	params.Set("state", query.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	var battletag string
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		battletag = r.PostForm.Get("code")
+		if _, exists := s.users[battletag]; !exists {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+	case "client_credentials":
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	accessToken := uuid.New().String()
+	s.mu.Lock()
+	s.tokens[accessToken] = battletag
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": accessToken,
+		"token_type":   "bearer",
+		"expires_in":   86399,
+	})
+}
+
+func (s *Server) userinfo(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.authorizedUser(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"battletag": user.Battletag})
+}
+
+func (s *Server) profile(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.authorizedUser(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(user.Profile)
+}
+
+// fixture serves the file mirroring the request path. Namespace and locale are ignored,
+// so one fixture tree answers for every region.
+func (s *Server) fixture(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.bearer(w, r); !ok {
+		return
+	}
+
+	name := strings.ToLower(strings.TrimPrefix(path.Clean(r.URL.Path), "/")) + ".json"
+	raw, err := fs.ReadFile(s.fixtures, name)
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(raw)
+}
+
+func (s *Server) bearer(w http.ResponseWriter, r *http.Request) (string, bool) {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	s.mu.Lock()
+	battletag, known := s.tokens[token]
+	s.mu.Unlock()
+	if !found || !known {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return "", false
+	}
+	return battletag, true
+}
+
+func (s *Server) authorizedUser(w http.ResponseWriter, r *http.Request) (User, bool) {
+	battletag, ok := s.bearer(w, r)
+	if !ok {
+		return User{}, false
+	}
+	user, exists := s.users[battletag]
+	if !exists {
+		http.Error(w, "token has no user", http.StatusForbidden)
+		return User{}, false
+	}
+	return user, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
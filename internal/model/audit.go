@@ -0,0 +1,92 @@
+package model
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AUDIT_MEMBER_JOINED        AuditAction = "member_joined"
+	AUDIT_MEMBER_LEFT          AuditAction = "member_left"
+	AUDIT_MEMBER_REMOVED       AuditAction = "member_removed"
+	AUDIT_JOIN_REQUESTED       AuditAction = "join_requested"
+	AUDIT_JOIN_APPROVED        AuditAction = "join_approved"
+	AUDIT_JOIN_DENIED          AuditAction = "join_denied"
+	AUDIT_COMMUNITY_LOCKED     AuditAction = "community_locked"
+	AUDIT_COMMUNITY_UNLOCKED   AuditAction = "community_unlocked"
+	AUDIT_COMMUNITY_FINALIZED  AuditAction = "community_finalized"
+	AUDIT_COMMUNITY_REOPENED   AuditAction = "community_reopened"
+	AUDIT_COMMUNITY_ARCHIVED   AuditAction = "community_archived"
+	AUDIT_COMMUNITY_UNARCHIVED AuditAction = "community_unarchived"
+	AUDIT_SETTINGS_CHANGED     AuditAction = "settings_changed"
+	AUDIT_DEADLINE_CHANGED     AuditAction = "deadline_changed"
+	AUDIT_ASSIGNMENT_SET       AuditAction = "assignment_set"
+	AUDIT_ASSIGNMENTS_UPLOADED AuditAction = "assignments_uploaded"
+	AUDIT_REVISION_RESTORED    AuditAction = "revision_restored"
+	AUDIT_MEMBER_PINNED        AuditAction = "member_pinned"
+	AUDIT_MEMBER_UNPINNED      AuditAction = "member_unpinned"
+	AUDIT_PLOT_RESERVED        AuditAction = "plot_reserved"
+	AUDIT_PLOT_RELEASED        AuditAction = "plot_released"
+	AUDIT_PREFERENCES_CHANGED  AuditAction = "preferences_changed"
+	AUDIT_NEIGHBORS_CHANGED    AuditAction = "neighbors_changed"
+	AUDIT_NOTE_CHANGED         AuditAction = "note_changed"
+	AUDIT_CHARACTER_CHANGED    AuditAction = "character_changed"
+)
+
+// REJOIN_BLOCK is how long a member removed with a tombstone is kept from joining again.
+const REJOIN_BLOCK = 14 * 24 * time.Hour
+
+const (
+	AUDIT_PAGE_SIZE     = 100
+	AUDIT_MAX_PAGE_SIZE = 500
+)
+
+// AuditEvent records who changed what in a community. Before and After hold
+// the changed state as JSON, either may be missing when there is nothing to show.
+type AuditEvent struct {
+	Id        int             `json:"id"`
+	Actor     string          `json:"actor"`
+	Action    AuditAction     `json:"action"`
+	Target    string          `json:"target,omitempty"`
+	Detail    string          `json:"detail,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// AuditState encodes the state before or after a change, nil when there is none.
+func AuditState(state any) json.RawMessage {
+	if state == nil {
+		return nil
+	}
+	raw, err := json.Marshal(state)
+	if err != nil {
+		log.Printf("Failed to encode audit state: %v", err)
+		return nil
+	}
+	if string(raw) == "null" {
+		return nil
+	}
+	return raw
+}
+
+// AuditFilter narrows down the audit log. Zero values don't filter.
+type AuditFilter struct {
+	Actor  string
+	Target string
+	Action AuditAction
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+}
+
+func (f *AuditFilter) Matches(e *AuditEvent) bool {
+	return (f.Actor == "" || e.Actor == f.Actor) &&
+		(f.Target == "" || e.Target == f.Target) &&
+		(f.Action == "" || e.Action == f.Action) &&
+		(f.Since.IsZero() || !e.CreatedAt.Before(f.Since)) &&
+		(f.Until.IsZero() || e.CreatedAt.Before(f.Until))
+}
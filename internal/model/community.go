@@ -1,21 +1,51 @@
 package model
 
+import "time"
+
 type CommunityData struct {
-	Id      string       `json:"id"`
-	Members []MemberData `json:"members"`
+	Id             string         `json:"id"`
+	Members        []MemberData   `json:"members"`
+	NeighborWeight int            `json:"neighborWeight"`
+	Neighborhoods  int            `json:"neighborhoods"`
+	MovePenalty    int            `json:"movePenalty"`
+	Objective      Objective      `json:"objective"`
+	RankWeighting  RankWeighting  `json:"rankWeighting"`
+	Plots          []Plot         `json:"-"`
+	Pins           []Pin          `json:"-"`
+	Reserved       []ReservedPlot `json:"-"`
+	Previous       []Assignment   `json:"-"`
 }
 
 type MemberData struct {
-	Character string      `json:"char"`
-	BattleTag string      `json:"battletag"`
-	PlotData  map[int]int `json:"plotData"`
+	Character string          `json:"char"`
+	BattleTag string          `json:"battletag"`
+	Rank      int             `json:"rank"`
+	PlotData  map[PlotKey]int `json:"plotData"`
+	Neighbors []string        `json:"neighbors,omitempty"`
 }
 
 type Community struct {
-	Id     string `json:"id"`
-	Name   string `json:"name"`
-	Realm  string `json:"realm"`
-	Locked bool   `json:"locked"`
+	Id            string          `json:"id"`
+	Name          string          `json:"name"`
+	Realm         string          `json:"realm"`
+	Region        Region          `json:"region"`
+	Status        CommunityStatus `json:"status"`
+	Neighborhoods int             `json:"neighborhoods"`
+}
+
+// Character is one of the account's characters found on the guild roster.
+type Character struct {
+	Name  string `json:"name"`
+	Realm string `json:"realm,omitempty"`
+	Rank  int    `json:"rank"`
+}
+
+// JoinedCommunity is the character picked on joining and the ones to switch to.
+// Request is set instead when the user still has to be approved by an officer.
+type JoinedCommunity struct {
+	Char       string       `json:"char"`
+	Characters []Character  `json:"characters"`
+	Request    *JoinRequest `json:"request,omitempty"`
 }
 
 type Roster struct {
@@ -28,28 +58,107 @@ type member struct {
 }
 
 type character struct {
-	Name string `json:"name"`
+	Name  string `json:"name"`
+	Realm Realm  `json:"realm"`
 }
 
 type Assignment struct {
-	Character string `json:"char"`
-	Battletag string `json:"btag"`
-	Plot      int    `json:"plot"`
-	Score     int    `json:"score"`
+	Character    string `json:"char"`
+	Battletag    string `json:"btag"`
+	Neighborhood int    `json:"neighborhood"`
+	Plot         int    `json:"plot"`
+	Score        int    `json:"score"`
+	Source       string `json:"source,omitempty"`
 }
 
 type Settings struct {
-	OfficerRank int `json:"officerRank"`
-	MemberRank  int `json:"memberRank"`
+	OfficerRank    int           `json:"officerRank"`
+	MemberRank     int           `json:"memberRank"`
+	NeighborWeight int           `json:"neighborWeight"`
+	Neighborhoods  int           `json:"neighborhoods"`
+	MovePenalty    int           `json:"movePenalty"`
+	Objective      Objective     `json:"objective"`
+	RankWeighting  RankWeighting `json:"rankWeighting"`
+	JoinRequests   bool          `json:"joinRequests"`
+	// PreferenceDeadline is set with its own endpoint, so it can be cleared.
+	PreferenceDeadline *time.Time `json:"preferenceDeadline,omitempty"`
 }
 
+// With returns the settings as a request changes them, fields missing from it are kept.
+func (s Settings) With(req *CommunityRankRequest) Settings {
+	s.OfficerRank, s.MemberRank = req.AdminRank, req.MemberRank
+	if req.NeighborWeight != nil {
+		s.NeighborWeight = *req.NeighborWeight
+	}
+	if req.Neighborhoods != nil {
+		s.Neighborhoods = *req.Neighborhoods
+	}
+	if req.MovePenalty != nil {
+		s.MovePenalty = *req.MovePenalty
+	}
+	if req.Objective != nil {
+		s.Objective = *req.Objective
+	}
+	if req.RankWeighting != nil {
+		s.RankWeighting = *req.RankWeighting
+	}
+	if req.JoinRequests != nil {
+		s.JoinRequests = *req.JoinRequests
+	}
+	return s
+}
+
+// FullCommunityData is the download format. Besides members it carries
+// everything the optimizer needs, so a download can be solved offline.
 type FullCommunityData struct {
-	Id      string           `json:"id"`
-	Members []FullMemberData `json:"members"`
+	Id       string           `json:"id"`
+	Members  []FullMemberData `json:"members"`
+	Settings *Settings        `json:"settings,omitempty"`
+	Plots    []Plot           `json:"plots,omitempty"`
+	Pins     []Pin            `json:"pins,omitempty"`
+	Reserved []ReservedPlot   `json:"reserved,omitempty"`
 }
 
 type FullMemberData struct {
-	Assignment Assignment  `json:"assignment"`
-	Note       string      `json:"note"`
-	PlotData   map[int]int `json:"plotSelection"`
+	Assignment Assignment      `json:"assignment"`
+	Note       string          `json:"note"`
+	Rank       int             `json:"rank"`
+	PlotData   map[PlotKey]int `json:"plotSelection"`
+	Neighbors  []string        `json:"neighbors,omitempty"`
+}
+
+// CommunityData converts a download back into optimizer input. Existing
+// assignments become the previous result for stable re-optimization.
+func (data *FullCommunityData) CommunityData() *CommunityData {
+	community := &CommunityData{
+		Id:       data.Id,
+		Members:  make([]MemberData, 0, len(data.Members)),
+		Plots:    data.Plots,
+		Pins:     data.Pins,
+		Reserved: data.Reserved,
+	}
+
+	if data.Settings != nil {
+		community.NeighborWeight = data.Settings.NeighborWeight
+		community.Neighborhoods = data.Settings.Neighborhoods
+		community.MovePenalty = data.Settings.MovePenalty
+		community.Objective = data.Settings.Objective
+		community.RankWeighting = data.Settings.RankWeighting
+	}
+
+	for _, m := range data.Members {
+		community.Members = append(community.Members, MemberData{
+			Character: m.Assignment.Character,
+			BattleTag: m.Assignment.Battletag,
+			Rank:      m.Rank,
+			PlotData:  m.PlotData,
+			Neighbors: m.Neighbors,
+		})
+		if m.Assignment.Plot > 0 {
+			previous := m.Assignment
+			previous.Neighborhood = max(previous.Neighborhood, 1)
+			community.Previous = append(community.Previous, previous)
+		}
+	}
+	return community
 }
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+type PreferenceStatus string
+
+const (
+	PREFERENCES_OPEN   PreferenceStatus = "open"
+	PREFERENCES_CLOSED PreferenceStatus = "closed"
+)
+
+// SCHEDULER_ACTOR stands in for the battletag on changes nobody made by hand.
+// Battletags always carry a '#', so it can't collide with a user.
+const SCHEDULER_ACTOR = "scheduler"
+
+// PreferenceWindow tells members whether they can still change their preferences and for how long.
+type PreferenceWindow struct {
+	Status      PreferenceStatus `json:"status"`
+	Deadline    *time.Time       `json:"deadline,omitempty"`
+	SecondsLeft int64            `json:"secondsLeft"`
+}
+
+// NewPreferenceWindow is the window of a community with the given deadline, nil for none.
+func NewPreferenceWindow(deadline *time.Time, now time.Time) PreferenceWindow {
+	window := PreferenceWindow{Status: PREFERENCES_OPEN, Deadline: deadline}
+	if deadline == nil {
+		return window
+	}
+	if !now.Before(*deadline) {
+		window.Status = PREFERENCES_CLOSED
+		return window
+	}
+	window.SecondsLeft = int64(deadline.Sub(now).Seconds())
+	return window
+}
+
+// PreferencesOpen is whether a community with the given deadline still takes preference changes.
+func PreferencesOpen(deadline *time.Time, now time.Time) bool {
+	return deadline == nil || now.Before(*deadline)
+}
@@ -0,0 +1,148 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+)
+
+// MAX_EXPLAINED_ALTERNATIVES limits how many better-ranked plots are re-solved per member.
+const MAX_EXPLAINED_ALTERNATIVES = 5
+
+type MemberExplanation struct {
+	Battletag    string            `json:"btag"`
+	Character    string            `json:"char"`
+	Neighborhood int               `json:"neighborhood"`
+	Plot         int               `json:"plot"`
+	Rank         int               `json:"rank"`
+	Pinned       bool              `json:"pinned"`
+	Alternatives []PlotExplanation `json:"alternatives"`
+}
+
+// PlotExplanation tells a member why they did not get a plot they ranked higher.
+type PlotExplanation struct {
+	Neighborhood int    `json:"neighborhood"`
+	Plot         int    `json:"plot"`
+	Rank         int    `json:"rank"`
+	HeldBy       string `json:"heldBy,omitempty"`
+	HeldByChar   string `json:"heldByChar,omitempty"`
+	Reason       string `json:"reason"`
+	// CostIncrease is how much the community's total rank would grow if the
+	// member had been given this plot and everyone else re-solved around it.
+	CostIncrease int `json:"costIncrease"`
+}
+
+// Explain re-solves the community once per better-ranked plot of each member,
+// forcing them onto it, and reports who holds the plot and what it would cost.
+// A non-empty battletag restricts the report to that member.
+func (community *CommunityData) Explain(assignments []Assignment, options OptimizeOptions, battletag string) []MemberExplanation {
+	p := newProblem(community, options)
+
+	members := make(map[string]*MemberData, len(community.Members))
+	for i := range community.Members {
+		members[community.Members[i].BattleTag] = &community.Members[i]
+	}
+
+	// forced re-solves are compared to an unforced one, the stored assignments may be
+	// manual or out of date and would not be comparable
+	baseline := community.Optimize(options).Stats.RankSum
+
+	// manual assignments carry no score, so ranks are recomputed from preferences
+	placed := make(map[string]Assignment, len(assignments))
+	holders := make(map[PlotKey]Assignment, len(assignments))
+	for _, a := range assignments {
+		if member, ok := members[a.Battletag]; ok {
+			a.Score = p.rank(member, Slot{Neighborhood: a.Neighborhood, Plot: Plot{Id: a.Plot}})
+		}
+		placed[a.Battletag] = a
+		holders[PlotKey{Neighborhood: a.Neighborhood, Plot: a.Plot}] = a
+	}
+
+	pins := make(map[PlotKey]Pin, len(community.Pins))
+	pinnedMembers := make(map[string]bool, len(community.Pins))
+	for _, pin := range community.Pins {
+		pins[PlotKey{Neighborhood: pin.Neighborhood, Plot: pin.Plot}] = pin
+		pinnedMembers[pin.Battletag] = true
+	}
+	reserved := make(map[PlotKey]ReservedPlot, len(community.Reserved))
+	for _, r := range community.Reserved {
+		reserved[PlotKey{Neighborhood: r.Neighborhood, Plot: r.Plot}] = r
+	}
+
+	explanations := []MemberExplanation{}
+	for _, member := range community.Members {
+		if battletag != "" && member.BattleTag != battletag {
+			continue
+		}
+		current, ok := placed[member.BattleTag]
+		if !ok {
+			continue
+		}
+
+		explanation := MemberExplanation{
+			Battletag:    member.BattleTag,
+			Character:    member.Character,
+			Neighborhood: current.Neighborhood,
+			Plot:         current.Plot,
+			Rank:         current.Score,
+			Pinned:       pinnedMembers[member.BattleTag],
+			Alternatives: []PlotExplanation{},
+		}
+
+		for _, slot := range p.betterSlots(&member, current.Score) {
+			key := PlotKey{Neighborhood: slot.Neighborhood, Plot: slot.Plot.Id}
+			alternative := PlotExplanation{
+				Neighborhood: slot.Neighborhood,
+				Plot:         slot.Plot.Id,
+				Rank:         p.rank(&member, slot),
+			}
+			if holder, ok := holders[key]; ok {
+				alternative.HeldBy = holder.Battletag
+				alternative.HeldByChar = holder.Character
+			}
+
+			switch {
+			case explanation.Pinned:
+				alternative.Reason = "you are pinned to your plot by an officer"
+			case reserved[key].Status != "":
+				alternative.Reason = fmt.Sprintf("plot is %s", reserved[key].Status)
+			case pins[key].Battletag != "":
+				alternative.Reason = fmt.Sprintf("plot is pinned to %s by an officer", pins[key].Battletag)
+			default:
+				forced := community.withMembers(community.Members)
+				forced.Pins = append(forced.Pins, Pin{Battletag: member.BattleTag, Neighborhood: slot.Neighborhood, Plot: slot.Plot.Id})
+				result := forced.Optimize(options)
+				alternative.CostIncrease = result.Stats.RankSum - baseline
+				switch {
+				case alternative.CostIncrease < 0:
+					alternative.Reason = fmt.Sprintf("it lowers the total rank by %d but does worse on the %s objective", -alternative.CostIncrease, result.Objective)
+				case alternative.CostIncrease == 0:
+					alternative.Reason = "an equally good outcome overall; the optimizer broke the tie the other way"
+				case alternative.HeldBy != "":
+					alternative.Reason = fmt.Sprintf("giving it to you instead of %s raises the total rank by %d", alternative.HeldBy, alternative.CostIncrease)
+				default:
+					alternative.Reason = fmt.Sprintf("giving it to you raises the total rank by %d", alternative.CostIncrease)
+				}
+			}
+			explanation.Alternatives = append(explanation.Alternatives, alternative)
+		}
+		explanations = append(explanations, explanation)
+	}
+	return explanations
+}
+
+// betterSlots lists the member's slots ranked above the given rank, best first.
+func (p *problem) betterSlots(member *MemberData, rank int) []Slot {
+	better := []Slot{}
+	for _, slot := range p.slots {
+		if w, ok := member.Priority(slot.Neighborhood, slot.Plot.Id); ok && w < rank {
+			better = append(better, slot)
+		}
+	}
+	slices.SortStableFunc(better, func(a, b Slot) int {
+		return p.rank(member, a) - p.rank(member, b)
+	})
+	if len(better) > MAX_EXPLAINED_ALTERNATIVES {
+		better = better[:MAX_EXPLAINED_ALTERNATIVES]
+	}
+	return better
+}
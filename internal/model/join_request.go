@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+const (
+	JOIN_REQUEST_PENDING  = "pending"
+	JOIN_REQUEST_APPROVED = "approved"
+	JOIN_REQUEST_DENIED   = "denied"
+)
+
+// JoinRequest is filed for a user who doesn't meet the member rank of a community
+// that accepts requests. Once approved, the user joins like everyone else.
+type JoinRequest struct {
+	Battletag   string    `json:"battletag"`
+	Character   string    `json:"char,omitempty"`
+	Rank        *int      `json:"rank,omitempty"`
+	Status      string    `json:"status"`
+	RequestedAt time.Time `json:"requestedAt"`
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MAX_NEIGHBORHOODS caps the instances of a community, each one adds a full set of slots
+// to every optimization.
+const MAX_NEIGHBORHOODS = 50
+
+// ErrInvalidNeighborhood is returned for a neighborhood a community can't grow to.
+var ErrInvalidNeighborhood = errors.New("invalid neighborhood")
+
+// CheckNeighborhood refuses a neighborhood beyond the next new instance of a community
+// with count neighborhoods, so a single edit can't open hundreds of empty ones.
+func CheckNeighborhood(neighborhood, count int) error {
+	if neighborhood < 1 || neighborhood > min(max(count, 1)+1, MAX_NEIGHBORHOODS) {
+		return fmt.Errorf("%w %d, the community has %d", ErrInvalidNeighborhood, neighborhood, count)
+	}
+	return nil
+}
+
+// PlotKey addresses a plot preference. Neighborhood 0 matches the plot in
+// every neighborhood, so plain plot ids stay valid JSON keys.
+type PlotKey struct {
+	Neighborhood int
+	Plot         int
+}
+
+func (k PlotKey) MarshalText() ([]byte, error) {
+	if k.Neighborhood == 0 {
+		return []byte(strconv.Itoa(k.Plot)), nil
+	}
+	return fmt.Appendf(nil, "%d:%d", k.Neighborhood, k.Plot), nil
+}
+
+func (k *PlotKey) UnmarshalText(text []byte) error {
+	neighborhood, plot, found := strings.Cut(string(text), ":")
+	if !found {
+		neighborhood, plot = "0", neighborhood
+	}
+
+	n, err := strconv.Atoi(neighborhood)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid neighborhood in plot key %q", text)
+	}
+	p, err := strconv.Atoi(plot)
+	if err != nil {
+		return fmt.Errorf("invalid plot in plot key %q", text)
+	}
+
+	k.Neighborhood, k.Plot = n, p
+	return nil
+}
+
+// Slot is a single plot inside one neighborhood instance.
+type Slot struct {
+	Neighborhood int
+	Plot         Plot
+}
+
+// Priority returns the member's rank for a plot in the given neighborhood.
+// A neighborhood-specific preference wins over one for the plot in general.
+func (member *MemberData) Priority(neighborhood, plot int) (int, bool) {
+	if w, ok := member.PlotData[PlotKey{Neighborhood: neighborhood, Plot: plot}]; ok {
+		return w, true
+	}
+	w, ok := member.PlotData[PlotKey{Plot: plot}]
+	return w, ok
+}
+
+// NeighborhoodCount is the number of neighborhood instances needed to house
+// every member, never less than the configured count.
+func (community *CommunityData) NeighborhoodCount() int {
+	count := max(community.Neighborhoods, 1)
+	for _, pin := range community.Pins {
+		count = max(count, pin.Neighborhood)
+	}
+	if len(community.Plots) == 0 {
+		return count
+	}
+	for count*len(community.Plots)-community.reservedWithin(count) < len(community.Members) {
+		count++
+	}
+	return count
+}
+
+func (community *CommunityData) reservedWithin(neighborhoods int) int {
+	reserved := 0
+	for _, r := range community.Reserved {
+		if r.Neighborhood <= neighborhoods {
+			reserved++
+		}
+	}
+	return reserved
+}
+
+func (community *CommunityData) Slots() []Slot {
+	count := community.NeighborhoodCount()
+	slots := make([]Slot, 0, count*len(community.Plots))
+	for n := 1; n <= count; n++ {
+		for _, p := range community.Plots {
+			slots = append(slots, Slot{Neighborhood: n, Plot: p})
+		}
+	}
+	return slots
+}
@@ -0,0 +1,181 @@
+package model
+
+// NEIGHBOR_DISTANCE_UNIT is the map distance that costs one priority rank per point of neighbor weight.
+const NEIGHBOR_DISTANCE_UNIT = 100.0
+
+const MAX_NEIGHBOR_WISHES = 5
+
+const maxLocalSearchPasses = 50
+
+// neighborhoodSolver improves a plot mapping for the combined objective of
+// plot priorities and distance to the neighbors each member wished for.
+// Rows of the mapping are the problem's free members; pinned members never
+// move but still count towards their neighbors' distances.
+type neighborhoodSolver struct {
+	problem *problem
+	matrix  [][]int // plain rank costs, not weighed by the objective
+	bound   int     // highest cost a member may be swapped onto
+	mapping []int
+	rowOf   map[int]int // community member index -> mapping row
+	weight  float64
+	apart   float64 // distance between members living in different neighborhoods
+	wishes  [][]int // member index -> wished neighbor indices
+	wishers [][]int // member index -> indices of members wishing for them
+}
+
+func newNeighborhoodSolver(p *problem, matrix [][]int, mapping []int, bound int) *neighborhoodSolver {
+	community := p.community
+	n := len(community.Members)
+	index := make(map[string]int, n)
+	for i, member := range community.Members {
+		index[member.BattleTag] = i
+	}
+
+	wishes := make([][]int, n)
+	wishers := make([][]int, n)
+	for i, member := range community.Members {
+		for _, btag := range member.Neighbors {
+			j, ok := index[btag]
+			if !ok || j == i {
+				continue
+			}
+			wishes[i] = append(wishes[i], j)
+			wishers[j] = append(wishers[j], i)
+		}
+	}
+
+	rowOf := make(map[int]int, len(p.members))
+	for row, i := range p.members {
+		rowOf[i] = row
+	}
+
+	apart := 0.0
+	for _, a := range community.Plots {
+		for _, b := range community.Plots {
+			apart = max(apart, a.DistanceTo(b))
+		}
+	}
+
+	return &neighborhoodSolver{
+		problem: p,
+		matrix:  matrix,
+		bound:   bound,
+		mapping: append([]int(nil), mapping...),
+		rowOf:   rowOf,
+		weight:  float64(community.NeighborWeight),
+		apart:   apart,
+		wishes:  wishes,
+		wishers: wishers,
+	}
+}
+
+// slotOf returns where a member currently lives, if anywhere.
+func (s *neighborhoodSolver) slotOf(i int) (Slot, bool) {
+	if slot, ok := s.problem.pinned[i]; ok {
+		return slot, true
+	}
+	slotIndex := s.mapping[s.rowOf[i]]
+	if slotIndex >= len(s.problem.free) {
+		return Slot{}, false
+	}
+	return s.problem.free[slotIndex], true
+}
+
+func (s *neighborhoodSolver) memberCost(i int) float64 {
+	cost := 0.0
+	if row, ok := s.rowOf[i]; ok {
+		cost = float64(s.matrix[row][s.mapping[row]]) / float64(s.problem.community.RankWeighting.scale())
+	}
+
+	own, ok := s.slotOf(i)
+	if !ok {
+		return cost
+	}
+	for _, j := range s.wishes[i] {
+		other, ok := s.slotOf(j)
+		if !ok {
+			continue
+		}
+		cost += s.weight * s.distance(own, other) / NEIGHBOR_DISTANCE_UNIT
+	}
+	return cost
+}
+
+func (s *neighborhoodSolver) distance(a, b Slot) float64 {
+	if a.Neighborhood != b.Neighborhood {
+		return s.apart
+	}
+	return a.Plot.DistanceTo(b.Plot)
+}
+
+// affected collects every member whose cost depends on the slots of rows a and b.
+func (s *neighborhoodSolver) affected(a, b int) []int {
+	seen := map[int]bool{}
+	result := []int{}
+	add := func(i int) {
+		if !seen[i] {
+			seen[i] = true
+			result = append(result, i)
+		}
+	}
+	for _, row := range []int{a, b} {
+		if row >= len(s.problem.members) {
+			continue
+		}
+		i := s.problem.members[row]
+		add(i)
+		for _, w := range s.wishers[i] {
+			add(w)
+		}
+	}
+	return result
+}
+
+// withinBound checks that swapping rows a and b puts no member above the bound.
+func (s *neighborhoodSolver) withinBound(a, b int) bool {
+	for _, swap := range [][2]int{{a, b}, {b, a}} {
+		row, slotIndex := swap[0], s.mapping[swap[1]]
+		if row < len(s.problem.members) && s.matrix[row][slotIndex] > s.bound {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *neighborhoodSolver) swapDelta(a, b int) float64 {
+	affected := s.affected(a, b)
+
+	before := 0.0
+	for _, i := range affected {
+		before += s.memberCost(i)
+	}
+
+	s.mapping[a], s.mapping[b] = s.mapping[b], s.mapping[a]
+	after := 0.0
+	for _, i := range affected {
+		after += s.memberCost(i)
+	}
+	s.mapping[a], s.mapping[b] = s.mapping[b], s.mapping[a]
+
+	return after - before
+}
+
+// improve runs a pairwise swap local search. Swapping with a padding row
+// moves the member onto a free plot.
+func (s *neighborhoodSolver) improve() []int {
+	for pass := 0; pass < maxLocalSearchPasses; pass++ {
+		improved := false
+		for a := 0; a < len(s.problem.members); a++ {
+			for b := a + 1; b < len(s.mapping); b++ {
+				if s.withinBound(a, b) && s.swapDelta(a, b) < -1e-9 {
+					s.mapping[a], s.mapping[b] = s.mapping[b], s.mapping[a]
+					improved = true
+				}
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+	return s.mapping
+}
@@ -0,0 +1,215 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	hungarianAlgorithm "github.com/oddg/hungarian-algorithm"
+)
+
+type Objective string
+
+const (
+	// OBJECTIVE_SUM minimizes the sum of priority ranks.
+	OBJECTIVE_SUM Objective = "sum"
+	// OBJECTIVE_MINMAX minimizes the worst rank anyone gets, then the sum.
+	OBJECTIVE_MINMAX Objective = "minmax"
+	// OBJECTIVE_LEXMINMAX minimizes the worst rank, then how many members get it,
+	// then the next worst rank and so on.
+	OBJECTIVE_LEXMINMAX Objective = "lexminmax"
+	// OBJECTIVE_SQUARES minimizes the sum of squared ranks.
+	OBJECTIVE_SQUARES Objective = "squares"
+)
+
+func ParseObjective(s string) (Objective, error) {
+	switch o := Objective(s); o {
+	case OBJECTIVE_SUM, OBJECTIVE_MINMAX, OBJECTIVE_LEXMINMAX, OBJECTIVE_SQUARES:
+		return o, nil
+	}
+	return "", fmt.Errorf("unknown objective %q", s)
+}
+
+type ObjectiveStats struct {
+	Objective  Objective   `json:"objective"`
+	Members    int         `json:"members"`
+	Assigned   int         `json:"assigned"`
+	Unranked   int         `json:"unranked"`
+	RankSum    int         `json:"rankSum"`
+	RankSquare int         `json:"rankSquareSum"`
+	MaxRank    int         `json:"maxRank"`
+	MeanRank   float64     `json:"meanRank"`
+	Histogram  map[int]int `json:"histogram"`
+}
+
+type OptimizationRun struct {
+	Id        int            `json:"id"`
+	Objective Objective      `json:"objective"`
+	Stable    bool           `json:"stable"`
+	Persisted bool           `json:"persisted"`
+	Stats     ObjectiveStats `json:"stats"`
+	CreatedBy string         `json:"createdBy"`
+	CreatedAt time.Time      `json:"createdAt"`
+}
+
+// weigh turns the rank matrix into the matrix the Hungarian solver minimizes
+// for the given objective. Rows past members are padding and left untouched.
+func (o Objective) weigh(matrix [][]int, members int) [][]int {
+	switch o {
+	case OBJECTIVE_SQUARES:
+		return mapCosts(matrix, members, func(c int) int { return c * c })
+	case OBJECTIVE_MINMAX:
+		return bottleneckMatrix(matrix, members)
+	case OBJECTIVE_LEXMINMAX:
+		return lexicographicMatrix(bottleneckMatrix(matrix, members), members)
+	}
+	return matrix
+}
+
+// bound is the highest cost the neighbor search may give a member. The bottleneck
+// objectives must not end up with a worse rank than the solver found.
+func (o Objective) bound(matrix [][]int, mapping []int, members int) int {
+	if o != OBJECTIVE_MINMAX && o != OBJECTIVE_LEXMINMAX {
+		return math.MaxInt
+	}
+	bound := 0
+	for row := range members {
+		bound = max(bound, matrix[row][mapping[row]])
+	}
+	return bound
+}
+
+func mapCosts(matrix [][]int, members int, f func(int) int) [][]int {
+	weighed := make([][]int, len(matrix))
+	for i, row := range matrix {
+		if i >= members {
+			weighed[i] = row
+			continue
+		}
+		weighed[i] = make([]int, len(row))
+		for j, c := range row {
+			weighed[i][j] = f(c)
+		}
+	}
+	return weighed
+}
+
+func distinctCosts(matrix [][]int, members int) []int {
+	seen := map[int]bool{}
+	levels := []int{}
+	for _, row := range matrix[:members] {
+		for _, c := range row {
+			if !seen[c] {
+				seen[c] = true
+				levels = append(levels, c)
+			}
+		}
+	}
+	slices.Sort(levels)
+	return levels
+}
+
+// bottleneckMatrix finds the smallest threshold every member can stay within
+// and forbids all entries above it.
+func bottleneckMatrix(matrix [][]int, members int) [][]int {
+	if members == 0 {
+		return matrix
+	}
+	levels := distinctCosts(matrix, members)
+
+	feasible := func(threshold int) bool {
+		check := mapCosts(matrix, members, func(c int) int {
+			if c <= threshold {
+				return 0
+			}
+			return 1
+		})
+		for i := members; i < len(check); i++ {
+			check[i] = make([]int, len(check[i]))
+		}
+		mapping, err := hungarianAlgorithm.Solve(check)
+		return err == nil && totalCost(check, mapping) == 0
+	}
+
+	lo, hi := 0, len(levels)-1
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if feasible(levels[mid]) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	threshold := levels[lo]
+
+	forbidden := (threshold + 1) * len(matrix)
+	return mapCosts(matrix, members, func(c int) int {
+		if c > threshold {
+			return forbidden
+		}
+		return c
+	})
+}
+
+// lexicographicMatrix weighs every cost level so that a single member on it
+// outweighs all members on lower levels. When the weights would overflow,
+// the lowest levels fall back to plain rank sums.
+func lexicographicMatrix(matrix [][]int, members int) [][]int {
+	if members == 0 {
+		return matrix
+	}
+	levels := distinctCosts(matrix, members)
+	limit := math.MaxInt64 / (len(matrix) + 1)
+
+	for flat := 1; flat <= len(levels); flat++ {
+		weights := make(map[int]int, len(levels))
+		ok := true
+		for l, level := range levels {
+			if l < flat {
+				weights[level] = level - levels[0]
+				continue
+			}
+			previous := weights[levels[l-1]]
+			if previous > (limit-1)/members {
+				ok = false
+				break
+			}
+			weights[level] = members*previous + 1
+		}
+		if ok {
+			return mapCosts(matrix, members, func(c int) int { return weights[c] })
+		}
+	}
+	return matrix
+}
+
+func totalCost(matrix [][]int, mapping []int) int {
+	total := 0
+	for i, j := range mapping {
+		total += matrix[i][j]
+	}
+	return total
+}
+
+func (p *problem) stats(assignments []Assignment, objective Objective) ObjectiveStats {
+	stats := ObjectiveStats{
+		Objective: objective,
+		Members:   len(p.community.Members),
+		Assigned:  len(assignments),
+		Histogram: map[int]int{},
+	}
+	for _, a := range assignments {
+		if a.Score >= len(p.slots) {
+			stats.Unranked++
+		}
+		stats.RankSum += a.Score
+		stats.RankSquare += a.Score * a.Score
+		stats.MaxRank = max(stats.MaxRank, a.Score)
+		stats.Histogram[a.Score]++
+	}
+	if len(assignments) > 0 {
+		stats.MeanRank = float64(stats.RankSum) / float64(len(assignments))
+	}
+	return stats
+}
@@ -4,69 +4,200 @@ import (
 	hungarianAlgorithm "github.com/oddg/hungarian-algorithm"
 )
 
-const PLOT_COUNT = 53
+const paddingCost = 1000
+
+type OptimizeOptions struct {
+	// Objective defaults to the community's configured objective.
+	Objective Objective
+	// Stable keeps members on their previous plots unless moving them
+	// saves more than the community's move penalty.
+	Stable bool
+}
+
+type OptimizationResult struct {
+	Objective   Objective      `json:"objective"`
+	Stats       ObjectiveStats `json:"stats"`
+	Assignments []Assignment   `json:"assignments"`
+	Moves       []Move         `json:"moves,omitempty"`
+}
+
+func (community *CommunityData) Optimize(options OptimizeOptions) *OptimizationResult {
+	if options.Objective == "" {
+		options.Objective = community.Objective
+	}
+	if options.Objective == "" {
+		options.Objective = OBJECTIVE_SUM
+	}
 
-func (community *CommunityData) Optimize() []Assignment {
-	matrix := buildCostMatrix(community)
+	if community.RankWeighting.Mode == RANK_WEIGHTING_TIERED {
+		return community.optimizeTiered(options)
+	}
+
+	p, assignments := community.solve(options)
+	return p.result(assignments, options)
+}
+
+func (community *CommunityData) solve(options OptimizeOptions) (*problem, []Assignment) {
+	p := newProblem(community, options)
+	costs := p.costMatrix()
+	matrix := p.withMovePenalty(options.Objective.weigh(costs, len(p.members)))
 	mapping, _ := hungarianAlgorithm.Solve(matrix)
 
-	return buildAssignments(mapping, community)
+	// the Hungarian result is optimal for priorities alone and seeds the neighbor search.
+	// The search trades plain ranks against distance, the same ranks the stats count,
+	// and keeps the worst rank the objective allowed.
+	if community.NeighborWeight > 0 {
+		costs = p.withMovePenalty(costs)
+		bound := options.Objective.bound(costs, mapping, len(p.members))
+		mapping = newNeighborhoodSolver(p, costs, mapping, bound).improve()
+	}
+
+	return p, p.assignments(mapping)
 }
 
-func buildAssignments(mapping []int, community *CommunityData) []Assignment {
-	n := len(community.Members)
-	m := PLOT_COUNT
+func (p *problem) result(assignments []Assignment, options OptimizeOptions) *OptimizationResult {
+	result := &OptimizationResult{
+		Objective:   options.Objective,
+		Stats:       p.stats(assignments, options.Objective),
+		Assignments: assignments,
+	}
+	if options.Stable {
+		result.Moves = p.moves(assignments)
+	}
+	return result
+}
 
-	assignments := []Assignment{}
+// problem is the part of a community the solver is free to arrange:
+// members without a pin and slots that are neither pinned nor reserved.
+type problem struct {
+	community *CommunityData
+	slots     []Slot
+	members   []int        // community member indices to place
+	free      []Slot       // slots available to those members
+	pinned    map[int]Slot // community member index -> fixed slot
+	previous  map[string]PlotKey
+}
 
-	for i, plotIndex := range mapping {
-		plotId := plotIndex + 1
+func newProblem(community *CommunityData, options OptimizeOptions) *problem {
+	slots := community.Slots()
 
-		if i < n && plotIndex < m {
-			member := community.Members[i]
+	index := make(map[string]int, len(community.Members))
+	for i, member := range community.Members {
+		index[member.BattleTag] = i
+	}
+
+	blocked := make(map[PlotKey]bool)
+	for _, r := range community.Reserved {
+		blocked[PlotKey{Neighborhood: r.Neighborhood, Plot: r.Plot}] = true
+	}
 
-			weight := m
-			if w, ok := member.PlotData[plotId]; ok {
-				weight = w
+	// a pin blocks its slot even when its member isn't placed in this problem,
+	// so an earlier rank tier can't be solved onto a plot an officer gave someone else
+	pinned := make(map[int]Slot)
+	for _, pin := range community.Pins {
+		i, ok := index[pin.Battletag]
+		key := PlotKey{Neighborhood: pin.Neighborhood, Plot: pin.Plot}
+		if blocked[key] {
+			continue
+		}
+		for _, slot := range slots {
+			if slot.Neighborhood == pin.Neighborhood && slot.Plot.Id == pin.Plot {
+				if ok {
+					pinned[i] = slot
+				}
+				blocked[key] = true
+				break
 			}
+		}
+	}
+
+	members := []int{}
+	for i := range community.Members {
+		if _, ok := pinned[i]; !ok {
+			members = append(members, i)
+		}
+	}
 
-			assignments = append(assignments, Assignment{
-				Battletag: member.BattleTag,
-				Character: member.Character,
-				Plot:      plotId,
-				Score:     weight,
-			})
+	free := []Slot{}
+	for _, slot := range slots {
+		if !blocked[PlotKey{Neighborhood: slot.Neighborhood, Plot: slot.Plot.Id}] {
+			free = append(free, slot)
+		}
+	}
+
+	p := &problem{community: community, slots: slots, members: members, free: free, pinned: pinned}
+	if options.Stable {
+		p.previous = p.previousSlots()
+	}
+	return p
+}
+
+// rank is the member's priority for a slot; unranked slots cost more than any ranked one.
+func (p *problem) rank(member *MemberData, slot Slot) int {
+	if w, ok := member.Priority(slot.Neighborhood, slot.Plot.Id); ok {
+		return w
+	}
+	return len(p.slots)
+}
+
+func (p *problem) assignments(mapping []int) []Assignment {
+	assignments := []Assignment{}
+
+	for i, member := range p.community.Members {
+		if slot, ok := p.pinned[i]; ok {
+			assignments = append(assignments, p.assignment(&member, slot, ASSIGNMENT_PINNED))
+		}
+	}
+
+	for row, slotIndex := range mapping {
+		if row < len(p.members) && slotIndex < len(p.free) {
+			member := p.community.Members[p.members[row]]
+			assignments = append(assignments, p.assignment(&member, p.free[slotIndex], ASSIGNMENT_SOLVED))
 		}
 	}
 	return assignments
 }
 
-func buildCostMatrix(community *CommunityData) [][]int {
+func (p *problem) assignment(member *MemberData, slot Slot, source string) Assignment {
+	return Assignment{
+		Battletag:    member.BattleTag,
+		Character:    member.Character,
+		Neighborhood: slot.Neighborhood,
+		Plot:         slot.Plot.Id,
+		Score:        p.rank(member, slot),
+		Source:       source,
+	}
+}
 
-	n := len(community.Members)
-	m := PLOT_COUNT
+func (p *problem) costMatrix() [][]int {
 
-	matrix := make([][]int, m)
+	n := len(p.members)
+	m := len(p.free)
+	size := max(n, m)
 
-	for i, member := range community.Members {
-		row := make([]int, m)
+	matrix := make([][]int, size)
 
-		for plot := 1; plot <= PLOT_COUNT; plot++ {
-			if w, ok := member.PlotData[plot]; ok {
-				row[plot-1] = w
-			} else {
-				row[plot-1] = m
+	for row, i := range p.members {
+		member := &p.community.Members[i]
+		cost := make([]int, size)
+
+		for j := range cost {
+			if j >= m {
+				// members beyond the catalog's capacity stay unassigned
+				cost[j] = paddingCost * p.community.RankWeighting.scale()
+				continue
 			}
+			cost[j] = p.community.RankWeighting.weigh(p.rank(member, p.free[j]), member.Rank)
 		}
 
-		matrix[i] = row
+		matrix[row] = cost
 	}
 
 	// padding matrix if less players than plots
-	for i := n; i < m; i++ {
-		row := make([]int, m)
+	for i := n; i < size; i++ {
+		row := make([]int, size)
 		for j := range row {
-			row[j] = 1000
+			row[j] = paddingCost * p.community.RankWeighting.scale()
 		}
 		matrix[i] = row
 	}
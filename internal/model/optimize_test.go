@@ -0,0 +1,105 @@
+package model
+
+import "testing"
+
+// linePlots lies on one line, the last plot far from the others.
+var linePlots = []Plot{{Id: 1, X: 0}, {Id: 2, X: 100}, {Id: 3, X: 2000}}
+
+func ranks(plots ...int) map[PlotKey]int {
+	ranks := make(map[PlotKey]int, len(plots))
+	for i, plot := range plots {
+		ranks[PlotKey{Plot: plot}] = i + 1
+	}
+	return ranks
+}
+
+func plotOf(t *testing.T, result *OptimizationResult, battletag string) int {
+	t.Helper()
+	for _, a := range result.Assignments {
+		if a.Battletag == battletag {
+			return a.Plot
+		}
+	}
+	t.Fatalf("%s was not assigned", battletag)
+	return 0
+}
+
+// neighborCommunity has two members wishing for each other, whose favorite plots lie far apart.
+func neighborCommunity(objective Objective) *CommunityData {
+	return &CommunityData{
+		NeighborWeight: 10,
+		Objective:      objective,
+		Plots:          linePlots,
+		Members: []MemberData{
+			{BattleTag: "a#1", PlotData: ranks(1, 2, 3), Neighbors: []string{"b#1"}},
+			{BattleTag: "b#1", PlotData: ranks(3, 2, 1), Neighbors: []string{"a#1"}},
+			{BattleTag: "c#1", PlotData: ranks(2, 1, 3)},
+		},
+	}
+}
+
+func TestNeighborSearchTradesPlainRanks(t *testing.T) {
+	for _, objective := range []Objective{OBJECTIVE_SUM, OBJECTIVE_SQUARES} {
+		result := neighborCommunity(objective).Optimize(OptimizeOptions{})
+		if plot := plotOf(t, result, "b#1"); plot != 2 {
+			t.Errorf("%s: b#1 got plot %d, want 2 next to a#1", objective, plot)
+		}
+		if result.Stats.RankSum != 6 {
+			t.Errorf("%s: rank sum %d, want 6", objective, result.Stats.RankSum)
+		}
+	}
+}
+
+func TestNeighborSearchKeepsBottleneck(t *testing.T) {
+	for _, objective := range []Objective{OBJECTIVE_MINMAX, OBJECTIVE_LEXMINMAX} {
+		result := neighborCommunity(objective).Optimize(OptimizeOptions{})
+		if result.Stats.MaxRank != 1 {
+			t.Errorf("%s: worst rank %d, want 1", objective, result.Stats.MaxRank)
+		}
+	}
+}
+
+func TestTieredKeepsOfficerPinsFree(t *testing.T) {
+	community := &CommunityData{
+		Plots:         linePlots,
+		RankWeighting: RankWeighting{Mode: RANK_WEIGHTING_TIERED, Tiers: []int{0}},
+		Pins:          []Pin{{Battletag: "pinned#1", Neighborhood: 1, Plot: 1}},
+		Members: []MemberData{
+			{BattleTag: "gm#1", Rank: 0, PlotData: ranks(1, 2, 3)},
+			{BattleTag: "pinned#1", Rank: 5, PlotData: ranks(1)},
+			{BattleTag: "member#1", Rank: 5, PlotData: ranks(2)},
+		},
+	}
+
+	result := community.Optimize(OptimizeOptions{})
+	if plot := plotOf(t, result, "pinned#1"); plot != 1 {
+		t.Errorf("pinned#1 got plot %d, want their pinned plot 1", plot)
+	}
+	// the guild master's tier is solved first and keeps the best plot left
+	if plot := plotOf(t, result, "gm#1"); plot != 2 {
+		t.Errorf("gm#1 got plot %d, want 2", plot)
+	}
+}
+
+func TestExplainComparesAgainstResolve(t *testing.T) {
+	community := &CommunityData{
+		Plots: linePlots,
+		Members: []MemberData{
+			{BattleTag: "a#1", PlotData: ranks(1, 2)},
+			{BattleTag: "b#1", PlotData: ranks(2, 1)},
+		},
+	}
+	// a manual assignment both members like less than the optimum
+	stored := []Assignment{
+		{Battletag: "a#1", Neighborhood: 1, Plot: 2},
+		{Battletag: "b#1", Neighborhood: 1, Plot: 1},
+	}
+
+	explanations := community.Explain(stored, OptimizeOptions{}, "a#1")
+	if len(explanations) != 1 || len(explanations[0].Alternatives) != 1 {
+		t.Fatalf("want one alternative for a#1, got %+v", explanations)
+	}
+	if increase := explanations[0].Alternatives[0].CostIncrease; increase != 0 {
+		t.Errorf("cost increase %d, want 0 for the plot the optimum gives a#1", increase)
+	}
+}
@@ -0,0 +1,61 @@
+package model
+
+const (
+	PLOT_RESERVED    = "reserved"
+	PLOT_UNAVAILABLE = "unavailable"
+)
+
+const (
+	ASSIGNMENT_PINNED = "pinned"
+	ASSIGNMENT_SOLVED = "solved"
+)
+
+// Pin fixes a member to a plot. The optimizer places everyone else around it.
+type Pin struct {
+	Battletag    string `json:"btag"`
+	Character    string `json:"char,omitempty"`
+	Neighborhood int    `json:"neighborhood"`
+	Plot         int    `json:"plot"`
+}
+
+// ReservedPlot is kept free of members, e.g. for a guild hall.
+type ReservedPlot struct {
+	Neighborhood int    `json:"neighborhood"`
+	Plot         int    `json:"plot"`
+	Status       string `json:"status"`
+	Note         string `json:"note,omitempty"`
+}
+
+type PlotConstraints struct {
+	Pins     []Pin          `json:"pins"`
+	Reserved []ReservedPlot `json:"reserved"`
+}
+
+func (c *PlotConstraints) IsReserved(neighborhood, plot int) bool {
+	for _, r := range c.Reserved {
+		if r.Neighborhood == neighborhood && r.Plot == plot {
+			return true
+		}
+	}
+	return false
+}
+
+// PinOf returns the pin of a member, or nil if the member isn't pinned.
+func (c *PlotConstraints) PinOf(battletag string) *Pin {
+	for i := range c.Pins {
+		if c.Pins[i].Battletag == battletag {
+			return &c.Pins[i]
+		}
+	}
+	return nil
+}
+
+// ReservationOf returns the reservation of a plot, or nil if the plot is free.
+func (c *PlotConstraints) ReservationOf(neighborhood, plot int) *ReservedPlot {
+	for i := range c.Reserved {
+		if c.Reserved[i].Neighborhood == neighborhood && c.Reserved[i].Plot == plot {
+			return &c.Reserved[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package model
+
+import "math"
+
+type Plot struct {
+	Id    int      `json:"id"`
+	Label string   `json:"label"`
+	X     int      `json:"xCoord"`
+	Y     int      `json:"yCoord"`
+	Size  string   `json:"size"`
+	Type  string   `json:"type"`
+	Tags  []string `json:"tags"`
+}
+
+func (p Plot) DistanceTo(other Plot) float64 {
+	return math.Hypot(float64(p.X-other.X), float64(p.Y-other.Y))
+}
@@ -0,0 +1,129 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+const (
+	RANK_WEIGHTING_NONE       = "none"
+	RANK_WEIGHTING_MULTIPLIER = "multiplier"
+	RANK_WEIGHTING_TIERED     = "tiered"
+)
+
+// rankWeightScale keeps two decimals of a multiplier in the integer cost matrix.
+const rankWeightScale = 100
+
+// RankWeighting gives members priority by their guild rank (0 is the guild master).
+type RankWeighting struct {
+	Mode string `json:"mode"`
+	// Multipliers scale the plot costs of a guild rank. Ranks without an entry use 1.
+	Multipliers map[int]float64 `json:"multipliers,omitempty"`
+	// Tiers hold the lowest guild rank of each tier, best tier first. Each
+	// tier is solved and fixed before the next; members below the last
+	// entry form the final tier.
+	Tiers []int `json:"tiers,omitempty"`
+}
+
+func (w *RankWeighting) Validate() error {
+	switch w.Mode {
+	case "", RANK_WEIGHTING_NONE:
+	case RANK_WEIGHTING_MULTIPLIER:
+		for rank, m := range w.Multipliers {
+			if rank < 0 || m <= 0 {
+				return fmt.Errorf("invalid multiplier %v for rank %d", m, rank)
+			}
+		}
+	case RANK_WEIGHTING_TIERED:
+		if len(w.Tiers) == 0 || w.Tiers[0] < 0 || !slices.IsSorted(w.Tiers) {
+			return fmt.Errorf("tiers must be ascending, non-negative guild ranks")
+		}
+		if len(slices.Compact(slices.Clone(w.Tiers))) != len(w.Tiers) {
+			return fmt.Errorf("tiers must not repeat a guild rank")
+		}
+	default:
+		return fmt.Errorf("unknown rank weighting mode %q", w.Mode)
+	}
+	return nil
+}
+
+func (w *RankWeighting) multiplier(rank int) float64 {
+	if w.Mode != RANK_WEIGHTING_MULTIPLIER {
+		return 1
+	}
+	if m, ok := w.Multipliers[rank]; ok {
+		return m
+	}
+	return 1
+}
+
+func (w *RankWeighting) scale() int {
+	if w.Mode == RANK_WEIGHTING_MULTIPLIER {
+		return rankWeightScale
+	}
+	return 1
+}
+
+// weigh applies the member's rank multiplier to a plot cost.
+func (w *RankWeighting) weigh(cost int, rank int) int {
+	if w.Mode != RANK_WEIGHTING_MULTIPLIER {
+		return cost
+	}
+	return int(math.Round(float64(cost) * w.multiplier(rank) * rankWeightScale))
+}
+
+func (w *RankWeighting) tier(rank int) int {
+	for i, lowest := range w.Tiers {
+		if rank <= lowest {
+			return i
+		}
+	}
+	return len(w.Tiers)
+}
+
+// optimizeTiered solves the best tier on its own, pins the result and moves
+// on to the next tier until everyone is placed.
+func (community *CommunityData) optimizeTiered(options OptimizeOptions) *OptimizationResult {
+	weighting := community.RankWeighting
+	pinned := make(map[string]bool, len(community.Pins))
+	for _, pin := range community.Pins {
+		pinned[pin.Battletag] = true
+	}
+
+	tier := community.withMembers(nil)
+	tier.Neighborhoods = community.NeighborhoodCount()
+
+	var p *problem
+	var assignments []Assignment
+	for t := 0; t <= len(weighting.Tiers); t++ {
+		for _, member := range community.Members {
+			if weighting.tier(member.Rank) == t {
+				tier.Members = append(tier.Members, member)
+			}
+		}
+
+		p, assignments = tier.solve(options)
+
+		for _, a := range assignments {
+			if !pinned[a.Battletag] && a.Source == ASSIGNMENT_SOLVED {
+				tier.Pins = append(tier.Pins, Pin{Battletag: a.Battletag, Neighborhood: a.Neighborhood, Plot: a.Plot})
+			}
+		}
+	}
+
+	// members fixed by an earlier tier were still solved, not pinned by an officer
+	for i, a := range assignments {
+		if !pinned[a.Battletag] {
+			assignments[i].Source = ASSIGNMENT_SOLVED
+		}
+	}
+	return p.result(assignments, options)
+}
+
+func (community *CommunityData) withMembers(members []MemberData) *CommunityData {
+	clone := *community
+	clone.Members = members
+	clone.Pins = slices.Clone(community.Pins)
+	return &clone
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Region is the Battle.net region a community lives in and a session was opened against.
+type Region string
+
+const (
+	REGION_EU Region = "eu"
+	REGION_US Region = "us"
+	REGION_KR Region = "kr"
+	REGION_TW Region = "tw"
+
+	DEFAULT_REGION = REGION_EU
+)
+
+var REGIONS = []Region{REGION_EU, REGION_US, REGION_KR, REGION_TW}
+
+// ParseRegion accepts a region code in any case. An empty string yields the default region.
+func ParseRegion(s string) (Region, error) {
+	if s == "" {
+		return DEFAULT_REGION, nil
+	}
+	region := Region(strings.ToLower(s))
+	for _, r := range REGIONS {
+		if r == region {
+			return region, nil
+		}
+	}
+	return "", fmt.Errorf("unknown region %q", s)
+}
+
+func (r Region) ProfileNamespace() string {
+	return "profile-" + string(r)
+}
+
+// BnetEndpoints are the Battle.net base URLs. A "{region}" placeholder is
+// replaced with the region code, URLs without one serve every region.
+type BnetEndpoints struct {
+	OAuthURL string
+	APIURL   string
+}
+
+var DEFAULT_BNET_ENDPOINTS = BnetEndpoints{
+	OAuthURL: "https://{region}.battle.net",
+	APIURL:   "https://{region}.api.blizzard.com",
+}
+
+// OAuthHost serves the authorize, token and userinfo endpoints for the region.
+func (e BnetEndpoints) OAuthHost(r Region) string {
+	return strings.TrimSuffix(strings.ReplaceAll(e.OAuthURL, "{region}", string(r)), "/")
+}
+
+// APIHost serves the game data and profile APIs for the region.
+func (e BnetEndpoints) APIHost(r Region) string {
+	return strings.TrimSuffix(strings.ReplaceAll(e.APIURL, "{region}", string(r)), "/")
+}
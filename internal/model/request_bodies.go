@@ -1,13 +1,36 @@
 package model
 
+import "time"
+
 type PlayerUpdateRequest struct {
-	Note     string      `json:"note"`
-	PlotData map[int]int `json:"plotData"`
+	Note      string          `json:"note"`
+	PlotData  map[PlotKey]int `json:"plotData"`
+	Neighbors []string        `json:"neighbors"`
+}
+
+type CharacterRequest struct {
+	Char string `json:"char"`
 }
 
 type CommunityRankRequest struct {
-	AdminRank  int `json:"adminRank"`
-	MemberRank int `json:"memberRank"`
+	AdminRank      int            `json:"adminRank"`
+	MemberRank     int            `json:"memberRank"`
+	NeighborWeight *int           `json:"neighborWeight,omitempty"`
+	Neighborhoods  *int           `json:"neighborhoods,omitempty"`
+	MovePenalty    *int           `json:"movePenalty,omitempty"`
+	Objective      *Objective     `json:"objective,omitempty"`
+	RankWeighting  *RankWeighting `json:"rankWeighting,omitempty"`
+	JoinRequests   *bool          `json:"joinRequests,omitempty"`
+}
+
+// DeadlineRequest sets when preferences close. A null deadline removes it.
+type DeadlineRequest struct {
+	Deadline *time.Time `json:"deadline"`
+}
+
+// StatusRequest moves a community to another status of its lifecycle.
+type StatusRequest struct {
+	Status string `json:"status"`
 }
 
 type AssignmentUpload struct {
@@ -17,7 +40,9 @@ type AssignmentUpload struct {
 }
 
 type SingleAssignmentRequest struct {
-	Battletag string `json:"btag"`
-	Char      string `json:"char"`
-	PlotId    int    `json:"plot"`
+	Battletag    string `json:"btag"`
+	Char         string `json:"char"`
+	Neighborhood int    `json:"neighborhood"`
+	PlotId       int    `json:"plot"`
+	Pin          bool   `json:"pin"`
 }
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+type RevisionSource string
+
+const (
+	REVISION_LOCK    RevisionSource = "lock"
+	REVISION_UPLOAD  RevisionSource = "upload"
+	REVISION_EDIT    RevisionSource = "edit"
+	REVISION_RESTORE RevisionSource = "restore"
+	REVISION_REMOVAL RevisionSource = "removal"
+)
+
+// RevisionOrigin is what produced a revision and who did it.
+type RevisionOrigin struct {
+	Source       RevisionSource `json:"source"`
+	CreatedBy    string         `json:"createdBy,omitempty"`
+	RestoredFrom int            `json:"restoredFrom,omitempty"`
+}
+
+// AssignmentRevision is an immutable, numbered snapshot of the assignments of a community.
+type AssignmentRevision struct {
+	Revision int `json:"revision"`
+	RevisionOrigin
+	CreatedAt   time.Time    `json:"createdAt"`
+	Assignments []Assignment `json:"assignments,omitempty"`
+}
+
+type RevisionChangeKind string
+
+const (
+	REVISION_ADDED   RevisionChangeKind = "added"
+	REVISION_REMOVED RevisionChangeKind = "removed"
+	REVISION_MOVED   RevisionChangeKind = "moved"
+)
+
+// RevisionChange is one member whose plot differs between two revisions.
+type RevisionChange struct {
+	Battletag string             `json:"btag"`
+	Character string             `json:"char"`
+	Kind      RevisionChangeKind `json:"kind"`
+	From      *PlotKey           `json:"from,omitempty"`
+	To        *PlotKey           `json:"to,omitempty"`
+}
+
+type RevisionDiff struct {
+	From    int              `json:"from"`
+	To      int              `json:"to"`
+	Changes []RevisionChange `json:"changes"`
+}
+
+// DiffAssignments lists who was added, removed or moved going from one set of assignments to another.
+func DiffAssignments(from, to []Assignment) []RevisionChange {
+	before := make(map[string]Assignment, len(from))
+	for _, a := range from {
+		before[a.Battletag] = a
+	}
+
+	changes := []RevisionChange{}
+	for _, a := range to {
+		key := PlotKey{Neighborhood: a.Neighborhood, Plot: a.Plot}
+		old, existed := before[a.Battletag]
+		delete(before, a.Battletag)
+		switch {
+		case !existed:
+			changes = append(changes, RevisionChange{Battletag: a.Battletag, Character: a.Character, Kind: REVISION_ADDED, To: &key})
+		case old.Neighborhood != a.Neighborhood || old.Plot != a.Plot:
+			oldKey := PlotKey{Neighborhood: old.Neighborhood, Plot: old.Plot}
+			changes = append(changes, RevisionChange{Battletag: a.Battletag, Character: a.Character, Kind: REVISION_MOVED, From: &oldKey, To: &key})
+		}
+	}
+	for _, old := range before {
+		oldKey := PlotKey{Neighborhood: old.Neighborhood, Plot: old.Plot}
+		changes = append(changes, RevisionChange{Battletag: old.Battletag, Character: old.Character, Kind: REVISION_REMOVED, From: &oldKey})
+	}
+
+	slices.SortFunc(changes, func(a, b RevisionChange) int { return cmp.Compare(a.Battletag, b.Battletag) })
+	return changes
+}
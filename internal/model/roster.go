@@ -0,0 +1,99 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+type RosterChangeKind string
+
+const (
+	ROSTER_RANK_CHANGED RosterChangeKind = "rank"
+	ROSTER_DEPARTED     RosterChangeKind = "departed"
+	ROSTER_RETURNED     RosterChangeKind = "returned"
+)
+
+// RosterChange is one member whose guild standing differs from what was stored.
+type RosterChange struct {
+	Battletag string           `json:"battletag"`
+	Character string           `json:"char"`
+	Kind      RosterChangeKind `json:"kind"`
+	OldRank   int              `json:"oldRank"`
+	NewRank   int              `json:"newRank"`
+}
+
+type RosterSyncReport struct {
+	CommunityId string         `json:"communityId"`
+	Name        string         `json:"name"`
+	Realm       string         `json:"realm"`
+	Region      Region         `json:"region"`
+	SyncedAt    time.Time      `json:"syncedAt"`
+	Members     int            `json:"members"`
+	Changes     []RosterChange `json:"changes"`
+	Error       string         `json:"error,omitempty"`
+}
+
+// RosterMember is a stored community member as the roster sync sees it.
+type RosterMember struct {
+	Battletag  string
+	Character  string
+	Characters []Character
+	Rank       int
+	Departed   bool
+	// Guest members were approved by an officer and don't have to be on the roster.
+	Guest bool
+	// Manual members were added by an officer or an upload and never joined from the roster.
+	Manual bool
+}
+
+// characterKey identifies a character across realms, names are only unique within one.
+func characterKey(name string, realm string) string {
+	return strings.ToLower(name) + "-" + strings.ToLower(realm)
+}
+
+// standing is the best rank of any of the member's characters on the roster.
+func (m RosterMember) standing(ranks map[string]int) (int, bool) {
+	best, listed := 0, false
+	for _, c := range m.Characters {
+		if rank, exists := ranks[characterKey(c.Name, c.Realm)]; exists && (!listed || rank < best) {
+			best, listed = rank, true
+		}
+	}
+	return best, listed
+}
+
+// Ranks indexes the roster by lower case character name and realm slug.
+func (r *Roster) Ranks() map[string]int {
+	ranks := make(map[string]int, len(r.Members))
+	for _, m := range r.Members {
+		ranks[characterKey(m.Character.Name, m.Character.Realm.Slug)] = m.Rank
+	}
+	return ranks
+}
+
+// Diff compares stored members against the roster. Members with none of their characters
+// listed are departed, departed members listed again have returned. Manual members have
+// no characters to look up and are left alone.
+func (r *Roster) Diff(members []RosterMember) []RosterChange {
+	ranks := r.Ranks()
+	changes := []RosterChange{}
+	for _, m := range members {
+		if m.Manual {
+			continue
+		}
+		rank, listed := m.standing(ranks)
+		change := RosterChange{Battletag: m.Battletag, Character: m.Character, OldRank: m.Rank, NewRank: m.Rank}
+		switch {
+		case !listed && !m.Departed && !m.Guest:
+			change.Kind = ROSTER_DEPARTED
+		case listed && m.Departed:
+			change.Kind, change.NewRank = ROSTER_RETURNED, rank
+		case listed && rank != m.Rank:
+			change.Kind, change.NewRank = ROSTER_RANK_CHANGED, rank
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
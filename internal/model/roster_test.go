@@ -0,0 +1,36 @@
+package model
+
+import "testing"
+
+func rosterOf(members ...member) *Roster {
+	return &Roster{Members: members}
+}
+
+func listed(name string, realm string, rank int) member {
+	return member{Character: character{Name: name, Realm: Realm{Slug: realm}}, Rank: rank}
+}
+
+func TestDiffMatchesRealm(t *testing.T) {
+	roster := rosterOf(listed("Thrall", "blackmoore", 2))
+	changes := roster.Diff([]RosterMember{
+		{Battletag: "a#1", Character: "Thrall", Characters: []Character{{Name: "Thrall", Realm: "blackmoore"}}, Rank: 4},
+		{Battletag: "b#1", Character: "Thrall", Characters: []Character{{Name: "Thrall", Realm: "antonidas"}}, Rank: 4},
+	})
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %+v", changes)
+	}
+	if changes[0].Battletag != "a#1" || changes[0].Kind != ROSTER_RANK_CHANGED || changes[0].NewRank != 2 {
+		t.Errorf("expected a#1 to be ranked 2, got %+v", changes[0])
+	}
+	if changes[1].Battletag != "b#1" || changes[1].Kind != ROSTER_DEPARTED {
+		t.Errorf("expected b#1 on another realm to have departed, got %+v", changes[1])
+	}
+}
+
+func TestDiffSkipsManualMembers(t *testing.T) {
+	roster := rosterOf(listed("Thrall", "blackmoore", 2))
+	changes := roster.Diff([]RosterMember{{Battletag: "a#1", Character: "Jaina", Rank: 4, Manual: true}})
+	if len(changes) != 0 {
+		t.Errorf("expected manual members to be left alone, got %+v", changes)
+	}
+}
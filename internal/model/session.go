@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+const (
+	// SESSION_IDLE_TTL is how long a session survives without being used.
+	SESSION_IDLE_TTL = 14 * 24 * time.Hour
+	// SESSION_MAX_AGE caps a session no matter how often it is used.
+	SESSION_MAX_AGE = 90 * 24 * time.Hour
+	// SESSION_RENEW_INTERVAL limits how often activity is written back.
+	SESSION_RENEW_INTERVAL = time.Minute
+)
+
+type Session struct {
+	Id         int       `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"`
+}
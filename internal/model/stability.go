@@ -0,0 +1,117 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+)
+
+const OPTIMIZE_MODE_STABLE = "stable"
+
+// Move describes a member that a stable re-optimization put on a new plot.
+type Move struct {
+	Battletag        string `json:"btag"`
+	Character        string `json:"char"`
+	FromNeighborhood int    `json:"fromNeighborhood"`
+	FromPlot         int    `json:"fromPlot"`
+	ToNeighborhood   int    `json:"toNeighborhood,omitempty"`
+	ToPlot           int    `json:"toPlot,omitempty"`
+	Reason           string `json:"reason"`
+}
+
+func (p *problem) previousSlots() map[string]PlotKey {
+	previous := make(map[string]PlotKey, len(p.community.Previous))
+	for _, a := range p.community.Previous {
+		previous[a.Battletag] = PlotKey{Neighborhood: a.Neighborhood, Plot: a.Plot}
+	}
+	return previous
+}
+
+// movePenalty is the extra cost of placing a member anywhere but their previous plot.
+func (p *problem) movePenalty(member *MemberData, slot Slot) int {
+	if p.previous == nil {
+		return 0
+	}
+	before, ok := p.previous[member.BattleTag]
+	if !ok || (before.Neighborhood == slot.Neighborhood && before.Plot == slot.Plot.Id) {
+		return 0
+	}
+	return p.community.MovePenalty
+}
+
+// withMovePenalty adds the move penalty to a cost matrix the objective already weighed.
+// The penalty counts plain ranks, it is neither squared nor capped by the objective.
+func (p *problem) withMovePenalty(matrix [][]int) [][]int {
+	if p.previous == nil {
+		return matrix
+	}
+	penalized := slices.Clone(matrix)
+	for row, i := range p.members {
+		member := &p.community.Members[i]
+		penalized[row] = slices.Clone(matrix[row])
+		for j, slot := range p.free {
+			penalized[row][j] += p.community.RankWeighting.weigh(p.movePenalty(member, slot), member.Rank)
+		}
+	}
+	return penalized
+}
+
+func (p *problem) moves(assignments []Assignment) []Move {
+	placed := make(map[string]Assignment, len(assignments))
+	holders := make(map[PlotKey]Assignment, len(assignments))
+	for _, a := range assignments {
+		placed[a.Battletag] = a
+		holders[PlotKey{Neighborhood: a.Neighborhood, Plot: a.Plot}] = a
+	}
+
+	reserved := make(map[PlotKey]bool, len(p.community.Reserved))
+	for _, r := range p.community.Reserved {
+		reserved[PlotKey{Neighborhood: r.Neighborhood, Plot: r.Plot}] = true
+	}
+
+	moves := []Move{}
+	for _, member := range p.community.Members {
+		before, ok := p.previous[member.BattleTag]
+		if !ok {
+			continue
+		}
+		after, ok := placed[member.BattleTag]
+		if ok && after.Neighborhood == before.Neighborhood && after.Plot == before.Plot {
+			continue
+		}
+
+		move := Move{
+			Battletag:        member.BattleTag,
+			Character:        member.Character,
+			FromNeighborhood: before.Neighborhood,
+			FromPlot:         before.Plot,
+		}
+		if ok {
+			move.ToNeighborhood, move.ToPlot = after.Neighborhood, after.Plot
+		}
+
+		oldRank, ranked := member.Priority(before.Neighborhood, before.Plot)
+		if !ranked {
+			oldRank = len(p.slots)
+		}
+		holder, taken := holders[before]
+
+		switch {
+		case !ok:
+			move.Reason = "no free plot left"
+		case after.Source == ASSIGNMENT_PINNED:
+			move.Reason = "pinned to a new plot by an officer"
+		case reserved[before]:
+			move.Reason = "previous plot is now reserved"
+		case taken && holder.Source == ASSIGNMENT_PINNED:
+			move.Reason = fmt.Sprintf("previous plot is pinned to %s", holder.Battletag)
+		case after.Score < oldRank:
+			move.Reason = fmt.Sprintf("moved up from rank %d to rank %d", oldRank, after.Score)
+		case taken:
+			move.Reason = fmt.Sprintf("previous plot went to %s (their rank %d), which lowers the total cost", holder.Battletag, holder.Score)
+		default:
+			move.Reason = "previous plot is no longer available"
+		}
+		moves = append(moves, move)
+	}
+	return moves
+}
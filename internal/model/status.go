@@ -0,0 +1,83 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+)
+
+// CommunityStatus is where a community is in its lifecycle.
+type CommunityStatus string
+
+const (
+	// STATUS_COLLECTING takes preferences, nothing is assigned yet.
+	STATUS_COLLECTING CommunityStatus = "collecting"
+	// STATUS_LOCKED has assignments that officers can still change.
+	STATUS_LOCKED CommunityStatus = "locked"
+	// STATUS_FINALIZED has assignments that are final.
+	STATUS_FINALIZED CommunityStatus = "finalized"
+	// STATUS_ARCHIVED is done and read-only.
+	STATUS_ARCHIVED CommunityStatus = "archived"
+)
+
+// COMMUNITY_TRANSITIONS lists where a community can go from each status.
+var COMMUNITY_TRANSITIONS = map[CommunityStatus][]CommunityStatus{
+	STATUS_COLLECTING: {STATUS_LOCKED},
+	STATUS_LOCKED:     {STATUS_COLLECTING, STATUS_FINALIZED},
+	STATUS_FINALIZED:  {STATUS_LOCKED, STATUS_ARCHIVED},
+	STATUS_ARCHIVED:   {STATUS_FINALIZED},
+}
+
+func ParseCommunityStatus(s string) (CommunityStatus, error) {
+	if _, exists := COMMUNITY_TRANSITIONS[CommunityStatus(s)]; exists {
+		return CommunityStatus(s), nil
+	}
+	return "", fmt.Errorf("unknown community status %q", s)
+}
+
+func (s CommunityStatus) CanBecome(to CommunityStatus) bool {
+	return slices.Contains(COMMUNITY_TRANSITIONS[s], to)
+}
+
+// Collecting is whether the community still waits to be locked.
+func (s CommunityStatus) Collecting() bool {
+	return s == STATUS_COLLECTING
+}
+
+// Locked is true once assignments were made.
+func (s CommunityStatus) Locked() bool {
+	return s == STATUS_LOCKED || s == STATUS_FINALIZED || s == STATUS_ARCHIVED
+}
+
+func (s CommunityStatus) Finalized() bool {
+	return s == STATUS_FINALIZED || s == STATUS_ARCHIVED
+}
+
+// PreferencesOpen is whether members can change their plot preferences and neighbor wishes.
+func (s CommunityStatus) PreferencesOpen() bool {
+	return s == STATUS_COLLECTING
+}
+
+// AssignmentsOpen is whether assignments, pins and reservations can change.
+func (s CommunityStatus) AssignmentsOpen() bool {
+	return s == STATUS_COLLECTING || s == STATUS_LOCKED
+}
+
+// Active is whether the community takes any change at all besides members leaving.
+func (s CommunityStatus) Active() bool {
+	return s != STATUS_ARCHIVED
+}
+
+// StatusError is returned when a change isn't allowed in the community's status.
+// Either To is the status that couldn't be reached or Action is what was refused.
+type StatusError struct {
+	Status CommunityStatus
+	To     CommunityStatus
+	Action string
+}
+
+func (e *StatusError) Error() string {
+	if e.To != "" {
+		return fmt.Sprintf("a %s community can't become %s", e.Status, e.To)
+	}
+	return fmt.Sprintf("can't %s while the community is %s", e.Action, e.Status)
+}
@@ -9,28 +9,56 @@ type User struct {
 	Community     UserCommunity
 	CommunityRank int
 	AccessToken   string
+	RefreshToken  string
 	Expiry        time.Time
+	Region        Region
+	SessionId     int
+	Departed      bool
 }
 
 type UserCommunity struct {
 	Id          string
 	Name        string
 	OfficerRank int
-	Locked      bool
-	Finalized   bool
+	Status      CommunityStatus
 	Realm       string
+	Region      Region
+	// PreferenceDeadline is when preferences close and the community is locked, nil for no deadline.
+	PreferenceDeadline *time.Time
 }
 type ValidatedUser struct {
-	Battletag string             `json:"battletag"`
+	Battletag   string             `json:"battletag"`
+	Char        string             `json:"char"`
+	Note        string             `json:"note"`
+	IsAdmin     bool               `json:"isAdmin"`
+	Departed    bool               `json:"departed"`
+	Characters  []string           `json:"characters"`
+	Region      Region             `json:"region"`
+	Bnet        BnetTokenStatus    `json:"bnet"`
+	Community   ValidatedCommunity `json:"community"`
+	Memberships []Membership       `json:"memberships"`
+}
+
+// BnetTokenStatus tells the frontend whether Battle.net calls will work without a new login.
+type BnetTokenStatus struct {
+	Expiry         time.Time `json:"expiry"`
+	Refreshable    bool      `json:"refreshable"`
+	ReauthRequired bool      `json:"reauthRequired"`
+}
+
+// Membership is one of the communities a user belongs to, as offered by the community selector.
+type Membership struct {
+	Community ValidatedCommunity `json:"community"`
 	Char      string             `json:"char"`
-	Note      string             `json:"note"`
 	IsAdmin   bool               `json:"isAdmin"`
-	Community ValidatedCommunity `json:"community"`
+	Departed  bool               `json:"departed"`
 }
+
 type ValidatedCommunity struct {
-	Id        string `json:"id"`
-	Name      string `json:"name"`
-	Realm     string `json:"realm"`
-	Locked    bool   `json:"locked"`
-	Finalized bool   `json:"finalized"`
+	Id          string           `json:"id"`
+	Name        string           `json:"name"`
+	Realm       string           `json:"realm"`
+	Region      Region           `json:"region"`
+	Status      CommunityStatus  `json:"status"`
+	Preferences PreferenceWindow `json:"preferences"`
 }
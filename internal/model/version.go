@@ -0,0 +1,10 @@
+package model
+
+// CommunityState is everything officers edit together. It is sent back when an edit was
+// based on an outdated version, so the officer can redo it on top of the current state.
+type CommunityState struct {
+	Version     int64           `json:"version"`
+	Status      CommunityStatus `json:"status"`
+	Settings    *Settings       `json:"settings"`
+	Assignments []Assignment    `json:"assignments"`
+}
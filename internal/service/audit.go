@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+
+	"github.com/sbraitsch/plotter/internal/middleware"
+	"github.com/sbraitsch/plotter/internal/model"
+)
+
+// auditEvent describes a mutation with the state before and after as JSON. It is handed to
+// the storage call making the change, which records it in the same transaction.
+func auditEvent(ctx context.Context, action model.AuditAction, target string, before, after any) *model.AuditEvent {
+	event := &model.AuditEvent{Action: action, Target: target, Before: model.AuditState(before), After: model.AuditState(after)}
+	if user, ok := ctx.Value(middleware.CtxUser).(*model.User); ok {
+		event.Actor = user.Battletag
+	}
+	return event
+}
+
+// assignmentsOf picks the assignments a change to one member or plot can touch.
+func assignmentsOf(assignments []model.Assignment, battletag string, key model.PlotKey) []model.Assignment {
+	touched := []model.Assignment{}
+	for _, a := range assignments {
+		if a.Battletag == battletag || (a.Neighborhood == key.Neighborhood && a.Plot == key.Plot) {
+			touched = append(touched, a)
+		}
+	}
+	return touched
+}
@@ -8,9 +8,9 @@ type AuthService interface {
 }
 
 type authServiceImpl struct {
-	storage *storage.StorageClient
+	storage storage.Store
 }
 
-func NewAuthService(storage *storage.StorageClient) AuthService {
+func NewAuthService(storage storage.Store) AuthService {
 	return &authServiceImpl{storage: storage}
 }
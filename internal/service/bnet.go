@@ -20,16 +20,23 @@ type BnetService interface {
 }
 
 type bnetServiceImpl struct {
-	client  *http.Client
-	storage *storage.StorageClient
+	client    *http.Client
+	storage   storage.Store
+	endpoints model.BnetEndpoints
+	region    model.Region
 }
 
-func NewBnetService(client *http.Client, storage *storage.StorageClient) BnetService {
-	return &bnetServiceImpl{client: client, storage: storage}
+// NewBnetService talks to the Battle.net APIs of the region the client's token was issued in.
+func NewBnetService(client *http.Client, storage storage.Store, endpoints model.BnetEndpoints, region model.Region) BnetService {
+	return &bnetServiceImpl{client: client, storage: storage, endpoints: endpoints, region: region}
 }
 
 func (s *bnetServiceImpl) GetProfile(ctx context.Context) (*model.WowProfile, error) {
-	resp, err := s.client.Get("https://eu.api.blizzard.com/profile/user/wow?namespace=profile-eu&locale=en_US")
+	profileURL := fmt.Sprintf(
+		"%s/profile/user/wow?namespace=%s&locale=en_US",
+		s.endpoints.APIHost(s.region), s.region.ProfileNamespace(),
+	)
+	resp, err := s.client.Get(profileURL)
 	if err != nil {
 		return nil, err
 	}
@@ -46,8 +53,8 @@ func (s *bnetServiceImpl) GetProfile(ctx context.Context) (*model.WowProfile, er
 func (s *bnetServiceImpl) GetGuildRoster(ctx context.Context, community *model.Community) (*model.Roster, error) {
 	guildSlug := strings.ToLower(strings.ReplaceAll(community.Name, " ", "-"))
 	rosterURL := fmt.Sprintf(
-		"https://eu.api.blizzard.com/data/wow/guild/%s/%s/roster?namespace=profile-eu&locale=en_US",
-		community.Realm, guildSlug,
+		"%s/data/wow/guild/%s/%s/roster?namespace=%s&locale=en_US",
+		s.endpoints.APIHost(community.Region), community.Realm, guildSlug, community.Region.ProfileNamespace(),
 	)
 
 	resp, err := s.client.Get(rosterURL)
@@ -56,6 +63,11 @@ func (s *bnetServiceImpl) GetGuildRoster(ctx context.Context, community *model.C
 	}
 	defer resp.Body.Close()
 
+	// an empty roster would read as everyone having left
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch guild roster: %s", resp.Status)
+	}
+
 	var roster model.Roster
 	if err := json.NewDecoder(resp.Body).Decode(&roster); err != nil {
 		return nil, fmt.Errorf("failed to parse guild roster: %w", err)
@@ -70,7 +82,7 @@ func (s *bnetServiceImpl) GetUserGuilds(ctx context.Context) ([]model.Community,
 		return nil, err
 	}
 
-	guilds, err := getUniqueGuilds(profile, s.client)
+	guilds, err := getUniqueGuilds(profile, s.client, s.endpoints.APIHost(s.region), s.region)
 	if err != nil {
 		log.Printf("Failed to fetch unique guilds: %v", err)
 		return nil, err
@@ -83,7 +95,7 @@ func (s *bnetServiceImpl) GetUserGuilds(ctx context.Context) ([]model.Community,
 	return saved, nil
 }
 
-func getUniqueGuilds(profile *model.WowProfile, client *http.Client) ([]model.Community, error) {
+func getUniqueGuilds(profile *model.WowProfile, client *http.Client, apiHost string, region model.Region) ([]model.Community, error) {
 	type result struct {
 		model.Community
 		Err error
@@ -102,9 +114,11 @@ func getUniqueGuilds(profile *model.WowProfile, client *http.Client) ([]model.Co
 				defer wg.Done()
 
 				charUrl := fmt.Sprintf(
-					"https://eu.api.blizzard.com/profile/wow/character/%s/%s?namespace=profile-eu&locale=en_US",
+					"%s/profile/wow/character/%s/%s?namespace=%s&locale=en_US",
+					apiHost,
 					c.Realm.Slug,
 					strings.ToLower(c.Name),
+					region.ProfileNamespace(),
 				)
 
 				resp, err := client.Get(charUrl)
@@ -121,7 +135,7 @@ func getUniqueGuilds(profile *model.WowProfile, client *http.Client) ([]model.Co
 				}
 
 				if detail.Guild.Name != "" {
-					results <- result{model.Community{Id: "", Name: detail.Guild.Name, Realm: c.Realm.Slug}, nil}
+					results <- result{model.Community{Id: "", Name: detail.Guild.Name, Realm: c.Realm.Slug, Region: region}, nil}
 				}
 			}(char)
 		}
@@ -132,22 +146,19 @@ func getUniqueGuilds(profile *model.WowProfile, client *http.Client) ([]model.Co
 		close(results)
 	}()
 
-	guildSet := make(map[string]string)
+	// guild names are only unique per realm
+	guildSet := make(map[string]model.Community)
 	for r := range results {
 		if r.Err != nil {
 			return nil, r.Err
 		}
-		guildSet[r.Name] = r.Realm
+		guildSet[r.Realm+"/"+r.Name] = r.Community
 	}
 
 	// convert set to slice
 	guilds := make([]model.Community, 0, len(guildSet))
-	for g := range guildSet {
-		info := model.Community{
-			Name:  g,
-			Realm: guildSet[g],
-		}
-		guilds = append(guilds, info)
+	for _, g := range guildSet {
+		guilds = append(guilds, g)
 	}
 
 	return guilds, nil
@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/sbraitsch/plotter/internal/middleware"
 	"github.com/sbraitsch/plotter/internal/model"
@@ -14,35 +18,67 @@ import (
 type CommunityService interface {
 	FinalizeCommunity(ctx context.Context) error
 	GetCommunityData(ctx context.Context) (*model.CommunityData, error)
-	JoinCommunity(ctx context.Context, communityId string) (string, error)
-	ToggleCommunityLock(ctx context.Context, user *model.User) ([]model.Assignment, error)
+	JoinCommunity(ctx context.Context, communityId string) (*model.JoinedCommunity, error)
+	LeaveCommunity(ctx context.Context) error
+	RemoveMember(ctx context.Context, communityId string, battletag string, block bool) error
+	GetJoinRequests(ctx context.Context, communityId string) ([]model.JoinRequest, error)
+	DecideJoinRequest(ctx context.Context, communityId string, battletag string, approve bool) error
+	GetAuditEvents(ctx context.Context, communityId string, filter *model.AuditFilter) ([]model.AuditEvent, error)
+	GetRevisions(ctx context.Context, communityId string) ([]model.AssignmentRevision, error)
+	GetRevision(ctx context.Context, communityId string, revision int) (*model.AssignmentRevision, error)
+	DiffRevisions(ctx context.Context, communityId string, from, to int) (*model.RevisionDiff, error)
+	RestoreRevision(ctx context.Context, communityId string, revision int) (*model.AssignmentRevision, error)
+	Optimize(ctx context.Context, options model.OptimizeOptions) (*model.OptimizationResult, error)
+	ToggleCommunityLock(ctx context.Context, user *model.User, options model.OptimizeOptions) (*model.OptimizationResult, error)
+	SetCommunityStatus(
+		ctx context.Context,
+		user *model.User,
+		to model.CommunityStatus,
+		options model.OptimizeOptions,
+	) (*model.OptimizationResult, error)
+	GetOptimizationRuns(ctx context.Context, communityId string) ([]model.OptimizationRun, error)
 	GetAssignments(ctx context.Context, communityId string) ([]model.Assignment, error)
+	ExplainAssignments(ctx context.Context, battletag string) ([]model.MemberExplanation, error)
 	SetAssignment(ctx context.Context, req *model.SingleAssignmentRequest, communityId string) error
 	SetCommunitySettings(ctx context.Context, communityId string, req *model.CommunityRankRequest) error
 	GetCommunitySettings(ctx context.Context, communityId string) (*model.Settings, error)
+	SetPreferenceDeadline(ctx context.Context, communityId string, deadline *time.Time) error
+	LockOverdueCommunities(ctx context.Context) error
+	GetPlotConstraints(ctx context.Context, communityId string) (*model.PlotConstraints, error)
+	PinMember(ctx context.Context, communityId string, pin *model.Pin) error
+	UnpinMember(ctx context.Context, communityId string, battletag string) error
+	ReservePlot(ctx context.Context, communityId string, reserved *model.ReservedPlot) error
+	ReleasePlot(ctx context.Context, communityId string, neighborhood, plot int) error
 	DownloadCommunityData(ctx context.Context) (*model.FullCommunityData, error)
 	UploadCommunityData(ctx context.Context, data *model.AssignmentUpload) ([]model.Assignment, error)
+	GetVersion(ctx context.Context, communityId string) (int64, error)
+	GetCommunityState(ctx context.Context, communityId string) (*model.CommunityState, error)
 }
 
+// ErrRemoveSelf is returned when an officer tries to remove themselves instead of leaving.
+var ErrRemoveSelf = errors.New("use leave to remove yourself from the community")
+
 type communityServiceImpl struct {
-	storage *storage.StorageClient
+	storage storage.Store
+	bnet    *oauth.Provider
 }
 
-func NewCommunityService(storage *storage.StorageClient) CommunityService {
-	return &communityServiceImpl{storage: storage}
+func NewCommunityService(storage storage.Store, bnet *oauth.Provider) CommunityService {
+	return &communityServiceImpl{storage: storage, bnet: bnet}
 }
 
+// FinalizeCommunity finalizes a locked community and reopens a finalized one.
 func (s *communityServiceImpl) FinalizeCommunity(ctx context.Context) error {
 	user, ok := ctx.Value(middleware.CtxUser).(*model.User)
 	if !ok || len(user.Community.Id) == 0 {
 		return fmt.Errorf("community not found in context")
 	}
-	err := s.storage.FinalizeCommunity(ctx, user.Community.Id)
-	if err != nil {
-		log.Printf("Failed to finalize community: %v", err)
+	to := model.STATUS_FINALIZED
+	if user.Community.Status.Finalized() {
+		to = model.STATUS_LOCKED
 	}
-
-	return nil
+	_, err := s.SetCommunityStatus(ctx, user, to, model.OptimizeOptions{})
+	return err
 }
 
 func (s *communityServiceImpl) GetCommunityData(ctx context.Context) (*model.CommunityData, error) {
@@ -68,29 +104,70 @@ func (s *communityServiceImpl) DownloadCommunityData(ctx context.Context) (*mode
 		log.Printf("Failed to retrieve community data from database: %v", err)
 		return nil, err
 	}
+
+	// include the optimizer inputs so the file can be solved offline
+	if community.Settings, err = s.storage.GetCommunitySettings(ctx, user.Community.Id); err != nil {
+		return nil, err
+	}
+	if community.Plots, err = s.storage.GetPlots(ctx); err != nil {
+		return nil, err
+	}
+	constraints, err := s.storage.GetPlotConstraints(ctx, user.Community.Id)
+	if err != nil {
+		return nil, err
+	}
+	community.Pins, community.Reserved = constraints.Pins, constraints.Reserved
+
 	return community, nil
 }
 
 func (s *communityServiceImpl) UploadCommunityData(ctx context.Context, data *model.AssignmentUpload) ([]model.Assignment, error) {
 	user := ctx.Value(middleware.CtxUser).(*model.User)
+	if err := requireStatus(ctx, s.storage, user.Community.Id, "upload assignments", model.CommunityStatus.AssignmentsOpen); err != nil {
+		return nil, err
+	}
 	assignments := make([]model.Assignment, 0, len(data.Members))
 	for _, member := range data.Members {
 		if member.Assignment.Battletag == "" {
 			continue
 		}
+		neighborhood := member.Assignment.Neighborhood
+		if neighborhood == 0 {
+			// files downloaded before neighborhoods existed
+			neighborhood = 1
+		}
 		assignments = append(assignments, model.Assignment{
-			Battletag: member.Assignment.Battletag,
-			Score:     member.Assignment.Score,
-			Character: member.Assignment.Character,
-			Plot:      member.Assignment.Plot,
+			Battletag:    member.Assignment.Battletag,
+			Score:        member.Assignment.Score,
+			Character:    member.Assignment.Character,
+			Neighborhood: neighborhood,
+			Plot:         member.Assignment.Plot,
 		})
 	}
 
-	if len(assignments) > 53 {
-		return nil, fmt.Errorf("too many assignments. community is overcrowded.")
+	plots, err := s.storage.GetPlots(ctx)
+	if err != nil {
+		log.Printf("Failed to load plot catalog: %v", err)
+		return nil, err
+	}
+	neighborhoods, err := validateAssignments(assignments, plots)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkNeighborhood(ctx, user.Community.Id, neighborhoods); err != nil {
+		return nil, err
+	}
+
+	previous, err := s.storage.GetAssignments(ctx, user.Community.Id)
+	if err != nil {
+		log.Printf("Failed to fetch previous assignments: %v", err)
+		return nil, err
 	}
-	err := s.storage.RegisterManualUsers(ctx, assignments, user.Community.Id)
-	err = s.storage.PersistAndLock(ctx, assignments, user.Community.Id)
+
+	// unknown members are registered as manual members in the transaction that assigns them
+	err = s.storage.PersistAndLock(ctx, assignments, user.Community.Id, model.CommunityStatus.AssignmentsOpen,
+		&model.RevisionOrigin{Source: model.REVISION_UPLOAD, CreatedBy: user.Battletag},
+		auditEvent(ctx, model.AUDIT_ASSIGNMENTS_UPLOADED, "", previous, assignments))
 	if err != nil {
 		log.Printf("Error persisting overwritten assignments: %v", err)
 		return nil, err
@@ -99,63 +176,252 @@ func (s *communityServiceImpl) UploadCommunityData(ctx context.Context, data *mo
 	return assignments, nil
 }
 
-func (s *communityServiceImpl) JoinCommunity(ctx context.Context, communityId string) (string, error) {
+func (s *communityServiceImpl) JoinCommunity(ctx context.Context, communityId string) (*model.JoinedCommunity, error) {
 	user := ctx.Value(middleware.CtxUser).(*model.User)
+	if err := requireStatus(ctx, s.storage, communityId, "join", model.CommunityStatus.Active); err != nil {
+		return nil, err
+	}
 	occupancy, err := s.storage.GetCommunitySize(ctx, communityId)
 	if err != nil {
 		log.Printf("Error retrieving community occupancy from database: %v", err)
-		return "", err
-	}
-	if occupancy > 53 {
-		return "", fmt.Errorf("Community is full. Apologies.")
+		return nil, err
 	}
-
 	community, requiredRank, err := s.storage.GetCommunity(ctx, communityId)
 	if err != nil {
 		log.Printf("Error retrieving community to join from database: %v", err)
-		return "", err
+		return nil, err
+	}
+
+	plots, err := s.storage.GetPlots(ctx)
+	if err != nil {
+		log.Printf("Error retrieving plot catalog from database: %v", err)
+		return nil, err
+	}
+	if len(plots) == 0 {
+		return nil, fmt.Errorf("no plots available")
+	}
+
+	// characters are only visible to a session opened in the community's region
+	if user.Region != community.Region {
+		return nil, fmt.Errorf("log in with a %s Battle.net account to join this community", strings.ToUpper(string(community.Region)))
 	}
 
-	client := oauth.GetClient(ctx)
-	bnetService := NewBnetService(client, s.storage)
+	client := s.bnet.GetClient(ctx, s.storage)
+	bnetService := NewBnetService(client, s.storage, s.bnet.Endpoints, community.Region)
 
 	profile, err := bnetService.GetProfile(ctx)
 	if err != nil {
 		log.Printf("Failed to retrieve wow profile: %v", err)
-		return "", err
+		return nil, err
 	}
 	roster, err := bnetService.GetGuildRoster(ctx, community)
+	if err != nil {
+		log.Printf("Failed to retrieve guild roster: %v", err)
+		return nil, err
+	}
 
-	joinedChar, err := s.storage.JoinCommunity(ctx, user, requiredRank, communityId, profile, roster)
-	return joinedChar, err
-}
+	// the joined characters are only known once stored, so the storage fills in the after state
+	event := auditEvent(ctx, model.AUDIT_MEMBER_JOINED, user.Battletag, nil, nil)
+	joined, err := s.storage.JoinCommunity(ctx, user, requiredRank, communityId, profile, roster, event)
+	if errors.Is(err, storage.ErrRankRequirement) {
+		return s.requestJoin(ctx, user, communityId, profile, roster)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-func (s *communityServiceImpl) ToggleCommunityLock(ctx context.Context, user *model.User) ([]model.Assignment, error) {
-	// unlock if locked
-	if user.Community.Locked {
-		err := s.storage.UnlockCommunity(ctx, user.Community.Id)
+	// overflow into a new neighborhood instead of turning members away
+	if occupancy >= len(plots)*community.Neighborhoods {
+		err = s.storage.EnsureNeighborhoods(ctx, communityId, community.Neighborhoods+1)
 		if err != nil {
-			log.Printf("Failed to unlock community: %v", err)
+			log.Printf("Failed to open an overflow neighborhood: %v", err)
 			return nil, err
 		}
-		log.Printf("Community %s locked.", user.Community.Id)
-		return nil, nil
 	}
+	return joined, nil
+}
+
+// requestJoin queues users below the member rank for an officer, if the community takes requests.
+func (s *communityServiceImpl) requestJoin(
+	ctx context.Context,
+	user *model.User,
+	communityId string,
+	profile *model.WowProfile,
+	roster *model.Roster,
+) (*model.JoinedCommunity, error) {
+	settings, err := s.storage.GetCommunitySettings(ctx, communityId)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.JoinRequests {
+		return nil, storage.ErrRankRequirement
+	}
+
+	event := auditEvent(ctx, model.AUDIT_JOIN_REQUESTED, user.Battletag, nil, nil)
+	request, err := s.storage.RequestJoin(ctx, user, communityId, profile, roster, event)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status == model.JOIN_REQUEST_DENIED {
+		return nil, fmt.Errorf("Your request to join was denied.")
+	}
+	log.Printf("%s requested to join community %s.", user.Battletag, communityId)
+	return &model.JoinedCommunity{Request: request}, nil
+}
+
+func (s *communityServiceImpl) GetJoinRequests(ctx context.Context, communityId string) ([]model.JoinRequest, error) {
+	return s.storage.GetJoinRequests(ctx, communityId)
+}
+
+// DecideJoinRequest answers a request. Approved users are let in the next time they join.
+func (s *communityServiceImpl) DecideJoinRequest(ctx context.Context, communityId string, battletag string, approve bool) error {
+	user := ctx.Value(middleware.CtxUser).(*model.User)
+	if err := requireStatus(ctx, s.storage, communityId, "answer join requests", model.CommunityStatus.Active); err != nil {
+		return err
+	}
+	event := &model.AuditEvent{Actor: user.Battletag, Action: model.AUDIT_JOIN_DENIED, Target: battletag}
+	if approve {
+		event.Action = model.AUDIT_JOIN_APPROVED
+	}
+	return s.storage.DecideJoinRequest(ctx, communityId, battletag, event)
+}
+
+func (s *communityServiceImpl) GetAuditEvents(ctx context.Context, communityId string, filter *model.AuditFilter) ([]model.AuditEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = model.AUDIT_PAGE_SIZE
+	}
+	filter.Limit = min(filter.Limit, model.AUDIT_MAX_PAGE_SIZE)
+	return s.storage.GetAuditEvents(ctx, communityId, filter)
+}
+
+func (s *communityServiceImpl) LeaveCommunity(ctx context.Context) error {
+	user := ctx.Value(middleware.CtxUser).(*model.User)
+	if len(user.Community.Id) == 0 {
+		return storage.ErrNotFound
+	}
+
+	event := &model.AuditEvent{Actor: user.Battletag, Action: model.AUDIT_MEMBER_LEFT, Target: user.Battletag}
+	origin := &model.RevisionOrigin{Source: model.REVISION_REMOVAL, CreatedBy: user.Battletag}
+	if err := s.storage.RemoveMember(ctx, user.Community.Id, user.Battletag, event, origin, time.Time{}); err != nil {
+		return err
+	}
+	log.Printf("%s left community %s.", user.Battletag, user.Community.Id)
+	return nil
+}
+
+// RemoveMember lets an officer take someone of a lower rank out of the community. With
+// block the member can't join again for REJOIN_BLOCK.
+func (s *communityServiceImpl) RemoveMember(ctx context.Context, communityId string, battletag string, block bool) error {
+	user := ctx.Value(middleware.CtxUser).(*model.User)
+	if strings.EqualFold(user.Battletag, battletag) {
+		return ErrRemoveSelf
+	}
+	if err := requireStatus(ctx, s.storage, communityId, "remove members", model.CommunityStatus.Active); err != nil {
+		return err
+	}
+
+	event := &model.AuditEvent{Actor: user.Battletag, Action: model.AUDIT_MEMBER_REMOVED, Target: battletag}
+	var blockedUntil time.Time
+	if block {
+		blockedUntil = time.Now().Add(model.REJOIN_BLOCK)
+		event.Detail = "blocked until " + blockedUntil.Format(time.DateOnly)
+	}
+	origin := &model.RevisionOrigin{Source: model.REVISION_REMOVAL, CreatedBy: user.Battletag}
+	if err := s.storage.RemoveMember(ctx, communityId, battletag, event, origin, blockedUntil); err != nil {
+		return err
+	}
+	log.Printf("%s removed %s from community %s.", user.Battletag, battletag, communityId)
+	return nil
+}
+
+func (s *communityServiceImpl) Optimize(ctx context.Context, options model.OptimizeOptions) (*model.OptimizationResult, error) {
+	result, err := s.optimize(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	s.recordRun(ctx, options, result, false)
+	return result, nil
+}
+
+func (s *communityServiceImpl) optimize(ctx context.Context, options model.OptimizeOptions) (*model.OptimizationResult, error) {
 	community, err := s.GetCommunityData(ctx)
 	if err != nil {
 		log.Printf("Failed to fetch community to optimize: %v", err)
 		return nil, err
 	}
 
-	assignments := community.Optimize()
+	if options.Stable {
+		community.Previous, err = s.storage.GetAssignments(ctx, community.Id)
+		if err != nil {
+			log.Printf("Failed to fetch previous assignments: %v", err)
+			return nil, err
+		}
+	}
+
+	return community.Optimize(options), nil
+}
+
+// ToggleCommunityLock locks a collecting community and unlocks a locked one.
+func (s *communityServiceImpl) ToggleCommunityLock(ctx context.Context, user *model.User, options model.OptimizeOptions) (*model.OptimizationResult, error) {
+	to := model.STATUS_LOCKED
+	if user.Community.Status.Locked() {
+		to = model.STATUS_COLLECTING
+	}
+	return s.SetCommunityStatus(ctx, user, to, options)
+}
+
+// SetCommunityStatus moves the community along its lifecycle. Locking a collecting community
+// runs the optimizer and persists its assignments, every other step only changes the status.
+func (s *communityServiceImpl) SetCommunityStatus(
+	ctx context.Context,
+	user *model.User,
+	to model.CommunityStatus,
+	options model.OptimizeOptions,
+) (*model.OptimizationResult, error) {
+	from, err := s.storage.GetCommunityStatus(ctx, user.Community.Id)
+	if err != nil {
+		log.Printf("Failed to read community status: %v", err)
+		return nil, err
+	}
+	if !from.CanBecome(to) {
+		return nil, &model.StatusError{Status: from, To: to}
+	}
+	if from == model.STATUS_COLLECTING && to == model.STATUS_LOCKED {
+		return s.lock(ctx, user, options)
+	}
+
+	event := auditEvent(ctx, statusActions[[2]model.CommunityStatus{from, to}], "",
+		map[string]model.CommunityStatus{"status": from}, map[string]model.CommunityStatus{"status": to})
+	if err := s.storage.TransitionCommunity(ctx, user.Community.Id, to, event); err != nil {
+		log.Printf("Failed to move community %s to %s: %v", user.Community.Id, to, err)
+		return nil, err
+	}
+	log.Printf("Community %s is %s.", user.Community.Id, to)
+	return nil, nil
+}
+
+func (s *communityServiceImpl) lock(ctx context.Context, user *model.User, options model.OptimizeOptions) (*model.OptimizationResult, error) {
+	result, err := s.optimize(ctx, options)
+	if err != nil {
+		return nil, err
+	}
 
-	err = s.storage.PersistAndLock(ctx, assignments, community.Id)
+	previous, err := s.storage.GetAssignments(ctx, user.Community.Id)
+	if err != nil {
+		log.Printf("Failed to fetch previous assignments: %v", err)
+		return nil, err
+	}
+	// only one lock gets to persist its result, a concurrent one finds the community locked
+	err = s.storage.PersistAndLock(ctx, result.Assignments, user.Community.Id, model.CommunityStatus.Collecting,
+		&model.RevisionOrigin{Source: model.REVISION_LOCK, CreatedBy: user.Battletag},
+		auditEvent(ctx, model.AUDIT_COMMUNITY_LOCKED, "", previous, result.Assignments))
 	if err != nil {
 		log.Printf("Error persisting assignments: %v", err)
 		return nil, err
 	}
-	log.Printf("Community %s locked.", community.Id)
-	return assignments, nil
+	s.recordRun(ctx, options, result, true)
+	log.Printf("Community %s locked.", user.Community.Id)
+	return result, nil
 }
 
 func (s *communityServiceImpl) GetAssignments(ctx context.Context, communityId string) ([]model.Assignment, error) {
@@ -163,13 +429,329 @@ func (s *communityServiceImpl) GetAssignments(ctx context.Context, communityId s
 }
 
 func (s *communityServiceImpl) SetAssignment(ctx context.Context, req *model.SingleAssignmentRequest, communityId string) error {
-	return s.storage.SetAssignment(ctx, req, communityId)
+	if err := requireStatus(ctx, s.storage, communityId, "change assignments", model.CommunityStatus.AssignmentsOpen); err != nil {
+		return err
+	}
+	if req.Neighborhood == 0 {
+		req.Neighborhood = 1
+	}
+	if err := s.checkNeighborhood(ctx, communityId, req.Neighborhood); err != nil {
+		return err
+	}
+	assignments, err := s.storage.GetAssignments(ctx, communityId)
+	if err != nil {
+		return err
+	}
+	key := model.PlotKey{Neighborhood: req.Neighborhood, Plot: req.PlotId}
+	// the member ends up alone on the plot, whoever held it and their old plot are released
+	after := []model.Assignment{{Character: req.Char, Battletag: req.Battletag, Neighborhood: req.Neighborhood, Plot: req.PlotId}}
+	event := auditEvent(ctx, model.AUDIT_ASSIGNMENT_SET, req.Battletag, assignmentsOf(assignments, req.Battletag, key), after)
+
+	origin := &model.RevisionOrigin{Source: model.REVISION_EDIT}
+	if user, ok := ctx.Value(middleware.CtxUser).(*model.User); ok {
+		origin.CreatedBy = user.Battletag
+	}
+	if err := s.storage.SetAssignment(ctx, req, communityId, origin, event); err != nil {
+		return err
+	}
+	if req.Pin {
+		return s.PinMember(ctx, communityId, &model.Pin{
+			Battletag:    req.Battletag,
+			Neighborhood: req.Neighborhood,
+			Plot:         req.PlotId,
+		})
+	}
+	return nil
 }
 
 func (s *communityServiceImpl) SetCommunitySettings(ctx context.Context, communityId string, req *model.CommunityRankRequest) error {
-	return s.storage.SetOfficerRank(ctx, communityId, req)
+	if err := requireStatus(ctx, s.storage, communityId, "change settings", model.CommunityStatus.Active); err != nil {
+		return err
+	}
+	before, err := s.storage.GetCommunitySettings(ctx, communityId)
+	if err != nil {
+		return err
+	}
+	event := auditEvent(ctx, model.AUDIT_SETTINGS_CHANGED, "", before, before.With(req))
+	return s.storage.SetOfficerRank(ctx, communityId, req, event)
 }
 
 func (s *communityServiceImpl) GetCommunitySettings(ctx context.Context, communityId string) (*model.Settings, error) {
 	return s.storage.GetCommunitySettings(ctx, communityId)
 }
+
+// SetPreferenceDeadline moves or removes the deadline. Moving it into the future opens preferences again.
+func (s *communityServiceImpl) SetPreferenceDeadline(ctx context.Context, communityId string, deadline *time.Time) error {
+	if err := requireStatus(ctx, s.storage, communityId, "change the deadline", model.CommunityStatus.Active); err != nil {
+		return err
+	}
+	settings, err := s.storage.GetCommunitySettings(ctx, communityId)
+	if err != nil {
+		return err
+	}
+	event := auditEvent(ctx, model.AUDIT_DEADLINE_CHANGED, "",
+		map[string]*time.Time{"deadline": settings.PreferenceDeadline}, map[string]*time.Time{"deadline": deadline})
+	return s.storage.SetPreferenceDeadline(ctx, communityId, deadline, event)
+}
+
+// LockOverdueCommunities locks every community whose preference deadline passed, the same way an
+// officer would. A community an officer locked before the deadline keeps its assignments.
+func (s *communityServiceImpl) LockOverdueCommunities(ctx context.Context) error {
+	communities, err := s.storage.GetOverdueCommunities(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, c := range communities {
+		if c.Status == model.STATUS_COLLECTING {
+			scheduler := &model.User{
+				Battletag: model.SCHEDULER_ACTOR,
+				Community: model.UserCommunity{Id: c.Id, Name: c.Name, Realm: c.Realm, Region: c.Region},
+			}
+			lockCtx := context.WithValue(ctx, middleware.CtxUser, scheduler)
+			if _, err := s.SetCommunityStatus(lockCtx, scheduler, model.STATUS_LOCKED, model.OptimizeOptions{}); err != nil {
+				log.Printf("Failed to lock community %s at its deadline: %v", c.Id, err)
+				continue
+			}
+		}
+		if err := s.storage.MarkDeadlineLocked(ctx, c.Id); err != nil {
+			log.Printf("Failed to mark deadline of community %s as handled: %v", c.Id, err)
+			continue
+		}
+		log.Printf("Preference deadline of community %s passed.", c.Id)
+	}
+	return nil
+}
+
+func (s *communityServiceImpl) GetRevisions(ctx context.Context, communityId string) ([]model.AssignmentRevision, error) {
+	return s.storage.GetRevisions(ctx, communityId)
+}
+
+func (s *communityServiceImpl) GetRevision(ctx context.Context, communityId string, revision int) (*model.AssignmentRevision, error) {
+	return s.storage.GetRevision(ctx, communityId, revision)
+}
+
+func (s *communityServiceImpl) DiffRevisions(ctx context.Context, communityId string, from, to int) (*model.RevisionDiff, error) {
+	before, err := s.storage.GetRevision(ctx, communityId, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.storage.GetRevision(ctx, communityId, to)
+	if err != nil {
+		return nil, err
+	}
+	return &model.RevisionDiff{From: from, To: to, Changes: model.DiffAssignments(before.Assignments, after.Assignments)}, nil
+}
+
+// RestoreRevision brings back the assignments of an earlier revision as a new revision.
+// Members who have left since don't get their plot back.
+func (s *communityServiceImpl) RestoreRevision(ctx context.Context, communityId string, revision int) (*model.AssignmentRevision, error) {
+	user := ctx.Value(middleware.CtxUser).(*model.User)
+	if err := requireStatus(ctx, s.storage, communityId, "restore a revision", model.CommunityStatus.AssignmentsOpen); err != nil {
+		return nil, err
+	}
+	restored, err := s.storage.GetRevision(ctx, communityId, revision)
+	if err != nil {
+		return nil, err
+	}
+	community, err := s.storage.GetCommunityData(ctx, user)
+	if err != nil {
+		log.Printf("Failed to fetch members to restore revision %d: %v", revision, err)
+		return nil, err
+	}
+	assignments := slices.DeleteFunc(restored.Assignments, func(a model.Assignment) bool {
+		return !slices.ContainsFunc(community.Members, func(m model.MemberData) bool { return m.BattleTag == a.Battletag })
+	})
+
+	neighborhoods := 1
+	for _, a := range assignments {
+		neighborhoods = max(neighborhoods, a.Neighborhood)
+	}
+	if err := s.checkNeighborhood(ctx, communityId, neighborhoods); err != nil {
+		return nil, err
+	}
+	previous, err := s.storage.GetAssignments(ctx, communityId)
+	if err != nil {
+		log.Printf("Failed to fetch previous assignments: %v", err)
+		return nil, err
+	}
+
+	origin := &model.RevisionOrigin{Source: model.REVISION_RESTORE, CreatedBy: user.Battletag, RestoredFrom: revision}
+	event := auditEvent(ctx, model.AUDIT_REVISION_RESTORED, "", previous, assignments)
+	if err := s.storage.PersistAndLock(ctx, assignments, communityId, model.CommunityStatus.AssignmentsOpen, origin, event); err != nil {
+		log.Printf("Error restoring revision %d: %v", revision, err)
+		return nil, err
+	}
+	log.Printf("Community %s restored revision %d.", communityId, revision)
+
+	revisions, err := s.storage.GetRevisions(ctx, communityId)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, storage.ErrNotFound
+	}
+	latest := &revisions[0]
+	latest.Assignments = assignments
+	return latest, nil
+}
+
+// validateAssignments checks uploaded assignments against the plot catalog
+// and returns the number of neighborhoods they occupy.
+func validateAssignments(assignments []model.Assignment, plots []model.Plot) (int, error) {
+	known := make(map[int]bool, len(plots))
+	for _, p := range plots {
+		known[p.Id] = true
+	}
+
+	neighborhoods := 1
+	taken := make(map[model.PlotKey]string, len(assignments))
+	for _, a := range assignments {
+		if !known[a.Plot] {
+			return 0, fmt.Errorf("plot %d does not exist", a.Plot)
+		}
+		if a.Neighborhood < 1 {
+			return 0, fmt.Errorf("invalid neighborhood %d for %s", a.Neighborhood, a.Battletag)
+		}
+		key := model.PlotKey{Neighborhood: a.Neighborhood, Plot: a.Plot}
+		if holder, ok := taken[key]; ok {
+			return 0, fmt.Errorf("plot %d in neighborhood %d is assigned to both %s and %s", a.Plot, a.Neighborhood, holder, a.Battletag)
+		}
+		taken[key] = a.Battletag
+		neighborhoods = max(neighborhoods, a.Neighborhood)
+	}
+	return neighborhoods, nil
+}
+
+// checkNeighborhood refuses a neighborhood the community would have to grow by more than one instance for.
+func (s *communityServiceImpl) checkNeighborhood(ctx context.Context, communityId string, neighborhood int) error {
+	settings, err := s.storage.GetCommunitySettings(ctx, communityId)
+	if err != nil {
+		log.Printf("Failed to read neighborhoods of community %s: %v", communityId, err)
+		return err
+	}
+	return model.CheckNeighborhood(neighborhood, settings.Neighborhoods)
+}
+
+func (s *communityServiceImpl) GetPlotConstraints(ctx context.Context, communityId string) (*model.PlotConstraints, error) {
+	return s.storage.GetPlotConstraints(ctx, communityId)
+}
+
+func (s *communityServiceImpl) PinMember(ctx context.Context, communityId string, pin *model.Pin) error {
+	if err := requireStatus(ctx, s.storage, communityId, "pin members", model.CommunityStatus.AssignmentsOpen); err != nil {
+		return err
+	}
+	if pin.Neighborhood == 0 {
+		pin.Neighborhood = 1
+	}
+	if err := s.checkNeighborhood(ctx, communityId, pin.Neighborhood); err != nil {
+		return err
+	}
+
+	constraints, err := s.storage.GetPlotConstraints(ctx, communityId)
+	if err != nil {
+		return err
+	}
+	if constraints.IsReserved(pin.Neighborhood, pin.Plot) {
+		return fmt.Errorf("plot %d in neighborhood %d is reserved", pin.Plot, pin.Neighborhood)
+	}
+
+	event := auditEvent(ctx, model.AUDIT_MEMBER_PINNED, pin.Battletag, constraints.PinOf(pin.Battletag), pin)
+	return s.storage.SetPin(ctx, communityId, pin, event)
+}
+
+func (s *communityServiceImpl) UnpinMember(ctx context.Context, communityId string, battletag string) error {
+	if err := requireStatus(ctx, s.storage, communityId, "unpin members", model.CommunityStatus.AssignmentsOpen); err != nil {
+		return err
+	}
+	constraints, err := s.storage.GetPlotConstraints(ctx, communityId)
+	if err != nil {
+		return err
+	}
+	event := auditEvent(ctx, model.AUDIT_MEMBER_UNPINNED, battletag, constraints.PinOf(battletag), nil)
+	return s.storage.RemovePin(ctx, communityId, battletag, event)
+}
+
+func (s *communityServiceImpl) ReservePlot(ctx context.Context, communityId string, reserved *model.ReservedPlot) error {
+	if err := requireStatus(ctx, s.storage, communityId, "reserve plots", model.CommunityStatus.AssignmentsOpen); err != nil {
+		return err
+	}
+	if reserved.Neighborhood == 0 {
+		reserved.Neighborhood = 1
+	}
+	if reserved.Status == "" {
+		reserved.Status = model.PLOT_RESERVED
+	}
+	if reserved.Status != model.PLOT_RESERVED && reserved.Status != model.PLOT_UNAVAILABLE {
+		return fmt.Errorf("unknown plot status %q", reserved.Status)
+	}
+
+	constraints, err := s.storage.GetPlotConstraints(ctx, communityId)
+	if err != nil {
+		return err
+	}
+	for _, pin := range constraints.Pins {
+		if pin.Neighborhood == reserved.Neighborhood && pin.Plot == reserved.Plot {
+			return fmt.Errorf("plot %d in neighborhood %d is pinned to %s", pin.Plot, pin.Neighborhood, pin.Battletag)
+		}
+	}
+
+	event := auditEvent(ctx, model.AUDIT_PLOT_RESERVED, "", constraints.ReservationOf(reserved.Neighborhood, reserved.Plot), reserved)
+	return s.storage.ReservePlot(ctx, communityId, reserved, event)
+}
+
+func (s *communityServiceImpl) ReleasePlot(ctx context.Context, communityId string, neighborhood, plot int) error {
+	if err := requireStatus(ctx, s.storage, communityId, "release plots", model.CommunityStatus.AssignmentsOpen); err != nil {
+		return err
+	}
+	constraints, err := s.storage.GetPlotConstraints(ctx, communityId)
+	if err != nil {
+		return err
+	}
+	event := auditEvent(ctx, model.AUDIT_PLOT_RELEASED, "", constraints.ReservationOf(neighborhood, plot), nil)
+	return s.storage.ReleasePlot(ctx, communityId, neighborhood, plot, event)
+}
+
+// recordRun keeps a trail of which objective produced which statistics.
+// Failing to record never fails the optimization itself.
+func (s *communityServiceImpl) recordRun(ctx context.Context, options model.OptimizeOptions, result *model.OptimizationResult, persisted bool) {
+	user, ok := ctx.Value(middleware.CtxUser).(*model.User)
+	if !ok {
+		return
+	}
+	run := &model.OptimizationRun{
+		Objective: result.Objective,
+		Stable:    options.Stable,
+		Persisted: persisted,
+		Stats:     result.Stats,
+		CreatedBy: user.Battletag,
+	}
+	if err := s.storage.RecordOptimizationRun(ctx, user.Community.Id, run); err != nil {
+		log.Printf("Failed to record optimization run: %v", err)
+	}
+}
+
+func (s *communityServiceImpl) GetOptimizationRuns(ctx context.Context, communityId string) ([]model.OptimizationRun, error) {
+	return s.storage.GetOptimizationRuns(ctx, communityId, 50)
+}
+
+func (s *communityServiceImpl) ExplainAssignments(ctx context.Context, battletag string) ([]model.MemberExplanation, error) {
+	community, err := s.GetCommunityData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments, err := s.storage.GetAssignments(ctx, community.Id)
+	if err != nil {
+		log.Printf("Failed to fetch assignments to explain: %v", err)
+		return nil, err
+	}
+
+	// without a lock there is nothing persisted yet, so explain a fresh solve
+	options := model.OptimizeOptions{}
+	if len(assignments) == 0 {
+		assignments = community.Optimize(options).Assignments
+	}
+
+	return community.Explain(assignments, options, battletag), nil
+}
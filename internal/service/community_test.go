@@ -0,0 +1,431 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/sbraitsch/plotter/internal/middleware"
+	"github.com/sbraitsch/plotter/internal/model"
+	"github.com/sbraitsch/plotter/internal/storage"
+)
+
+var testPlots = []model.Plot{{Id: 1, X: 0}, {Id: 2, X: 100}, {Id: 3, X: 200}}
+
+type testCommunity struct {
+	store   *storage.MemoryStore
+	service CommunityService
+	ctx     context.Context
+	officer *model.User
+}
+
+// newTestCommunity sets up a community in a MemoryStore with an officer in the context
+// and a member for every set of preferences, named member0#1, member1#1 and so on.
+func newTestCommunity(t *testing.T, preferences ...map[model.PlotKey]int) *testCommunity {
+	t.Helper()
+	store := storage.NewMemoryStore(testPlots)
+	ctx := context.Background()
+
+	communities, err := store.InsertGuilds(ctx, []model.Community{{Name: "Test", Realm: "test", Region: model.DEFAULT_REGION}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	communityId := communities[0].Id
+
+	members := []model.Assignment{{Battletag: "officer#1", Character: "Officer"}}
+	for i := range preferences {
+		members = append(members, model.Assignment{Battletag: memberTag(i), Character: memberTag(i)})
+	}
+	if err := store.RegisterManualUsers(ctx, members, communityId); err != nil {
+		t.Fatal(err)
+	}
+	for i, mappings := range preferences {
+		member := &model.User{Battletag: memberTag(i), Community: model.UserCommunity{Id: communityId}}
+		event := &model.AuditEvent{Actor: member.Battletag, Action: model.AUDIT_PREFERENCES_CHANGED, Target: member.Battletag}
+		if err := store.SavePlotMappings(ctx, member, mappings, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	officer := &model.User{
+		Battletag: "officer#1",
+		Community: model.UserCommunity{Id: communityId, Status: model.STATUS_COLLECTING},
+	}
+	return &testCommunity{
+		store:   store,
+		service: NewCommunityService(store, nil),
+		ctx:     context.WithValue(ctx, middleware.CtxUser, officer),
+		officer: officer,
+	}
+}
+
+func memberTag(i int) string {
+	return fmt.Sprintf("member%d#1", i)
+}
+
+func prefer(plots ...int) map[model.PlotKey]int {
+	mappings := make(map[model.PlotKey]int, len(plots))
+	for i, plot := range plots {
+		mappings[model.PlotKey{Plot: plot}] = i + 1
+	}
+	return mappings
+}
+
+func (c *testCommunity) assignments(t *testing.T) map[string]int {
+	t.Helper()
+	assignments, err := c.service.GetAssignments(c.ctx, c.officer.Community.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plots := make(map[string]int, len(assignments))
+	for _, a := range assignments {
+		plots[a.Battletag] = a.Plot
+	}
+	return plots
+}
+
+func (c *testCommunity) status(t *testing.T) model.CommunityStatus {
+	t.Helper()
+	status, err := c.store.GetCommunityStatus(c.ctx, c.officer.Community.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+func TestOptimize(t *testing.T) {
+	c := newTestCommunity(t, prefer(2, 1), prefer(2))
+
+	result, err := c.service.Optimize(c.ctx, model.OptimizeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// member0 gives up plot 2 for their second choice, member1 ranked nothing else
+	placed := make(map[string]model.Assignment, len(result.Assignments))
+	for _, a := range result.Assignments {
+		placed[a.Battletag] = a
+	}
+	if placed[memberTag(0)].Plot != 1 || placed[memberTag(1)].Plot != 2 {
+		t.Errorf("unexpected assignments %+v", result.Assignments)
+	}
+	if len(c.assignments(t)) != 0 {
+		t.Error("optimizing alone must not persist assignments")
+	}
+
+	runs, err := c.service.GetOptimizationRuns(c.ctx, c.officer.Community.Id)
+	if err != nil || len(runs) != 1 || runs[0].Persisted {
+		t.Errorf("want one unpersisted run, got %+v (%v)", runs, err)
+	}
+}
+
+func TestLockAndUnlock(t *testing.T) {
+	c := newTestCommunity(t, prefer(1), prefer(2))
+
+	result, err := c.service.ToggleCommunityLock(c.ctx, c.officer, model.OptimizeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result == nil || len(result.Assignments) != 3 {
+		t.Fatalf("locking should return the optimized assignments, got %+v", result)
+	}
+	if status := c.status(t); status != model.STATUS_LOCKED {
+		t.Fatalf("status %s after locking", status)
+	}
+	if plots := c.assignments(t); plots[memberTag(0)] != 1 || plots[memberTag(1)] != 2 {
+		t.Errorf("locking persisted %v", plots)
+	}
+
+	// a lock racing the first one must not overwrite its assignments
+	err = c.store.PersistAndLock(c.ctx, nil, c.officer.Community.Id, model.CommunityStatus.Collecting,
+		&model.RevisionOrigin{Source: model.REVISION_LOCK}, &model.AuditEvent{Action: model.AUDIT_COMMUNITY_LOCKED})
+	var statusErr *model.StatusError
+	if !errors.As(err, &statusErr) || len(c.assignments(t)) != 3 {
+		t.Errorf("locking twice: %v, %v", err, c.assignments(t))
+	}
+
+	c.officer.Community.Status = model.STATUS_LOCKED
+	if result, err := c.service.ToggleCommunityLock(c.ctx, c.officer, model.OptimizeOptions{}); err != nil || result != nil {
+		t.Fatalf("unlocking: %+v, %v", result, err)
+	}
+	if status := c.status(t); status != model.STATUS_COLLECTING {
+		t.Errorf("status %s after unlocking", status)
+	}
+}
+
+func TestSetAssignment(t *testing.T) {
+	c := newTestCommunity(t, prefer(1), prefer(2))
+	communityId := c.officer.Community.Id
+
+	err := c.service.SetAssignment(c.ctx, &model.SingleAssignmentRequest{Battletag: memberTag(0), Char: memberTag(0), PlotId: 3}, communityId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a plot holds one member, the previous holder loses it
+	err = c.service.SetAssignment(c.ctx, &model.SingleAssignmentRequest{Battletag: memberTag(1), Char: memberTag(1), PlotId: 3, Pin: true}, communityId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plots := c.assignments(t); len(plots) != 1 || plots[memberTag(1)] != 3 {
+		t.Errorf("assignments after overwriting plot 3: %v", plots)
+	}
+
+	constraints, err := c.service.GetPlotConstraints(c.ctx, communityId)
+	if err != nil || len(constraints.Pins) != 1 || constraints.Pins[0].Battletag != memberTag(1) {
+		t.Errorf("pinning with the assignment: %+v, %v", constraints, err)
+	}
+
+	// a manual assignment registers unknown members
+	err = c.service.SetAssignment(c.ctx, &model.SingleAssignmentRequest{Battletag: "manual#1", Char: "Manual", PlotId: 1}, communityId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plots := c.assignments(t); plots["manual#1"] != 1 {
+		t.Errorf("manual member was not assigned: %v", plots)
+	}
+
+	revisions, err := c.service.GetRevisions(c.ctx, communityId)
+	if err != nil || len(revisions) != 3 {
+		t.Errorf("want a revision per edit, got %d (%v)", len(revisions), err)
+	}
+	events, err := c.service.GetAuditEvents(c.ctx, communityId, &model.AuditFilter{Action: model.AUDIT_ASSIGNMENT_SET})
+	if err != nil || len(events) != 3 {
+		t.Errorf("want an audit event per edit, got %d (%v)", len(events), err)
+	}
+}
+
+func TestSetAssignmentRefusedWhenFinalized(t *testing.T) {
+	c := newTestCommunity(t, prefer(1))
+	for _, to := range []model.CommunityStatus{model.STATUS_LOCKED, model.STATUS_FINALIZED} {
+		if _, err := c.service.SetCommunityStatus(c.ctx, c.officer, to, model.OptimizeOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := c.service.SetAssignment(c.ctx, &model.SingleAssignmentRequest{Battletag: memberTag(0), PlotId: 2}, c.officer.Community.Id)
+	var statusErr *model.StatusError
+	if !errors.As(err, &statusErr) || statusErr.Status != model.STATUS_FINALIZED {
+		t.Errorf("want a status error, got %v", err)
+	}
+	events, err := c.service.GetAuditEvents(c.ctx, c.officer.Community.Id, &model.AuditFilter{Action: model.AUDIT_ASSIGNMENT_SET})
+	if err != nil || len(events) != 0 {
+		t.Errorf("a refused edit must not be audited, got %+v (%v)", events, err)
+	}
+}
+
+func TestRemoveMemberRefusedForSameRank(t *testing.T) {
+	c := newTestCommunity(t, prefer(1))
+
+	err := c.service.RemoveMember(c.ctx, c.officer.Community.Id, memberTag(0), false)
+	if !errors.Is(err, storage.ErrOutranked) {
+		t.Errorf("want ErrOutranked, got %v", err)
+	}
+}
+
+func TestLeaveRecordsRevision(t *testing.T) {
+	c := newTestCommunity(t, prefer(1), prefer(2))
+	communityId := c.officer.Community.Id
+	if _, err := c.service.ToggleCommunityLock(c.ctx, c.officer, model.OptimizeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	member := &model.User{Battletag: memberTag(0), Community: model.UserCommunity{Id: communityId}}
+	if err := c.service.LeaveCommunity(context.WithValue(c.ctx, middleware.CtxUser, member)); err != nil {
+		t.Fatal(err)
+	}
+	if plots := c.assignments(t); len(plots) != 2 || plots[memberTag(0)] != 0 {
+		t.Errorf("assignments after leaving: %v", plots)
+	}
+
+	revisions, err := c.service.GetRevisions(c.ctx, communityId)
+	if err != nil || len(revisions) != 2 || revisions[0].Source != model.REVISION_REMOVAL {
+		t.Errorf("want a removal revision after the lock, got %+v (%v)", revisions, err)
+	}
+}
+
+func TestVersionMovesOnlyWithChanges(t *testing.T) {
+	c := newTestCommunity(t, prefer(1))
+	communityId := c.officer.Community.Id
+	version := func() int64 {
+		t.Helper()
+		v, err := c.service.GetVersion(c.ctx, communityId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	start := version()
+
+	stale := start + 1
+	ctx, claim := storage.WithVersionClaim(c.ctx, &stale)
+	err := c.service.SetAssignment(ctx, &model.SingleAssignmentRequest{Battletag: memberTag(0), Char: memberTag(0), PlotId: 1}, communityId)
+	if !errors.Is(err, storage.ErrVersionConflict) || !claim.Conflicted {
+		t.Errorf("want a version conflict, got %v", err)
+	}
+	if plots := c.assignments(t); len(plots) != 0 || version() != start {
+		t.Errorf("an outdated edit changed the community: %v at version %d", plots, version())
+	}
+
+	ctx, claim = storage.WithVersionClaim(c.ctx, &start)
+	err = c.service.SetAssignment(ctx, &model.SingleAssignmentRequest{Battletag: memberTag(0), Char: memberTag(0), PlotId: 99}, communityId)
+	if err == nil || version() != start {
+		t.Errorf("a failed edit moved the version to %d (%v)", version(), err)
+	}
+	err = c.service.SetAssignment(ctx, &model.SingleAssignmentRequest{Battletag: memberTag(0), Char: memberTag(0), PlotId: 1, Pin: true}, communityId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claim.Version != version() || claim.Version <= start {
+		t.Errorf("edit claimed version %d, community is at %d", claim.Version, version())
+	}
+
+	// leaving releases the plot, which officers have to see before their next edit
+	before := version()
+	member := &model.User{Battletag: memberTag(0), Community: model.UserCommunity{Id: communityId}}
+	if err := c.service.LeaveCommunity(context.WithValue(c.ctx, middleware.CtxUser, member)); err != nil {
+		t.Fatal(err)
+	}
+	if version() == before {
+		t.Error("leaving with an assignment did not move the version")
+	}
+}
+
+func TestUploadRegistersMembersWithTheAssignments(t *testing.T) {
+	c := newTestCommunity(t, prefer(1))
+	var upload model.AssignmentUpload
+	err := json.Unmarshal([]byte(`{"members": [
+		{"assignment": {"btag": "member0#1", "char": "member0#1", "neighborhood": 1, "plot": 2}},
+		{"assignment": {"btag": "manual#1", "char": "Manual", "neighborhood": 1, "plot": 3}}
+	]}`), &upload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	members := func() []string {
+		t.Helper()
+		community, err := c.store.GetCommunityData(c.ctx, c.officer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tags := []string{}
+		for _, m := range community.Members {
+			tags = append(tags, m.BattleTag)
+		}
+		return tags
+	}
+
+	// an upload that is refused must not leave its unknown members behind
+	stale := int64(-1)
+	ctx, _ := storage.WithVersionClaim(c.ctx, &stale)
+	if _, err := c.service.UploadCommunityData(ctx, &upload); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Fatalf("want a version conflict, got %v", err)
+	}
+	if tags := members(); slices.Contains(tags, "manual#1") {
+		t.Errorf("refused upload registered its members: %v", tags)
+	}
+
+	if _, err := c.service.UploadCommunityData(c.ctx, &upload); err != nil {
+		t.Fatal(err)
+	}
+	if tags := members(); !slices.Contains(tags, "manual#1") {
+		t.Errorf("uploaded member was not registered: %v", tags)
+	}
+	if plots := c.assignments(t); plots["manual#1"] != 3 || plots[memberTag(0)] != 2 {
+		t.Errorf("assignments after upload: %v", plots)
+	}
+}
+
+func TestNeighborhoodsGrowOneAtATime(t *testing.T) {
+	c := newTestCommunity(t, prefer(1))
+	communityId := c.officer.Community.Id
+
+	err := c.service.SetAssignment(c.ctx, &model.SingleAssignmentRequest{Battletag: memberTag(0), Neighborhood: 1000000, PlotId: 1}, communityId)
+	if !errors.Is(err, model.ErrInvalidNeighborhood) {
+		t.Errorf("want ErrInvalidNeighborhood, got %v", err)
+	}
+	err = c.service.PinMember(c.ctx, communityId, &model.Pin{Battletag: memberTag(0), Neighborhood: 3, Plot: 1})
+	if !errors.Is(err, model.ErrInvalidNeighborhood) {
+		t.Errorf("want ErrInvalidNeighborhood for a pin, got %v", err)
+	}
+	settings, err := c.store.GetCommunitySettings(c.ctx, communityId)
+	if err != nil || settings.Neighborhoods != 1 {
+		t.Errorf("refused edits grew the community: %+v (%v)", settings, err)
+	}
+
+	err = c.service.SetAssignment(c.ctx, &model.SingleAssignmentRequest{Battletag: memberTag(0), Neighborhood: 2, PlotId: 1}, communityId)
+	if err != nil {
+		t.Fatalf("the next neighborhood has to be open: %v", err)
+	}
+}
+
+func TestRefusedEditOpensNoNeighborhood(t *testing.T) {
+	c := newTestCommunity(t, prefer(1))
+	communityId := c.officer.Community.Id
+
+	stale := int64(-1)
+	ctx, _ := storage.WithVersionClaim(c.ctx, &stale)
+	err := c.service.SetAssignment(ctx, &model.SingleAssignmentRequest{Battletag: memberTag(0), Neighborhood: 2, PlotId: 1}, communityId)
+	if !errors.Is(err, storage.ErrVersionConflict) {
+		t.Fatalf("want a version conflict, got %v", err)
+	}
+	err = c.service.PinMember(ctx, communityId, &model.Pin{Battletag: memberTag(0), Neighborhood: 2, Plot: 1})
+	if !errors.Is(err, storage.ErrVersionConflict) {
+		t.Fatalf("want a version conflict for the pin, got %v", err)
+	}
+	settings, err := c.store.GetCommunitySettings(c.ctx, communityId)
+	if err != nil || settings.Neighborhoods != 1 {
+		t.Errorf("a refused edit opened a neighborhood: %+v (%v)", settings, err)
+	}
+}
+
+func TestPreferencesCloseWithTheLock(t *testing.T) {
+	c := newTestCommunity(t, prefer(1))
+	communityId := c.officer.Community.Id
+	if _, err := c.service.ToggleCommunityLock(c.ctx, c.officer, model.OptimizeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	version, err := c.service.GetVersion(c.ctx, communityId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	member := &model.User{Battletag: memberTag(0), Community: model.UserCommunity{Id: communityId}}
+	event := &model.AuditEvent{Actor: member.Battletag, Action: model.AUDIT_PREFERENCES_CHANGED, Target: member.Battletag}
+	err = c.store.SavePlotMappings(c.ctx, member, prefer(2), event)
+	var statusErr *model.StatusError
+	if !errors.As(err, &statusErr) || statusErr.Status != model.STATUS_LOCKED {
+		t.Errorf("want a status error, got %v", err)
+	}
+	if after, err := c.service.GetVersion(c.ctx, communityId); err != nil || after != version {
+		t.Errorf("refused preferences moved the version from %d to %d (%v)", version, after, err)
+	}
+}
+
+func TestClosedPreferencesAcceptUnchangedData(t *testing.T) {
+	c := newTestCommunity(t, prefer(1), prefer(2))
+	communityId := c.officer.Community.Id
+	if _, err := c.service.ToggleCommunityLock(c.ctx, c.officer, model.OptimizeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the snapshot in the session still says collecting, the stored status has the final word
+	member := &model.User{Battletag: memberTag(0), Community: model.UserCommunity{Id: communityId, Status: model.STATUS_COLLECTING}}
+	ctx := context.WithValue(c.ctx, middleware.CtxUser, member)
+	users := NewUserService(c.store, nil)
+	var statusErr *model.StatusError
+
+	if _, err := users.UpdateMappings(ctx, prefer(1)); err != nil {
+		t.Errorf("unchanged preferences were refused: %v", err)
+	}
+	if _, err := users.UpdateMappings(ctx, prefer(2)); !errors.As(err, &statusErr) {
+		t.Errorf("want a status error for changed preferences, got %v", err)
+	}
+	if err := users.SetNeighbors(ctx, nil); err != nil {
+		t.Errorf("unchanged neighbor wishes were refused: %v", err)
+	}
+	if err := users.SetNeighbors(ctx, []string{memberTag(1)}); !errors.As(err, &statusErr) {
+		t.Errorf("want a status error for changed neighbor wishes, got %v", err)
+	}
+}
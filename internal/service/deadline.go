@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const DEADLINE_CHECK_INTERVAL = time.Minute
+
+// StartDeadlineScheduler locks communities whose preference deadline passed until ctx is done.
+// The first check runs right away, so deadlines that passed while the server was down are not missed.
+func StartDeadlineScheduler(ctx context.Context, communities CommunityService, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := communities.LockOverdueCommunities(ctx); err != nil {
+				log.Printf("Failed to lock communities past their deadline: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
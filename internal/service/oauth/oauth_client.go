@@ -10,7 +10,9 @@ import (
 	"golang.org/x/oauth2"
 )
 
-type TokenExpiredError struct{}
+type TokenExpiredError struct {
+	Region model.Region
+}
 
 func (e *TokenExpiredError) Error() string {
 	return "token expired, user must re-authenticate"
@@ -29,16 +31,15 @@ func tokenFetcher(ctx context.Context) (*oauth2.Token, error) {
 	}, nil
 }
 
-func refreshToken() (*oauth2.Token, error) {
-	return nil, &TokenExpiredError{}
-}
-
-func GetClient(ctx context.Context) *http.Client {
+// GetClient calls Battle.net as the user in the context, refreshing an expired token if possible.
+func (p *Provider) GetClient(ctx context.Context, store TokenStore) *http.Client {
 	ts := &BattleNetTokenSource{
 		TokenFunc: func() (*oauth2.Token, error) {
 			return tokenFetcher(ctx)
 		},
-		RefreshFunc: refreshToken,
+		RefreshFunc: func() (*oauth2.Token, error) {
+			return p.refreshUserToken(ctx, store)
+		},
 	}
 
 	return oauth2.NewClient(ctx, ts)
@@ -0,0 +1,135 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sbraitsch/plotter/internal/middleware"
+	"github.com/sbraitsch/plotter/internal/model"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// TokenStore persists Battle.net tokens obtained by a refresh.
+type TokenStore interface {
+	UpdateUserToken(ctx context.Context, battletag string, token *oauth2.Token) error
+	ClearRefreshToken(ctx context.Context, battletag string) error
+}
+
+// Provider bundles the OAuth client of every region with the API endpoints.
+type Provider struct {
+	Endpoints model.BnetEndpoints
+	configs   map[model.Region]*oauth2.Config
+	apps      map[model.Region]oauth2.TokenSource
+}
+
+// NewProvider registers one client for every region, only the endpoints differ.
+func NewProvider(clientId, clientSecret, redirectURL string, endpoints model.BnetEndpoints) *Provider {
+	configs := make(map[model.Region]*oauth2.Config, len(model.REGIONS))
+	apps := make(map[model.Region]oauth2.TokenSource, len(model.REGIONS))
+	for _, region := range model.REGIONS {
+		configs[region] = &oauth2.Config{
+			ClientID:     clientId,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  endpoints.OAuthHost(region) + "/oauth/authorize",
+				TokenURL: endpoints.OAuthHost(region) + "/oauth/token",
+			},
+			Scopes: []string{"wow.profile"},
+		}
+		app := &clientcredentials.Config{
+			ClientID:     clientId,
+			ClientSecret: clientSecret,
+			TokenURL:     endpoints.OAuthHost(region) + "/oauth/token",
+		}
+		apps[region] = app.TokenSource(context.Background())
+	}
+	return &Provider{Endpoints: endpoints, configs: configs, apps: apps}
+}
+
+// AppClient calls Battle.net as the application itself, for game data no user has to grant.
+// The token is fetched once and reused until it expires.
+func (p *Provider) AppClient(ctx context.Context, region model.Region) *http.Client {
+	ts, exists := p.apps[region]
+	if !exists {
+		ts = p.apps[model.DEFAULT_REGION]
+	}
+	return oauth2.NewClient(ctx, ts)
+}
+
+func (p *Provider) Config(region model.Region) *oauth2.Config {
+	if cfg, exists := p.configs[region]; exists {
+		return cfg
+	}
+	return p.configs[model.DEFAULT_REGION]
+}
+
+// LoginURL is where a browser has to go to log in again. It lives next to the callback.
+func (p *Provider) LoginURL(region model.Region, returnTo string) string {
+	params := url.Values{"region": {string(region)}}
+	if returnTo != "" {
+		params.Set("returnTo", returnTo)
+	}
+	base := strings.TrimSuffix(p.Config(region).RedirectURL, "/callback")
+	return fmt.Sprintf("%s/login?%s", base, params.Encode())
+}
+
+// Refresh trades a refresh token for a new access token. Blizzard only hands out
+// refresh tokens for some grants, without one the user has to log in again.
+// A TokenExpiredError means Battle.net rejected the refresh token, any other error may be temporary.
+func (p *Provider) Refresh(ctx context.Context, region model.Region, refreshToken string) (*oauth2.Token, error) {
+	if refreshToken == "" {
+		return nil, &TokenExpiredError{Region: region}
+	}
+	token, err := p.Config(region).TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken}).Token()
+	var rejected *oauth2.RetrieveError
+	if errors.As(err, &rejected) {
+		log.Printf("Battle.net rejected refresh token: %v", err)
+		return nil, &TokenExpiredError{Region: region}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh Battle.net token: %w", err)
+	}
+	return token, nil
+}
+
+// RefreshUser refreshes and stores the token of a user. A rejected refresh token is
+// dropped, so the user shows up as having to log in again instead of being retried.
+func (p *Provider) RefreshUser(ctx context.Context, store TokenStore, user *model.User) (*oauth2.Token, error) {
+	token, err := p.Refresh(ctx, user.Region, user.RefreshToken)
+	var expired *TokenExpiredError
+	if errors.As(err, &expired) && user.RefreshToken != "" {
+		if err := store.ClearRefreshToken(ctx, user.Battletag); err != nil {
+			log.Printf("Failed to drop rejected refresh token of %s: %v", user.Battletag, err)
+		}
+		user.RefreshToken = ""
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := store.UpdateUserToken(ctx, user.Battletag, token); err != nil {
+		log.Printf("Failed to persist refreshed token of %s: %v", user.Battletag, err)
+	}
+	user.AccessToken, user.Expiry = token.AccessToken, token.Expiry
+	if token.RefreshToken != "" {
+		user.RefreshToken = token.RefreshToken
+	}
+	return token, nil
+}
+
+// refreshUserToken refreshes the token of the user in the context and writes it back.
+func (p *Provider) refreshUserToken(ctx context.Context, store TokenStore) (*oauth2.Token, error) {
+	user, ok := ctx.Value(middleware.CtxUser).(*model.User)
+	if !ok {
+		return nil, &TokenExpiredError{Region: model.DEFAULT_REGION}
+	}
+
+	return p.RefreshUser(ctx, store, user)
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+
+	"github.com/sbraitsch/plotter/internal/model"
+	"github.com/sbraitsch/plotter/internal/storage"
+)
+
+type PlotService interface {
+	GetPlots(ctx context.Context) ([]model.Plot, error)
+}
+
+type plotServiceImpl struct {
+	storage storage.Store
+}
+
+func NewPlotService(storage storage.Store) PlotService {
+	return &plotServiceImpl{storage: storage}
+}
+
+func (s *plotServiceImpl) GetPlots(ctx context.Context) ([]model.Plot, error) {
+	return s.storage.GetPlots(ctx)
+}
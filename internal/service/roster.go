@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sbraitsch/plotter/internal/model"
+	"github.com/sbraitsch/plotter/internal/service/oauth"
+	"github.com/sbraitsch/plotter/internal/storage"
+)
+
+const ROSTER_SYNC_INTERVAL = 6 * time.Hour
+
+type RosterService interface {
+	SyncCommunity(ctx context.Context, communityId string) (*model.RosterSyncReport, error)
+	SyncAll(ctx context.Context) ([]model.RosterSyncReport, error)
+}
+
+type rosterServiceImpl struct {
+	storage storage.Store
+	bnet    *oauth.Provider
+}
+
+func NewRosterService(storage storage.Store, bnet *oauth.Provider) RosterService {
+	return &rosterServiceImpl{storage: storage, bnet: bnet}
+}
+
+func (s *rosterServiceImpl) SyncCommunity(ctx context.Context, communityId string) (*model.RosterSyncReport, error) {
+	community, _, err := s.storage.GetCommunity(ctx, communityId)
+	if err != nil {
+		log.Printf("Error retrieving community to sync from database: %v", err)
+		return nil, err
+	}
+	return s.sync(ctx, community)
+}
+
+// SyncAll syncs every community with members. A failing guild is reported, not fatal.
+func (s *rosterServiceImpl) SyncAll(ctx context.Context) ([]model.RosterSyncReport, error) {
+	communities, err := s.storage.GetCommunities(ctx)
+	if err != nil {
+		log.Printf("Error retrieving communities to sync from database: %v", err)
+		return nil, err
+	}
+
+	reports := make([]model.RosterSyncReport, 0, len(communities))
+	for i := range communities {
+		report, err := s.sync(ctx, &communities[i])
+		if err != nil {
+			report = &model.RosterSyncReport{
+				CommunityId: communities[i].Id,
+				Name:        communities[i].Name,
+				Realm:       communities[i].Realm,
+				Region:      communities[i].Region,
+				SyncedAt:    time.Now(),
+				Changes:     []model.RosterChange{},
+				Error:       err.Error(),
+			}
+		}
+		reports = append(reports, *report)
+	}
+	return reports, nil
+}
+
+func (s *rosterServiceImpl) sync(ctx context.Context, community *model.Community) (*model.RosterSyncReport, error) {
+	client := s.bnet.AppClient(ctx, community.Region)
+	bnetService := NewBnetService(client, s.storage, s.bnet.Endpoints, community.Region)
+
+	roster, err := bnetService.GetGuildRoster(ctx, community)
+	if err != nil {
+		log.Printf("Failed to retrieve roster of %s-%s: %v", community.Name, community.Realm, err)
+		return nil, err
+	}
+	if len(roster.Members) == 0 {
+		return nil, fmt.Errorf("roster of %s-%s is empty", community.Name, community.Realm)
+	}
+
+	changes, err := s.storage.SyncRoster(ctx, community.Id, roster)
+	if err != nil {
+		log.Printf("Failed to apply roster of %s-%s: %v", community.Name, community.Realm, err)
+		return nil, err
+	}
+
+	members, err := s.storage.GetCommunitySize(ctx, community.Id)
+	if err != nil {
+		log.Printf("Failed to count members of %s-%s: %v", community.Name, community.Realm, err)
+	}
+
+	return &model.RosterSyncReport{
+		CommunityId: community.Id,
+		Name:        community.Name,
+		Realm:       community.Realm,
+		Region:      community.Region,
+		SyncedAt:    time.Now(),
+		Members:     members,
+		Changes:     changes,
+	}, nil
+}
+
+// StartRosterSyncer syncs all guild rosters in the background until ctx is done.
+func StartRosterSyncer(ctx context.Context, rosters RosterService, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			reports, err := rosters.SyncAll(ctx)
+			if err != nil {
+				continue
+			}
+			for _, report := range reports {
+				if report.Error != "" || len(report.Changes) > 0 {
+					log.Printf("Roster sync of %s-%s: %d changes %s", report.Name, report.Realm, len(report.Changes), report.Error)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+
+	"github.com/sbraitsch/plotter/internal/model"
+	"github.com/sbraitsch/plotter/internal/storage"
+)
+
+// statusActions is how the audit log names each step of the lifecycle.
+var statusActions = map[[2]model.CommunityStatus]model.AuditAction{
+	{model.STATUS_COLLECTING, model.STATUS_LOCKED}:  model.AUDIT_COMMUNITY_LOCKED,
+	{model.STATUS_LOCKED, model.STATUS_COLLECTING}:  model.AUDIT_COMMUNITY_UNLOCKED,
+	{model.STATUS_LOCKED, model.STATUS_FINALIZED}:   model.AUDIT_COMMUNITY_FINALIZED,
+	{model.STATUS_FINALIZED, model.STATUS_LOCKED}:   model.AUDIT_COMMUNITY_REOPENED,
+	{model.STATUS_FINALIZED, model.STATUS_ARCHIVED}: model.AUDIT_COMMUNITY_ARCHIVED,
+	{model.STATUS_ARCHIVED, model.STATUS_FINALIZED}: model.AUDIT_COMMUNITY_UNARCHIVED,
+}
+
+// requireStatus refuses an action the current status of the community doesn't allow.
+func requireStatus(
+	ctx context.Context,
+	store storage.Store,
+	communityId string,
+	action string,
+	allowed func(model.CommunityStatus) bool,
+) error {
+	status, err := store.GetCommunityStatus(ctx, communityId)
+	if err != nil {
+		return err
+	}
+	if !allowed(status) {
+		return &model.StatusError{Status: status, Action: action}
+	}
+	return nil
+}
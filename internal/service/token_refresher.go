@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/sbraitsch/plotter/internal/service/oauth"
+	"github.com/sbraitsch/plotter/internal/storage"
+)
+
+const (
+	TOKEN_REFRESH_INTERVAL = 15 * time.Minute
+	// tokens are renewed this long before they run out, so no request has to wait for a refresh
+	TOKEN_REFRESH_AHEAD = time.Hour
+)
+
+// StartTokenRefresher renews expiring Battle.net tokens in the background until ctx is done.
+func StartTokenRefresher(ctx context.Context, store storage.Store, bnet *oauth.Provider, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			refreshExpiringTokens(ctx, store, bnet)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func refreshExpiringTokens(ctx context.Context, store storage.Store, bnet *oauth.Provider) {
+	users, err := store.GetRefreshableUsers(ctx, time.Now().Add(TOKEN_REFRESH_AHEAD))
+	if err != nil {
+		log.Printf("Failed to list expiring Battle.net tokens: %v", err)
+		return
+	}
+
+	refreshed := 0
+	for i := range users {
+		if _, err := bnet.RefreshUser(ctx, store, &users[i]); err != nil {
+			log.Printf("Failed to refresh Battle.net token of %s: %v", users[i].Battletag, err)
+			continue
+		}
+		refreshed++
+	}
+	if len(users) > 0 {
+		log.Printf("Refreshed %d of %d expiring Battle.net tokens", refreshed, len(users))
+	}
+}
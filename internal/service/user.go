@@ -3,8 +3,12 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"slices"
+	"time"
 
 	"github.com/sbraitsch/plotter/internal/middleware"
 	"github.com/sbraitsch/plotter/internal/model"
@@ -16,22 +20,31 @@ import (
 type UserService interface {
 	GetUserByToken(ctx context.Context, token string) (*model.User, error)
 	Validate(ctx context.Context) (*model.ValidatedUser, error)
-	RegisterUser(code string, oauth *oauth2.Config) (string, error)
-	UpdateMappings(ctx context.Context, mappings map[int]int) (*model.CommunityData, error)
+	RegisterUser(code string, verifier string, userAgent string, region model.Region) (string, error)
+	GetSessions(ctx context.Context) ([]model.Session, error)
+	Logout(ctx context.Context) error
+	LogoutEverywhere(ctx context.Context) error
+	UpdateMappings(ctx context.Context, mappings map[model.PlotKey]int) (*model.CommunityData, error)
 	SetNote(ctx context.Context, note string) error
+	SetNeighbors(ctx context.Context, neighbors []string) error
+	SetCharacter(ctx context.Context, char string) (string, error)
 	ListAvailableCommunities(ctx context.Context) ([]model.Community, error)
 }
 
+// ErrPreferencesClosed is returned when preferences change after the community's deadline.
+var ErrPreferencesClosed = errors.New("The deadline for preferences has passed.")
+
 type userServiceImpl struct {
-	storage *storage.StorageClient
+	storage storage.Store
+	bnet    *oauth.Provider
 }
 
-func NewUserService(storage *storage.StorageClient) UserService {
-	return &userServiceImpl{storage: storage}
+func NewUserService(storage storage.Store, bnet *oauth.Provider) UserService {
+	return &userServiceImpl{storage: storage, bnet: bnet}
 }
 
 func (s *userServiceImpl) GetUserByToken(ctx context.Context, token string) (*model.User, error) {
-	user, err := s.storage.GetUserByToken(ctx, token)
+	user, err := s.storage.GetUserByToken(ctx, token, "")
 	if err != nil {
 		log.Printf("Failed to retrieve user from database: %v", err)
 		return nil, err
@@ -41,39 +54,62 @@ func (s *userServiceImpl) GetUserByToken(ctx context.Context, token string) (*mo
 }
 
 func (s *userServiceImpl) ListAvailableCommunities(ctx context.Context) ([]model.Community, error) {
-	client := oauth.GetClient(ctx)
-	bnetService := NewBnetService(client, s.storage)
+	client := s.bnet.GetClient(ctx, s.storage)
+	user := ctx.Value(middleware.CtxUser).(*model.User)
+	bnetService := NewBnetService(client, s.storage, s.bnet.Endpoints, user.Region)
 	return bnetService.GetUserGuilds(ctx)
 }
 
 func (s *userServiceImpl) Validate(ctx context.Context) (*model.ValidatedUser, error) {
 	user := ctx.Value(middleware.CtxUser).(*model.User)
+	characters, err := s.storage.GetCharacters(ctx, user.Battletag, user.Community.Id)
+	if err != nil {
+		log.Printf("Failed to get characters of %s: %v", user.Battletag, err)
+		return nil, err
+	}
+	memberships, err := s.storage.GetMemberships(ctx, user.Battletag)
+	if err != nil {
+		log.Printf("Failed to get memberships of %s: %v", user.Battletag, err)
+		return nil, err
+	}
+
 	return &model.ValidatedUser{
-		Battletag: user.Battletag,
-		Char:      user.Char,
-		Note:      user.Note,
-		IsAdmin:   user.CommunityRank <= user.Community.OfficerRank,
+		Battletag:   user.Battletag,
+		Char:        user.Char,
+		Note:        user.Note,
+		IsAdmin:     !user.Departed && user.CommunityRank <= user.Community.OfficerRank,
+		Departed:    user.Departed,
+		Characters:  characters,
+		Memberships: memberships,
+		Region:      user.Region,
+		Bnet: model.BnetTokenStatus{
+			Expiry:         user.Expiry,
+			Refreshable:    user.RefreshToken != "",
+			ReauthRequired: user.RefreshToken == "" && !time.Now().Before(user.Expiry),
+		},
 		Community: model.ValidatedCommunity{
-			Id:        user.Community.Id,
-			Name:      user.Community.Name,
-			Realm:     user.Community.Realm,
-			Locked:    user.Community.Locked,
-			Finalized: user.Community.Finalized,
+			Id:          user.Community.Id,
+			Name:        user.Community.Name,
+			Realm:       user.Community.Realm,
+			Region:      user.Community.Region,
+			Status:      user.Community.Status,
+			Preferences: model.NewPreferenceWindow(user.Community.PreferenceDeadline, time.Now()),
 		},
 	}, nil
 }
 
-func (s *userServiceImpl) RegisterUser(code string, oauth *oauth2.Config) (string, error) {
+func (s *userServiceImpl) RegisterUser(code string, verifier string, userAgent string, region model.Region) (string, error) {
 	ctx := context.Background()
+	cfg := s.bnet.Config(region)
 
-	token, err := oauth.Exchange(ctx, code)
+	token, err := cfg.Exchange(ctx, code, oauth2.VerifierOption(verifier))
 	if err != nil {
 		log.Printf("Failed token exchange: %v", err)
 		return "", err
 	}
 
-	client := oauth.Client(ctx, token)
-	resp, err := client.Get("https://oauth.battle.net/oauth/userinfo")
+	client := cfg.Client(ctx, token)
+	resp, err := client.Get(s.bnet.Endpoints.OAuthHost(region) + "/oauth/userinfo")
 	if err != nil {
 		log.Printf("Failed to fetch user profile: %v", err)
 		return "", err
@@ -89,22 +125,67 @@ func (s *userServiceImpl) RegisterUser(code string, oauth *oauth2.Config) (strin
 		return "", err
 	}
 
-	sessionToken, err := s.storage.RegisterUser(ctx, profile.Battletag, token)
+	err = s.storage.RegisterUser(ctx, profile.Battletag, region, token)
 	if err != nil {
 		log.Printf("Failed to register new user %v", err)
 		return "", err
 	}
+
+	// every login gets its own session, so other devices stay logged in
+	sessionToken, err := s.storage.CreateSession(ctx, profile.Battletag, userAgent)
+	if err != nil {
+		log.Printf("Failed to open session for %s: %v", profile.Battletag, err)
+		return "", err
+	}
 	return sessionToken, nil
 }
 
-func (s *userServiceImpl) UpdateMappings(ctx context.Context, mappings map[int]int) (*model.CommunityData, error) {
+func (s *userServiceImpl) GetSessions(ctx context.Context) ([]model.Session, error) {
+	user := ctx.Value(middleware.CtxUser).(*model.User)
+	sessions, err := s.storage.GetSessions(ctx, user.Battletag)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].Id == user.SessionId
+	}
+	return sessions, nil
+}
+
+func (s *userServiceImpl) Logout(ctx context.Context) error {
+	user := ctx.Value(middleware.CtxUser).(*model.User)
+	return s.storage.DeleteSession(ctx, user.Battletag, user.SessionId)
+}
+
+func (s *userServiceImpl) LogoutEverywhere(ctx context.Context) error {
+	user := ctx.Value(middleware.CtxUser).(*model.User)
+	return s.storage.DeleteSessions(ctx, user.Battletag)
+}
+
+func (s *userServiceImpl) UpdateMappings(ctx context.Context, mappings map[model.PlotKey]int) (*model.CommunityData, error) {
 
 	user, ok := ctx.Value(middleware.CtxUser).(*model.User)
 	if !ok || len(user.Community.Id) == 0 {
 		return nil, fmt.Errorf("community not found in context")
 	}
 
-	err := s.storage.SavePlotMappings(ctx, user, mappings)
+	previous, err := s.storage.GetCommunityData(ctx, user)
+	if err != nil {
+		log.Printf("Failed to retrieve community data from database: %v", err)
+		return nil, err
+	}
+
+	// the player form always sends the preferences, only actual changes are turned away once
+	// they closed. The status is checked by the storage in the transaction of the change.
+	if maps.Equal(plotDataOf(previous, user.Battletag), mappings) {
+		return previous, nil
+	}
+	if !model.PreferencesOpen(user.Community.PreferenceDeadline, time.Now()) {
+		return nil, ErrPreferencesClosed
+	}
+
+	event := auditEvent(ctx, model.AUDIT_PREFERENCES_CHANGED, user.Battletag, plotDataOf(previous, user.Battletag), mappings)
+	err = s.storage.SavePlotMappings(ctx, user, mappings, event)
 	if err != nil {
 		return nil, err
 	}
@@ -119,15 +200,78 @@ func (s *userServiceImpl) UpdateMappings(ctx context.Context, mappings map[int]i
 
 func (s *userServiceImpl) SetNote(ctx context.Context, note string) error {
 
+	user, ok := ctx.Value(middleware.CtxUser).(*model.User)
+	if !ok || len(user.Community.Id) == 0 {
+		return fmt.Errorf("community not found in context")
+	}
+	if !user.Community.Status.Active() {
+		return &model.StatusError{Status: user.Community.Status, Action: "change notes"}
+	}
+
+	event := auditEvent(ctx, model.AUDIT_NOTE_CHANGED, user.Battletag, user.Note, note)
+	return s.storage.SetNote(ctx, user, note, event)
+}
+
+func (s *userServiceImpl) SetNeighbors(ctx context.Context, neighbors []string) error {
+
 	user, ok := ctx.Value(middleware.CtxUser).(*model.User)
 	if !ok || len(user.Community.Id) == 0 {
 		return fmt.Errorf("community not found in context")
 	}
 
-	err := s.storage.SetNote(ctx, user, note)
+	if len(neighbors) > model.MAX_NEIGHBOR_WISHES {
+		return fmt.Errorf("at most %d neighbors can be requested", model.MAX_NEIGHBOR_WISHES)
+	}
+
+	// wishes close with the preferences and follow the same rule, see UpdateMappings
+	community, err := s.storage.GetCommunityData(ctx, user)
 	if err != nil {
+		log.Printf("Failed to retrieve community data from database: %v", err)
 		return err
 	}
+	wished := slices.Clone(neighbors)
+	slices.Sort(wished)
+	if slices.Equal(slices.Compact(wished), neighborsOf(community, user.Battletag)) {
+		return nil
+	}
+	if !model.PreferencesOpen(user.Community.PreferenceDeadline, time.Now()) {
+		return ErrPreferencesClosed
+	}
+
+	event := auditEvent(ctx, model.AUDIT_NEIGHBORS_CHANGED, user.Battletag, neighborsOf(community, user.Battletag), neighbors)
+	return s.storage.SaveNeighborWishes(ctx, user, neighbors, event)
+}
 
+func (s *userServiceImpl) SetCharacter(ctx context.Context, char string) (string, error) {
+	user, ok := ctx.Value(middleware.CtxUser).(*model.User)
+	if !ok || len(user.Community.Id) == 0 {
+		return "", fmt.Errorf("community not found in context")
+	}
+	if !user.Community.Status.Active() {
+		return "", &model.StatusError{Status: user.Community.Status, Action: "switch characters"}
+	}
+
+	// the name is matched case-insensitively, so the storage fills in the one it switched to
+	event := auditEvent(ctx, model.AUDIT_CHARACTER_CHANGED, user.Battletag, user.Char, nil)
+	return s.storage.SetCharacter(ctx, user, char, event)
+}
+
+// neighborsOf lists the stored wishes of a member, sorted.
+func neighborsOf(community *model.CommunityData, battletag string) []string {
+	for _, m := range community.Members {
+		if m.BattleTag == battletag {
+			return slices.Sorted(slices.Values(m.Neighbors))
+		}
+	}
+	return []string{}
+}
+
+// plotDataOf picks the preferences of one member out of the community.
+func plotDataOf(community *model.CommunityData, battletag string) map[model.PlotKey]int {
+	for _, m := range community.Members {
+		if m.BattleTag == battletag {
+			return m.PlotData
+		}
+	}
 	return nil
 }
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+
+	"github.com/sbraitsch/plotter/internal/model"
+)
+
+func (s *communityServiceImpl) GetVersion(ctx context.Context, communityId string) (int64, error) {
+	return s.storage.GetCommunityVersion(ctx, communityId)
+}
+
+func (s *communityServiceImpl) GetCommunityState(ctx context.Context, communityId string) (*model.CommunityState, error) {
+	// the version is read first, a change in between makes the state look older than it is, never newer
+	version, err := s.storage.GetCommunityVersion(ctx, communityId)
+	if err != nil {
+		return nil, err
+	}
+	status, err := s.storage.GetCommunityStatus(ctx, communityId)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := s.storage.GetCommunitySettings(ctx, communityId)
+	if err != nil {
+		return nil, err
+	}
+	assignments, err := s.storage.GetAssignments(ctx, communityId)
+	if err != nil {
+		return nil, err
+	}
+	return &model.CommunityState{Version: version, Status: status, Settings: settings, Assignments: assignments}, nil
+}
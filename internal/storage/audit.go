@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sbraitsch/plotter/internal/model"
+)
+
+// execer is satisfied by the pool and by transactions, so an event can be written
+// together with the change it records.
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+func recordAuditEvent(ctx context.Context, db execer, communityId string, event *model.AuditEvent) error {
+	_, err := db.Exec(ctx, `
+		INSERT INTO audit_events (community_id, actor, action, target, detail, before, after)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7)
+	`, communityId, event.Actor, event.Action, event.Target, event.Detail, []byte(event.Before), []byte(event.After))
+	return err
+}
+
+// GetAuditEvents returns the matching events of a community, newest first.
+func (s *StorageClient) GetAuditEvents(ctx context.Context, communityId string, filter *model.AuditFilter) ([]model.AuditEvent, error) {
+	var since, until *time.Time
+	if !filter.Since.IsZero() {
+		since = &filter.Since
+	}
+	if !filter.Until.IsZero() {
+		until = &filter.Until
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT id, actor, action, COALESCE(target, ''), COALESCE(detail, ''), before, after, created_at
+		FROM audit_events
+		WHERE community_id = $1
+			AND ($2 = '' OR actor = $2)
+			AND ($3 = '' OR target = $3)
+			AND ($4 = '' OR action = $4)
+			AND ($5::timestamptz IS NULL OR created_at >= $5)
+			AND ($6::timestamptz IS NULL OR created_at < $6)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $7
+	`, communityId, filter.Actor, filter.Target, string(filter.Action), since, until, filter.Limit)
+	if err != nil {
+		log.Printf("Audit query failed: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []model.AuditEvent{}
+
+	for rows.Next() {
+		var e model.AuditEvent
+		var before, after []byte
+		if err := rows.Scan(&e.Id, &e.Actor, &e.Action, &e.Target, &e.Detail, &before, &after, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Before, e.After = before, after
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error reading audit events from database: %v", err)
+		return nil, err
+	}
+
+	return events, nil
+}
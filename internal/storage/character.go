@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/sbraitsch/plotter/internal/model"
+)
+
+func (s *StorageClient) GetCharacters(ctx context.Context, battletag string, communityId string) ([]string, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT DISTINCT name FROM characters WHERE battletag = $1 AND community_id = $2 ORDER BY name`,
+		battletag, communityId,
+	)
+	if err != nil {
+		log.Printf("Character query failed: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	characters := []string{}
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		characters = append(characters, name)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return characters, nil
+}
+
+// SetCharacter switches the displayed character. Until the plots are handed out in game,
+// an existing assignment moves to the new character as well. The event is recorded with
+// the character switched to as its after state.
+func (s *StorageClient) SetCharacter(ctx context.Context, user *model.User, char string, event *model.AuditEvent) (string, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin character transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var name string
+	err = tx.QueryRow(ctx,
+		`SELECT name FROM characters WHERE battletag = $1 AND community_id = $2 AND LOWER(name) = LOWER($3)`,
+		user.Battletag, user.Community.Id, strings.TrimSpace(char),
+	).Scan(&name)
+	if err != nil {
+		return "", fmt.Errorf("%s is not one of your characters in this guild", char)
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE memberships SET char = $1 WHERE battletag = $2 AND community_id = $3`,
+		name, user.Battletag, user.Community.Id,
+	)
+	if err != nil {
+		log.Printf("Failed to switch character of %s: %v", user.Battletag, err)
+		return "", err
+	}
+
+	moved, err := tx.Exec(ctx, `
+		UPDATE assignments a
+		SET char = $1
+		FROM communities c
+		WHERE a.battletag = $2 AND a.community_id = $3 AND c.id = a.community_id AND c.status IN ($4, $5)
+	`, name, user.Battletag, user.Community.Id, model.STATUS_COLLECTING, model.STATUS_LOCKED)
+	if err != nil {
+		log.Printf("Failed to move assignment of %s to %s: %v", user.Battletag, name, err)
+		return "", err
+	}
+	if moved.RowsAffected() > 0 {
+		if err := claimVersion(ctx, tx, user.Community.Id); err != nil {
+			return "", err
+		}
+	}
+
+	event.After = model.AuditState(name)
+	if err := recordAuditEvent(ctx, tx, user.Community.Id, event); err != nil {
+		log.Printf("Failed to record character switch of %s: %v", user.Battletag, err)
+		return "", err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit character transaction: %v", err)
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return name, nil
+}
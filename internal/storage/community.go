@@ -1,38 +1,26 @@
 package storage
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
-	"math"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/sbraitsch/plotter/internal/model"
 )
 
-func (s *StorageClient) FinalizeCommunity(ctx context.Context, communityId string) error {
-	_, err := s.db.Exec(ctx,
-		`UPDATE communities
-				 SET finalized = NOT finalized
-				 WHERE id = $1`,
-		communityId,
-	)
-
-	if err != nil {
-		log.Printf("Failed to update community values for community %s:%v", communityId, err)
-		return fmt.Errorf("Information could not be persisted.")
-	}
-	return nil
-}
-
 func (s *StorageClient) GetCommunityData(ctx context.Context, user *model.User) (*model.CommunityData, error) {
 	rows, err := s.db.Query(ctx, `
-        SELECT u.battletag, u.char, pm.plot_id, pm.priority
-        FROM users u
-        LEFT JOIN plot_mappings pm ON pm.battletag = u.battletag
-			WHERE u.community_id = $1
-        ORDER BY u.battletag, pm.plot_id
+        SELECT m.battletag, m.char, m.community_rank, pm.neighborhood, pm.plot_id, pm.priority
+        FROM memberships m
+        LEFT JOIN plot_mappings pm ON pm.battletag = m.battletag AND pm.community_id = m.community_id
+			WHERE m.community_id = $1 AND m.departed_at IS NULL
+        ORDER BY m.battletag, pm.neighborhood, pm.plot_id
     `, user.Community.Id)
 
 	if err != nil {
@@ -45,8 +33,9 @@ func (s *StorageClient) GetCommunityData(ctx context.Context, user *model.User)
 
 	for rows.Next() {
 		var btag, char string
-		var fromNum, toNum *int
-		if err := rows.Scan(&btag, &char, &fromNum, &toNum); err != nil {
+		var rank sql.NullInt32
+		var neighborhood, fromNum, toNum *int
+		if err := rows.Scan(&btag, &char, &rank, &neighborhood, &fromNum, &toNum); err != nil {
 			return nil, err
 		}
 
@@ -54,42 +43,89 @@ func (s *StorageClient) GetCommunityData(ctx context.Context, user *model.User)
 			playerMap[btag] = &model.MemberData{
 				BattleTag: btag,
 				Character: char,
-				PlotData:  make(map[int]int),
+				Rank:      int(rank.Int32),
+				PlotData:  make(map[model.PlotKey]int),
 			}
 		}
 
-		if fromNum != nil && toNum != nil {
-			playerMap[btag].PlotData[*fromNum] = *toNum
+		if neighborhood != nil && fromNum != nil && toNum != nil {
+			playerMap[btag].PlotData[model.PlotKey{Neighborhood: *neighborhood, Plot: *fromNum}] = *toNum
 		}
 	}
 
 	if rows.Err() != nil {
 		return nil, rows.Err()
 	}
+	wishes, err := s.getNeighborWishes(ctx, user.Community.Id)
+	if err != nil {
+		return nil, err
+	}
+	for btag, neighbors := range wishes {
+		if member, exists := playerMap[btag]; exists {
+			member.Neighbors = neighbors
+		}
+	}
+
+	var neighborWeight, neighborhoods, movePenalty int
+	var objective model.Objective
+	var weighting model.RankWeighting
+	err = s.db.QueryRow(ctx, `
+		SELECT neighbor_weight, neighborhoods, move_penalty, objective, rank_weighting
+		FROM communities
+		WHERE id = $1
+	`, user.Community.Id).Scan(&neighborWeight, &neighborhoods, &movePenalty, &objective, &weighting)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get optimizer settings: %w", err)
+	}
+
 	members := make([]model.MemberData, 0, len(playerMap))
 	for _, pd := range playerMap {
 		members = append(members, *pd)
 	}
 
-	community := &model.CommunityData{Id: user.Community.Id, Members: members}
+	plots, err := s.GetPlots(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	constraints, err := s.GetPlotConstraints(ctx, user.Community.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	community := &model.CommunityData{
+		Id:             user.Community.Id,
+		Members:        members,
+		NeighborWeight: neighborWeight,
+		Neighborhoods:  neighborhoods,
+		MovePenalty:    movePenalty,
+		Objective:      objective,
+		RankWeighting:  weighting,
+		Plots:          plots,
+		Pins:           constraints.Pins,
+		Reserved:       constraints.Reserved,
+	}
 	return community, nil
 }
 
 func (s *StorageClient) GetFullCommunityData(ctx context.Context, user *model.User) (*model.FullCommunityData, error) {
 	rows, err := s.db.Query(ctx, `
 		SELECT
-			u.battletag,
-			u.char,
-			COALESCE(u.note, '') AS note,
+			m.battletag,
+			m.char,
+			COALESCE(m.note, '') AS note,
+			COALESCE(m.community_rank, 0) AS community_rank,
+			a.neighborhood,
 			a.plot_id,
 			a.plot_score,
+			pm.neighborhood AS mapping_neighborhood,
 			pm.plot_id AS mapping_plot_id,
 			pm.priority
-		FROM users u
-		LEFT JOIN assignments a ON a.battletag = u.battletag
-		LEFT JOIN plot_mappings pm ON pm.battletag = u.battletag
-		WHERE u.community_id = $1
-		ORDER BY u.battletag, pm.plot_id
+		FROM memberships m
+		LEFT JOIN assignments a ON a.battletag = m.battletag AND a.community_id = m.community_id
+		LEFT JOIN plot_mappings pm ON pm.battletag = m.battletag AND pm.community_id = m.community_id
+		WHERE m.community_id = $1 AND m.departed_at IS NULL
+		ORDER BY m.battletag, pm.neighborhood, pm.plot_id
 	`, user.Community.Id)
 	if err != nil {
 		return nil, err
@@ -100,12 +136,17 @@ func (s *StorageClient) GetFullCommunityData(ctx context.Context, user *model.Us
 
 	for rows.Next() {
 		var (
-			btag, char, note          string
-			assignPlotID, assignScore sql.NullInt32
-			mappingPlotID, priority   sql.NullInt32
+			btag, char, note                              string
+			rank                                          int
+			assignNeighborhood, assignPlotID, assignScore sql.NullInt32
+			mappingNeighborhood, mappingPlotID, priority  sql.NullInt32
 		)
 
-		if err := rows.Scan(&btag, &char, &note, &assignPlotID, &assignScore, &mappingPlotID, &priority); err != nil {
+		if err := rows.Scan(
+			&btag, &char, &note, &rank,
+			&assignNeighborhood, &assignPlotID, &assignScore,
+			&mappingNeighborhood, &mappingPlotID, &priority,
+		); err != nil {
 			return nil, err
 		}
 
@@ -117,10 +158,14 @@ func (s *StorageClient) GetFullCommunityData(ctx context.Context, user *model.Us
 					Character: char,
 				},
 				Note:     note,
-				PlotData: make(map[int]int),
+				Rank:     rank,
+				PlotData: make(map[model.PlotKey]int),
 			}
 
 			// Fill assignment if available
+			if assignNeighborhood.Valid {
+				member.Assignment.Neighborhood = int(assignNeighborhood.Int32)
+			}
 			if assignPlotID.Valid {
 				member.Assignment.Plot = int(assignPlotID.Int32)
 			}
@@ -131,8 +176,9 @@ func (s *StorageClient) GetFullCommunityData(ctx context.Context, user *model.Us
 			memberMap[btag] = member
 		}
 
-		if mappingPlotID.Valid && priority.Valid {
-			member.PlotData[int(mappingPlotID.Int32)] = int(priority.Int32)
+		if mappingNeighborhood.Valid && mappingPlotID.Valid && priority.Valid {
+			key := model.PlotKey{Neighborhood: int(mappingNeighborhood.Int32), Plot: int(mappingPlotID.Int32)}
+			member.PlotData[key] = int(priority.Int32)
 		}
 	}
 
@@ -140,8 +186,14 @@ func (s *StorageClient) GetFullCommunityData(ctx context.Context, user *model.Us
 		return nil, err
 	}
 
+	wishes, err := s.getNeighborWishes(ctx, user.Community.Id)
+	if err != nil {
+		return nil, err
+	}
+
 	members := make([]model.FullMemberData, 0, len(memberMap))
 	for _, m := range memberMap {
+		m.Neighbors = wishes[m.Assignment.Battletag]
 		members = append(members, *m)
 	}
 
@@ -154,8 +206,8 @@ func (s *StorageClient) GetFullCommunityData(ctx context.Context, user *model.Us
 func (s *StorageClient) GetCommunity(ctx context.Context, communityId string) (*model.Community, int, error) {
 	var community model.Community
 	requiredRank := 0
-	err := s.db.QueryRow(ctx, `SELECT id, name, realm, member_rank FROM communities WHERE id = $1`, communityId).
-		Scan(&community.Id, &community.Name, &community.Realm, &requiredRank)
+	err := s.db.QueryRow(ctx, `SELECT id, name, realm, region, member_rank, neighborhoods FROM communities WHERE id = $1`, communityId).
+		Scan(&community.Id, &community.Name, &community.Realm, &community.Region, &requiredRank, &community.Neighborhoods)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get community info: %w", err)
 	}
@@ -167,8 +219,8 @@ func (s *StorageClient) GetCommunitySize(ctx context.Context, communityId string
 
 	err := s.db.QueryRow(ctx, `
         SELECT COUNT(*)
-        FROM users
-        WHERE community_id = $1
+        FROM memberships
+        WHERE community_id = $1 AND departed_at IS NULL
     `, communityId).Scan(&count)
 
 	if err != nil {
@@ -177,6 +229,36 @@ func (s *StorageClient) GetCommunitySize(ctx context.Context, communityId string
 	return count, nil
 }
 
+// GetCommunities lists every community that has members, which are the ones worth a roster sync.
+func (s *StorageClient) GetCommunities(ctx context.Context) ([]model.Community, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT c.id, c.name, c.realm, c.region, c.status, c.neighborhoods
+		FROM communities c
+		WHERE EXISTS (SELECT 1 FROM memberships m WHERE m.community_id = c.id)
+		ORDER BY c.region, c.realm, c.name
+	`)
+	if err != nil {
+		log.Printf("Community query failed: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	communities := []model.Community{}
+
+	for rows.Next() {
+		var c model.Community
+		if err := rows.Scan(&c.Id, &c.Name, &c.Realm, &c.Region, &c.Status, &c.Neighborhoods); err != nil {
+			return nil, err
+		}
+		communities = append(communities, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return communities, nil
+}
+
 func (s *StorageClient) JoinCommunity(
 	ctx context.Context,
 	user *model.User,
@@ -184,60 +266,258 @@ func (s *StorageClient) JoinCommunity(
 	communityId string,
 	profile *model.WowProfile,
 	roster *model.Roster,
-) (string, error) {
-	minRank := math.MaxInt
-	var charName string
+	event *model.AuditEvent,
+) (*model.JoinedCommunity, error) {
+	characters := guildCharacters(profile, roster)
+	eligible := len(characters) > 0 && requiredRank >= characters[0].Rank
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin join transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var blockedUntil time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT expires_at FROM membership_tombstones
+		WHERE battletag = $1 AND community_id = $2 AND expires_at > NOW()
+	`, user.Battletag, communityId).Scan(&blockedUntil)
+	if err == nil {
+		return nil, fmt.Errorf("You were removed from this community and can join again after %s.", blockedUntil.Format(time.DateOnly))
+	}
+	if !errors.Is(err, ErrNotFound) {
+		log.Printf("Failed to check tombstone of %s: %v", user.Battletag, err)
+		return nil, fmt.Errorf("Information could not be persisted.")
+	}
+
+	// an approved request lets in who the rank requirement would turn away
+	var approvedBy *string
+	if !eligible {
+		err = tx.QueryRow(ctx, `
+			SELECT decided_by FROM join_requests
+			WHERE battletag = $1 AND community_id = $2 AND status = $3
+		`, user.Battletag, communityId, model.JOIN_REQUEST_APPROVED).Scan(&approvedBy)
+		if errors.Is(err, ErrNotFound) {
+			log.Printf("Failed to join community. Rank requirement not fulfilled.")
+			return nil, ErrRankRequirement
+		}
+		if err != nil {
+			log.Printf("Failed to check join request of %s: %v", user.Battletag, err)
+			return nil, fmt.Errorf("Information could not be persisted.")
+		}
+		if len(characters) == 0 {
+			characters = profileCharacters(profile, requiredRank)
+		}
+		if len(characters) == 0 {
+			return nil, ErrRankRequirement
+		}
+	}
+
+	// the account's standing is its best character, the displayed one can be switched later
+	_, err = tx.Exec(ctx,
+		`INSERT INTO memberships (battletag, community_id, char, community_rank, approved_by)
+			 VALUES ($4, $2, $1, $3, $5)
+			 ON CONFLICT (battletag, community_id) DO UPDATE
+			 SET char = $1, community_rank = $3, departed_at = NULL, manual = false,
+			     approved_by = COALESCE(EXCLUDED.approved_by, memberships.approved_by)`,
+		characters[0].Name, communityId, characters[0].Rank, user.Battletag, approvedBy,
+	)
+	if err != nil {
+		log.Printf("Failed to update community values for user %v:%v", user, err)
+		return nil, fmt.Errorf("Information could not be persisted.")
+	}
+
+	names := make([]string, 0, len(characters))
+	realms := make([]string, 0, len(characters))
+	for _, c := range characters {
+		names = append(names, c.Name)
+		realms = append(realms, c.Realm)
+	}
+	_, err = tx.Exec(ctx, `DELETE FROM characters WHERE battletag = $1 AND community_id = $2`, user.Battletag, communityId)
+	if err == nil {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO characters (battletag, community_id, name, realm)
+			SELECT $1, $2, name, realm FROM unnest($3::text[], $4::text[]) AS c(name, realm)
+		`, user.Battletag, communityId, names, realms)
+	}
+	if err != nil {
+		log.Printf("Failed to store characters of %s: %v", user.Battletag, err)
+		return nil, fmt.Errorf("Information could not be persisted.")
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM join_requests WHERE battletag = $1 AND community_id = $2`, user.Battletag, communityId)
+	if err != nil {
+		log.Printf("Failed to close join request of %s: %v", user.Battletag, err)
+		return nil, fmt.Errorf("Information could not be persisted.")
+	}
+
+	joined := &model.JoinedCommunity{Char: characters[0].Name, Characters: characters}
+	event.After = model.AuditState(joined)
+	if err := recordAuditEvent(ctx, tx, communityId, event); err != nil {
+		log.Printf("Failed to record join of %s: %v", user.Battletag, err)
+		return nil, fmt.Errorf("Information could not be persisted.")
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit join transaction: %v", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return joined, nil
+}
+
+// guildCharacters lists the account's characters on the roster, best (lowest) guild rank first.
+func guildCharacters(profile *model.WowProfile, roster *model.Roster) []model.Character {
+	characters := []model.Character{}
 	for _, acc := range profile.WowAccounts {
 		for _, char := range acc.Characters {
 			for _, member := range roster.Members {
-				if strings.EqualFold(char.Name, member.Character.Name) {
-					if member.Rank < minRank {
-						minRank = member.Rank
-						charName = member.Character.Name
-					}
+				name, realm := member.Character.Name, member.Character.Realm.Slug
+				if strings.EqualFold(char.Name, name) && strings.EqualFold(char.Realm.Slug, realm) &&
+					!slices.ContainsFunc(characters, func(c model.Character) bool { return c.Name == name && c.Realm == realm }) {
+					characters = append(characters, model.Character{Name: name, Realm: realm, Rank: member.Rank})
 				}
 			}
 		}
 	}
+	slices.SortStableFunc(characters, func(a, b model.Character) int {
+		return cmp.Or(cmp.Compare(a.Rank, b.Rank), cmp.Compare(a.Name, b.Name), cmp.Compare(a.Realm, b.Realm))
+	})
+	return characters
+}
 
-	if requiredRank < minRank {
-		log.Printf("Failed to join community. Rank requirement not fulfilled.")
-		return "", fmt.Errorf("Rank requirement not fulfilled.")
+// profileCharacters stands in for the roster when an approved guest has no character in the guild.
+func profileCharacters(profile *model.WowProfile, rank int) []model.Character {
+	characters := []model.Character{}
+	for _, acc := range profile.WowAccounts {
+		for _, char := range acc.Characters {
+			if !slices.ContainsFunc(characters, func(c model.Character) bool { return c.Name == char.Name && c.Realm == char.Realm.Slug }) {
+				characters = append(characters, model.Character{Name: char.Name, Realm: char.Realm.Slug, Rank: rank})
+			}
+		}
 	}
+	slices.SortFunc(characters, func(a, b model.Character) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Realm, b.Realm))
+	})
+	return characters
+}
 
-	_, err := s.db.Exec(ctx,
-		`UPDATE users
-			 SET char = $1, community_id = $2, community_rank = $3
-			 WHERE battletag = $4`,
-		charName, communityId, minRank, user.Battletag,
-	)
+// SyncRoster brings the stored ranks of a community in line with the guild roster.
+// Members missing from the roster are flagged as departed instead of being removed,
+// so their preferences survive a short absence from the guild.
+func (s *StorageClient) SyncRoster(ctx context.Context, communityId string, roster *model.Roster) ([]model.RosterChange, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin roster transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
 
+	// the aggregate below can't lock, so hold the memberships first to keep joins and
+	// other syncs from changing them between the diff and its updates
+	_, err = tx.Exec(ctx, `SELECT 1 FROM memberships WHERE community_id = $1 FOR UPDATE`, communityId)
 	if err != nil {
-		log.Printf("Failed to update community values for user %v:%v", user, err)
-		return "", fmt.Errorf("Information could not be persisted.")
+		return nil, fmt.Errorf("failed to lock community members: %w", err)
 	}
 
-	return charName, nil
-}
+	rows, err := tx.Query(ctx, `
+		SELECT
+			m.battletag,
+			COALESCE(m.char, ''),
+			COALESCE(m.community_rank, 100),
+			m.departed_at IS NOT NULL,
+			m.approved_by IS NOT NULL,
+			m.manual,
+			COALESCE(array_agg(ch.name ORDER BY ch.name, ch.realm) FILTER (WHERE ch.name IS NOT NULL), '{}'),
+			COALESCE(array_agg(ch.realm ORDER BY ch.name, ch.realm) FILTER (WHERE ch.name IS NOT NULL), '{}')
+		FROM memberships m
+		LEFT JOIN characters ch ON ch.battletag = m.battletag AND ch.community_id = m.community_id
+		WHERE m.community_id = $1
+		GROUP BY m.battletag, m.community_id
+		ORDER BY m.battletag
+	`, communityId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read community members: %w", err)
+	}
 
-func (s *StorageClient) UnlockCommunity(ctx context.Context, communityId string) error {
-	_, err := s.db.Exec(ctx,
-		`UPDATE communities
-		SET locked = false
-		WHERE id = $1`,
-		communityId,
-	)
-	return err
+	members := []model.RosterMember{}
+	for rows.Next() {
+		var m model.RosterMember
+		var names, realms []string
+		if err := rows.Scan(&m.Battletag, &m.Character, &m.Rank, &m.Departed, &m.Guest, &m.Manual, &names, &realms); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		for i, name := range names {
+			m.Characters = append(m.Characters, model.Character{Name: name, Realm: realms[i]})
+		}
+		members = append(members, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	changes := roster.Diff(members)
+	for _, change := range changes {
+		switch change.Kind {
+		case model.ROSTER_DEPARTED:
+			_, err = tx.Exec(ctx,
+				`UPDATE memberships SET departed_at = NOW() WHERE battletag = $1 AND community_id = $2`,
+				change.Battletag, communityId,
+			)
+		default:
+			_, err = tx.Exec(ctx,
+				`UPDATE memberships SET community_rank = $3, departed_at = NULL WHERE battletag = $1 AND community_id = $2`,
+				change.Battletag, communityId, change.NewRank,
+			)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update member %s: %w", change.Battletag, err)
+		}
+	}
+	// departed members lose their claim on a plot, so officers have to see the new roster first
+	if len(changes) > 0 {
+		if err := claimVersion(ctx, tx, communityId); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit roster transaction: %v", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return changes, nil
 }
 
-func (s *StorageClient) PersistAndLock(ctx context.Context, assignments []model.Assignment, communityId string) error {
+// PersistAndLock replaces the assignments of a community and locks it, if its status allows it.
+// Assigned members that aren't known yet are registered as manual members in the same transaction.
+func (s *StorageClient) PersistAndLock(
+	ctx context.Context,
+	assignments []model.Assignment,
+	communityId string,
+	allowed func(model.CommunityStatus) bool,
+	origin *model.RevisionOrigin,
+	event *model.AuditEvent,
+) error {
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin lock transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = s.db.Exec(ctx,
+	status, err := lockCommunityStatus(ctx, tx, communityId)
+	if err != nil {
+		return err
+	}
+	if !allowed(status) {
+		return &model.StatusError{Status: status, Action: "change assignments"}
+	}
+	if err := registerManualUsers(ctx, tx, assignments, communityId); err != nil {
+		log.Printf("Failed to register assigned members of community %s: %v", communityId, err)
+		return err
+	}
+
+	_, err = tx.Exec(ctx,
 		`DELETE FROM assignments WHERE community_id=$1`,
 		communityId,
 	)
@@ -245,32 +525,57 @@ func (s *StorageClient) PersistAndLock(ctx context.Context, assignments []model.
 		return fmt.Errorf("failed to clean up assignments before update: %w", err)
 	}
 
-	sqlStr := `INSERT INTO assignments (battletag, char, community_id, plot_id, plot_score) VALUES `
+	sqlStr := `INSERT INTO assignments (battletag, char, community_id, neighborhood, plot_id, plot_score) VALUES `
 	args := []any{}
 
 	for i, a := range assignments {
-		idx := i * 5
-		sqlStr += fmt.Sprintf("($%d, $%d, $%d, $%d, $%d),", idx+1, idx+2, idx+3, idx+4, idx+5)
-		args = append(args, a.Battletag, a.Character, communityId, a.Plot, a.Score)
+		idx := i * 6
+		sqlStr += fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d),", idx+1, idx+2, idx+3, idx+4, idx+5, idx+6)
+		args = append(args, a.Battletag, a.Character, communityId, a.Neighborhood, a.Plot, a.Score)
 	}
 
 	sqlStr = strings.TrimSuffix(sqlStr, ",")
-	sqlStr += ` ON CONFLICT (battletag)
+	sqlStr += ` ON CONFLICT (community_id, battletag)
 		        DO UPDATE SET
+                  char = EXCLUDED.char,
+                  neighborhood = EXCLUDED.neighborhood,
                   plot_id = EXCLUDED.plot_id,
                   plot_score = EXCLUDED.plot_score`
 
-	_, err = tx.Exec(ctx, sqlStr, args...)
-	if err != nil {
-		return err
+	if len(assignments) > 0 {
+		_, err = tx.Exec(ctx, sqlStr, args...)
+		if err != nil {
+			return err
+		}
 	}
 
 	_, err = tx.Exec(ctx,
 		`UPDATE communities
-		SET locked = true
+		SET status = $2
 		WHERE id = $1`,
-		communityId,
+		communityId, model.STATUS_LOCKED,
 	)
+	if err != nil {
+		return err
+	}
+	if err := snapshotAssignments(ctx, tx, communityId, origin); err != nil {
+		log.Printf("Failed to snapshot assignments of community %s: %v", communityId, err)
+		return err
+	}
+	if err := claimVersion(ctx, tx, communityId); err != nil {
+		return err
+	}
+	neighborhoods := 1
+	for _, a := range assignments {
+		neighborhoods = max(neighborhoods, a.Neighborhood)
+	}
+	if err := ensureNeighborhoods(ctx, tx, communityId, neighborhoods); err != nil {
+		return err
+	}
+	if err := recordAuditEvent(ctx, tx, communityId, event); err != nil {
+		log.Printf("Failed to record %s in community %s: %v", event.Action, communityId, err)
+		return err
+	}
 	if err = tx.Commit(ctx); err != nil {
 		log.Printf("failed to commit lock transaction: %v", err)
 		return fmt.Errorf("failed to commit transaction: %w", err)
@@ -280,9 +585,10 @@ func (s *StorageClient) PersistAndLock(ctx context.Context, assignments []model.
 
 func (s *StorageClient) GetAssignments(ctx context.Context, communityId string) ([]model.Assignment, error) {
 	rows, err := s.db.Query(ctx, `
-		SELECT battletag, char, plot_id, plot_score
+		SELECT battletag, char, neighborhood, plot_id, plot_score
 		FROM assignments as a
 		WHERE community_id = $1
+		ORDER BY neighborhood, plot_id
 	`, communityId)
 	if err != nil {
 		log.Printf("Assignment query failed: %v", err)
@@ -294,7 +600,7 @@ func (s *StorageClient) GetAssignments(ctx context.Context, communityId string)
 
 	for rows.Next() {
 		var a model.Assignment
-		if err := rows.Scan(&a.Battletag, &a.Character, &a.Plot, &a.Score); err != nil {
+		if err := rows.Scan(&a.Battletag, &a.Character, &a.Neighborhood, &a.Plot, &a.Score); err != nil {
 			return nil, err
 		}
 		assignments = append(assignments, a)
@@ -308,18 +614,33 @@ func (s *StorageClient) GetAssignments(ctx context.Context, communityId string)
 	return assignments, nil
 }
 
-func (s *StorageClient) SetAssignment(ctx context.Context, req *model.SingleAssignmentRequest, communityId string) error {
+func (s *StorageClient) SetAssignment(
+	ctx context.Context,
+	req *model.SingleAssignmentRequest,
+	communityId string,
+	origin *model.RevisionOrigin,
+	event *model.AuditEvent,
+) error {
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin assignment transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	err = s.RegisterManualUsers(ctx, []model.Assignment{model.Assignment{
-		Character: req.Char,
-		Battletag: req.Battletag,
-		Plot:      req.PlotId,
-		Score:     0,
+	status, err := lockCommunityStatus(ctx, tx, communityId)
+	if err != nil {
+		return err
+	}
+	if !status.AssignmentsOpen() {
+		return &model.StatusError{Status: status, Action: "change assignments"}
+	}
+
+	err = registerManualUsers(ctx, tx, []model.Assignment{model.Assignment{
+		Character:    req.Char,
+		Battletag:    req.Battletag,
+		Neighborhood: req.Neighborhood,
+		Plot:         req.PlotId,
+		Score:        0,
 	}}, communityId)
 
 	if err != nil {
@@ -327,45 +648,63 @@ func (s *StorageClient) SetAssignment(ctx context.Context, req *model.SingleAssi
 		return err
 	}
 
-	_, err = s.db.Exec(ctx, `
+	_, err = tx.Exec(ctx, `
 			DELETE FROM assignments
-			WHERE plot_id = $1 AND community_id = $2
-		`, req.PlotId, communityId)
+			WHERE neighborhood = $1 AND plot_id = $2 AND community_id = $3
+		`, req.Neighborhood, req.PlotId, communityId)
 	if err != nil {
 		log.Printf("Failed to remove plot assignment for %s: %v", req.Battletag, err)
 		return err
 	}
 
-	_, err = s.db.Exec(ctx, `
-		INSERT INTO assignments (battletag, plot_id, char, community_id, plot_score)
-		VALUES ($1, $2, $3, $4, 0)
-		ON CONFLICT (battletag)
+	_, err = tx.Exec(ctx, `
+		INSERT INTO assignments (battletag, neighborhood, plot_id, char, community_id, plot_score)
+		VALUES ($1, $2, $3, $4, $5, 0)
+		ON CONFLICT (community_id, battletag)
 		DO UPDATE SET
+			neighborhood = EXCLUDED.neighborhood,
 			plot_id = EXCLUDED.plot_id,
 			char = EXCLUDED.char,
 			plot_score = 0
-`, req.Battletag, req.PlotId, req.Char, communityId)
+`, req.Battletag, req.Neighborhood, req.PlotId, req.Char, communityId)
 	if err != nil {
 		log.Printf("Failed to update plot assignment for %s to %d: %v", req.Battletag, req.PlotId, err)
 		return err
 	}
 
+	if err := snapshotAssignments(ctx, tx, communityId, origin); err != nil {
+		log.Printf("Failed to snapshot assignments of community %s: %v", communityId, err)
+		return err
+	}
+	if err := claimVersion(ctx, tx, communityId); err != nil {
+		return err
+	}
+	if err := ensureNeighborhoods(ctx, tx, communityId, req.Neighborhood); err != nil {
+		return err
+	}
+	if err := recordAuditEvent(ctx, tx, communityId, event); err != nil {
+		log.Printf("Failed to record assignment of %s: %v", req.Battletag, err)
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit assignment transaction: %w", err)
+	}
 	return nil
 }
 
 func (s *StorageClient) InsertGuilds(ctx context.Context, guilds []model.Community) ([]model.Community, error) {
 	tx, err := s.db.Begin(ctx)
-	sqlStr := `INSERT INTO communities (name, realm) VALUES `
+	sqlStr := `INSERT INTO communities (name, realm, region) VALUES `
 	args := []any{}
 
 	for i, g := range guilds {
-		idx := i * 2
-		sqlStr += fmt.Sprintf("($%d, $%d),", idx+1, idx+2)
-		args = append(args, g.Name, g.Realm)
+		idx := i * 3
+		sqlStr += fmt.Sprintf("($%d, $%d, $%d),", idx+1, idx+2, idx+3)
+		args = append(args, g.Name, g.Realm, g.Region)
 	}
 
 	sqlStr = strings.TrimSuffix(sqlStr, ",")
-	sqlStr += " ON CONFLICT (name) DO NOTHING"
+	sqlStr += " ON CONFLICT (region, realm, name) DO NOTHING"
 
 	_, err = tx.Exec(ctx, sqlStr, args...)
 	if err != nil {
@@ -373,15 +712,20 @@ func (s *StorageClient) InsertGuilds(ctx context.Context, guilds []model.Communi
 	}
 
 	names := make([]string, len(guilds))
+	realms := make([]string, len(guilds))
+	regions := make([]string, len(guilds))
 	for i, g := range guilds {
 		names[i] = g.Name
+		realms[i] = g.Realm
+		regions[i] = string(g.Region)
 	}
 
 	rows, err := tx.Query(ctx,
-		`SELECT id, name, realm, locked
-		     FROM communities
-			 WHERE name = ANY($1)`,
-		names,
+		`SELECT c.id, c.name, c.realm, c.region, c.status
+		     FROM communities c
+			 JOIN unnest($1::text[], $2::text[], $3::text[]) AS g(name, realm, region)
+			   ON c.name = g.name AND c.realm = g.realm AND c.region = g.region`,
+		names, realms, regions,
 	)
 	if err != nil {
 		return nil, err
@@ -391,7 +735,7 @@ func (s *StorageClient) InsertGuilds(ctx context.Context, guilds []model.Communi
 	var saved []model.Community
 	for rows.Next() {
 		var c model.Community
-		if err := rows.Scan(&c.Id, &c.Name, &c.Realm, &c.Locked); err != nil {
+		if err := rows.Scan(&c.Id, &c.Name, &c.Realm, &c.Region, &c.Status); err != nil {
 			return nil, err
 		}
 		saved = append(saved, c)
@@ -405,33 +749,151 @@ func (s *StorageClient) InsertGuilds(ctx context.Context, guilds []model.Communi
 	return saved, nil
 }
 
-func (s *StorageClient) SetOfficerRank(ctx context.Context, communityId string, req *model.CommunityRankRequest) error {
-	_, err := s.db.Exec(ctx, `
+func (s *StorageClient) SetOfficerRank(
+	ctx context.Context,
+	communityId string,
+	req *model.CommunityRankRequest,
+	event *model.AuditEvent,
+) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin settings transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
 			UPDATE communities
-			SET officer_rank = $1, member_rank = $2
-			WHERE id = $3::uuid
-		`, req.AdminRank, req.MemberRank, communityId)
+			SET officer_rank = $1,
+				member_rank = $2,
+				neighbor_weight = COALESCE($3, neighbor_weight),
+				neighborhoods = COALESCE($4, neighborhoods),
+				move_penalty = COALESCE($5, move_penalty),
+				objective = COALESCE($6, objective),
+				rank_weighting = COALESCE($7, rank_weighting),
+				join_requests = COALESCE($8, join_requests)
+			WHERE id = $9::uuid
+		`, req.AdminRank, req.MemberRank, req.NeighborWeight, req.Neighborhoods, req.MovePenalty, req.Objective,
+		req.RankWeighting, req.JoinRequests, communityId)
 	if err != nil {
 		log.Printf("Failed to update community %s's rank settings: %v", communityId, err)
 		return err
 	}
+	if err := claimVersion(ctx, tx, communityId); err != nil {
+		return err
+	}
+	if err := recordAuditEvent(ctx, tx, communityId, event); err != nil {
+		log.Printf("Failed to record settings of community %s: %v", communityId, err)
+		return err
+	}
 
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit settings transaction: %w", err)
+	}
 	return nil
 }
 
 func (s *StorageClient) GetCommunitySettings(ctx context.Context, communityId string) (*model.Settings, error) {
 	var officerRank, memberRank sql.NullInt32
+	var neighborWeight, neighborhoods, movePenalty int
+	var objective model.Objective
+	var weighting model.RankWeighting
+	var joinRequests bool
+	var deadline *time.Time
 	err := s.db.QueryRow(ctx,
-		`SELECT officer_rank, member_rank
+		`SELECT officer_rank, member_rank, neighbor_weight, neighborhoods, move_penalty, objective, rank_weighting, join_requests,
+				preference_deadline
 			     FROM communities
 				 WHERE id = $1`,
 		communityId,
-	).Scan(&officerRank, &memberRank)
+	).Scan(&officerRank, &memberRank, &neighborWeight, &neighborhoods, &movePenalty, &objective, &weighting, &joinRequests, &deadline)
 
 	if err != nil {
 		log.Printf("Failed to retrieve settings for community %s: %v", communityId, err)
 		return nil, err
 	}
 
-	return &model.Settings{OfficerRank: int(officerRank.Int32), MemberRank: int(memberRank.Int32)}, nil
+	return &model.Settings{
+		OfficerRank:        int(officerRank.Int32),
+		MemberRank:         int(memberRank.Int32),
+		NeighborWeight:     neighborWeight,
+		Neighborhoods:      neighborhoods,
+		MovePenalty:        movePenalty,
+		Objective:          objective,
+		RankWeighting:      weighting,
+		JoinRequests:       joinRequests,
+		PreferenceDeadline: deadline,
+	}, nil
+}
+
+// EnsureNeighborhoods grows a community to at least the given number of neighborhood instances.
+// Growing moves the version, officers have to see the new instance before their next edit.
+func (s *StorageClient) EnsureNeighborhoods(ctx context.Context, communityId string, count int) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin neighborhood transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var grown bool
+	err = tx.QueryRow(ctx, `SELECT neighborhoods < $2 FROM communities WHERE id = $1 FOR UPDATE`, communityId, count).Scan(&grown)
+	if err != nil {
+		log.Printf("Failed to read neighborhoods of community %s: %v", communityId, err)
+		return err
+	}
+	if !grown {
+		return nil
+	}
+	if err := claimVersion(ctx, tx, communityId); err != nil {
+		return err
+	}
+	if err := ensureNeighborhoods(ctx, tx, communityId, count); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit neighborhood transaction: %w", err)
+	}
+	return nil
+}
+
+// ensureNeighborhoods grows a community inside the transaction of the edit that needs the neighborhoods,
+// so they are only opened if the edit goes through.
+func ensureNeighborhoods(ctx context.Context, db execer, communityId string, count int) error {
+	_, err := db.Exec(ctx, `
+		UPDATE communities
+		SET neighborhoods = GREATEST(neighborhoods, $1)
+		WHERE id = $2
+	`, count, communityId)
+	if err != nil {
+		log.Printf("Failed to grow community %s to %d neighborhoods: %v", communityId, count, err)
+		return err
+	}
+	return nil
+}
+
+func (s *StorageClient) getNeighborWishes(ctx context.Context, communityId string) (map[string][]string, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT nw.battletag, nw.neighbor
+		FROM neighbor_wishes nw
+		WHERE nw.community_id = $1
+		ORDER BY nw.battletag, nw.neighbor
+	`, communityId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wishes := make(map[string][]string)
+	for rows.Next() {
+		var btag, neighbor string
+		if err := rows.Scan(&btag, &neighbor); err != nil {
+			return nil, err
+		}
+		wishes[btag] = append(wishes[btag], neighbor)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return wishes, nil
 }
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sbraitsch/plotter/internal/model"
+)
+
+// SetPreferenceDeadline moves or removes the deadline. A new deadline is handled by the scheduler again.
+func (s *StorageClient) SetPreferenceDeadline(ctx context.Context, communityId string, deadline *time.Time, event *model.AuditEvent) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin deadline transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE communities
+		SET preference_deadline = $2, deadline_locked_at = NULL
+		WHERE id = $1
+	`, communityId, deadline)
+	if err != nil {
+		log.Printf("Failed to set preference deadline of community %s: %v", communityId, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	if err := claimVersion(ctx, tx, communityId); err != nil {
+		return err
+	}
+	if err := recordAuditEvent(ctx, tx, communityId, event); err != nil {
+		log.Printf("Failed to record preference deadline of community %s: %v", communityId, err)
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit deadline transaction: %w", err)
+	}
+	return nil
+}
+
+// GetOverdueCommunities lists communities whose deadline passed and hasn't been handled yet.
+func (s *StorageClient) GetOverdueCommunities(ctx context.Context, now time.Time) ([]model.Community, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, name, realm, region, status, neighborhoods
+		FROM communities
+		WHERE preference_deadline <= $1 AND deadline_locked_at IS NULL AND status IN ($2, $3)
+		ORDER BY preference_deadline
+	`, now, model.STATUS_COLLECTING, model.STATUS_LOCKED)
+	if err != nil {
+		log.Printf("Overdue community query failed: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	communities := []model.Community{}
+
+	for rows.Next() {
+		var c model.Community
+		if err := rows.Scan(&c.Id, &c.Name, &c.Realm, &c.Region, &c.Status, &c.Neighborhoods); err != nil {
+			return nil, err
+		}
+		communities = append(communities, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return communities, nil
+}
+
+// MarkDeadlineLocked records that the deadline of a community was handled, so it isn't locked twice.
+func (s *StorageClient) MarkDeadlineLocked(ctx context.Context, communityId string) error {
+	_, err := s.db.Exec(ctx, `UPDATE communities SET deadline_locked_at = NOW() WHERE id = $1`, communityId)
+	if err != nil {
+		log.Printf("Failed to mark deadline of community %s as handled: %v", communityId, err)
+		return err
+	}
+	return nil
+}
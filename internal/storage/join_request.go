@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/sbraitsch/plotter/internal/model"
+)
+
+// RequestJoin files a request for the user. An open or denied request is returned
+// as it is, so asking again neither jumps the queue nor overturns a denial. The event
+// is recorded with the request as its after state, unless the request was denied.
+func (s *StorageClient) RequestJoin(
+	ctx context.Context,
+	user *model.User,
+	communityId string,
+	profile *model.WowProfile,
+	roster *model.Roster,
+	event *model.AuditEvent,
+) (*model.JoinRequest, error) {
+	request := &model.JoinRequest{Battletag: user.Battletag, Status: model.JOIN_REQUEST_PENDING}
+	if characters := guildCharacters(profile, roster); len(characters) > 0 {
+		request.Character, request.Rank = characters[0].Name, &characters[0].Rank
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin join request transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO join_requests (battletag, community_id, char, community_rank, status)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5)
+		ON CONFLICT (battletag, community_id) DO UPDATE
+		SET char = EXCLUDED.char, community_rank = EXCLUDED.community_rank
+	`, user.Battletag, communityId, request.Character, request.Rank, request.Status)
+	if err != nil {
+		log.Printf("Failed to file join request of %s: %v", user.Battletag, err)
+		return nil, fmt.Errorf("Information could not be persisted.")
+	}
+
+	err = tx.QueryRow(ctx, `
+		SELECT status, requested_at FROM join_requests WHERE battletag = $1 AND community_id = $2
+	`, user.Battletag, communityId).Scan(&request.Status, &request.RequestedAt)
+	if err != nil {
+		log.Printf("Failed to read join request of %s: %v", user.Battletag, err)
+		return nil, err
+	}
+
+	if request.Status != model.JOIN_REQUEST_DENIED {
+		event.After = model.AuditState(request)
+		if err := recordAuditEvent(ctx, tx, communityId, event); err != nil {
+			log.Printf("Failed to record join request of %s: %v", user.Battletag, err)
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit join request transaction: %w", err)
+	}
+	return request, nil
+}
+
+// GetJoinRequests is the queue officers work through, oldest first.
+func (s *StorageClient) GetJoinRequests(ctx context.Context, communityId string) ([]model.JoinRequest, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT battletag, COALESCE(char, ''), community_rank, status, requested_at
+		FROM join_requests
+		WHERE community_id = $1 AND status = $2
+		ORDER BY requested_at
+	`, communityId, model.JOIN_REQUEST_PENDING)
+	if err != nil {
+		log.Printf("Join request query failed: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []model.JoinRequest{}
+
+	for rows.Next() {
+		var r model.JoinRequest
+		if err := rows.Scan(&r.Battletag, &r.Character, &r.Rank, &r.Status, &r.RequestedAt); err != nil {
+			return nil, err
+		}
+		requests = append(requests, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error reading join requests from database: %v", err)
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// DecideJoinRequest approves or denies a request, depending on the action of the event.
+// Only pending requests can be decided, a decided one is reported as not found.
+func (s *StorageClient) DecideJoinRequest(ctx context.Context, communityId string, battletag string, event *model.AuditEvent) error {
+	status := model.JOIN_REQUEST_DENIED
+	if event.Action == model.AUDIT_JOIN_APPROVED {
+		status = model.JOIN_REQUEST_APPROVED
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin join request transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE join_requests
+		SET status = $3, decided_by = $4, decided_at = NOW()
+		WHERE battletag = $1 AND community_id = $2 AND status = $5
+	`, battletag, communityId, status, event.Actor, model.JOIN_REQUEST_PENDING)
+	if err != nil {
+		log.Printf("Failed to decide join request of %s: %v", battletag, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	// an approved guest counts as a member for the officers' next edit
+	if status == model.JOIN_REQUEST_APPROVED {
+		if err := claimVersion(ctx, tx, communityId); err != nil {
+			return err
+		}
+	}
+
+	if err := recordAuditEvent(ctx, tx, communityId, event); err != nil {
+		log.Printf("Failed to record join decision for %s: %v", battletag, err)
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit join request transaction: %w", err)
+	}
+	return nil
+}
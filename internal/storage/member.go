@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/sbraitsch/plotter/internal/model"
+)
+
+// RemoveMember deletes a membership together with its characters, preferences, wishes,
+// pin and assignment. Anyone but the member themselves has to outrank them. A released
+// assignment is recorded as a new revision under origin. A non-zero blockedUntil leaves
+// a tombstone that keeps the member from joining again before then. Manual users that
+// belong nowhere anymore are dropped.
+func (s *StorageClient) RemoveMember(
+	ctx context.Context,
+	communityId string,
+	battletag string,
+	event *model.AuditEvent,
+	origin *model.RevisionOrigin,
+	blockedUntil time.Time,
+) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin removal transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var rank, actorRank int
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(t.community_rank, 100), COALESCE(a.community_rank, 100)
+		FROM memberships t
+		LEFT JOIN memberships a ON a.battletag = $3 AND a.community_id = t.community_id
+		WHERE t.battletag = $1 AND t.community_id = $2
+		FOR UPDATE OF t
+	`, battletag, communityId, event.Actor).Scan(&rank, &actorRank)
+	if err != nil {
+		log.Printf("Failed to read membership of %s: %v", battletag, err)
+		return err
+	}
+	if !strings.EqualFold(event.Actor, battletag) && rank <= actorRank {
+		return ErrOutranked
+	}
+
+	// characters, plot_mappings and neighbor_wishes cascade from the membership
+	_, err = tx.Exec(ctx, `DELETE FROM memberships WHERE battletag = $1 AND community_id = $2`, battletag, communityId)
+	if err != nil {
+		log.Printf("Failed to remove membership of %s: %v", battletag, err)
+		return err
+	}
+
+	released, err := tx.Exec(ctx, `DELETE FROM assignments WHERE battletag = $1 AND community_id = $2`, battletag, communityId)
+	if err == nil {
+		_, err = tx.Exec(ctx, `DELETE FROM plot_pins WHERE battletag = $1 AND community_id = $2`, battletag, communityId)
+	}
+	if err != nil {
+		log.Printf("Failed to release plots of %s: %v", battletag, err)
+		return err
+	}
+	if released.RowsAffected() > 0 {
+		if err := snapshotAssignments(ctx, tx, communityId, origin); err != nil {
+			log.Printf("Failed to record released assignment of %s: %v", battletag, err)
+			return err
+		}
+	}
+
+	if !blockedUntil.IsZero() {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO membership_tombstones (battletag, community_id, removed_by, expires_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (battletag, community_id)
+			DO UPDATE SET removed_by = EXCLUDED.removed_by, expires_at = EXCLUDED.expires_at
+		`, battletag, communityId, event.Actor, blockedUntil)
+		if err != nil {
+			log.Printf("Failed to leave tombstone for %s: %v", battletag, err)
+			return err
+		}
+	}
+
+	if err := claimVersion(ctx, tx, communityId); err != nil {
+		return err
+	}
+	if err := recordAuditEvent(ctx, tx, communityId, event); err != nil {
+		log.Printf("Failed to record removal of %s: %v", battletag, err)
+		return err
+	}
+
+	// accounts that never logged in only existed for this community
+	_, err = tx.Exec(ctx, `
+		DELETE FROM users u
+		WHERE u.battletag = $1
+			AND NOT EXISTS (SELECT 1 FROM memberships m WHERE m.battletag = u.battletag)
+			AND NOT EXISTS (SELECT 1 FROM sessions s WHERE s.battletag = u.battletag)
+	`, battletag)
+	if err != nil {
+		log.Printf("Failed to drop account of %s: %v", battletag, err)
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit removal transaction: %w", err)
+	}
+	return nil
+}
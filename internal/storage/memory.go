@@ -0,0 +1,1459 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sbraitsch/plotter/internal/model"
+	"golang.org/x/oauth2"
+)
+
+// MemoryStore keeps everything in process memory. It enforces the same
+// constraints as the Postgres schema, so services behave the same on both.
+type MemoryStore struct {
+	mu sync.Mutex
+
+	plots       map[int]model.Plot
+	communities map[string]*memoryCommunity
+	users       map[string]*memoryUser
+	memberships map[memberKey]*memoryMembership
+	sessions    map[int]*memorySession
+	mappings    map[memberKey]map[model.PlotKey]int
+	wishes      map[memberKey][]string
+	assignments map[memberKey]model.Assignment
+	pins        map[string]map[string]model.PlotKey
+	reserved    map[string]map[model.PlotKey]model.ReservedPlot
+	runs        map[string][]model.OptimizationRun
+	audit       map[string][]model.AuditEvent
+	tombstones  map[memberKey]time.Time
+	requests    map[memberKey]*memoryJoinRequest
+	revisions   map[string][]model.AssignmentRevision
+	nextRunId   int
+	nextAuditId int
+	nextSession int
+	nextJoin    int
+}
+
+type memoryCommunity struct {
+	id       string
+	name     string
+	realm    string
+	region   model.Region
+	status   model.CommunityStatus
+	settings model.Settings
+	version  int64
+	// deadlineLocked is set once the scheduler handled the preference deadline
+	deadlineLocked bool
+}
+
+type memoryUser struct {
+	battletag    string
+	accessToken  string
+	refreshToken string
+	expiry       time.Time
+	region       model.Region
+}
+
+// memberKey addresses everything a user states or holds in one community.
+type memberKey struct {
+	battletag   string
+	communityId string
+}
+
+type memoryMembership struct {
+	memberKey
+	char          string
+	note          string
+	communityRank int
+	departed      bool
+	characters    []model.Character
+	approvedBy    string
+	manual        bool
+	joined        int
+}
+
+type memoryJoinRequest struct {
+	request   model.JoinRequest
+	decidedBy string
+}
+
+type memorySession struct {
+	session   model.Session
+	tokenHash string
+	battletag string
+}
+
+// NewMemoryStore creates an empty store serving the given plot catalog.
+func NewMemoryStore(plots []model.Plot) *MemoryStore {
+	catalog := make(map[int]model.Plot, len(plots))
+	for _, p := range plots {
+		catalog[p.Id] = p
+	}
+	return &MemoryStore{
+		plots:       catalog,
+		communities: make(map[string]*memoryCommunity),
+		users:       make(map[string]*memoryUser),
+		memberships: make(map[memberKey]*memoryMembership),
+		sessions:    make(map[int]*memorySession),
+		mappings:    make(map[memberKey]map[model.PlotKey]int),
+		wishes:      make(map[memberKey][]string),
+		assignments: make(map[memberKey]model.Assignment),
+		pins:        make(map[string]map[string]model.PlotKey),
+		reserved:    make(map[string]map[model.PlotKey]model.ReservedPlot),
+		runs:        make(map[string][]model.OptimizationRun),
+		audit:       make(map[string][]model.AuditEvent),
+		tombstones:  make(map[memberKey]time.Time),
+		requests:    make(map[memberKey]*memoryJoinRequest),
+		revisions:   make(map[string][]model.AssignmentRevision),
+	}
+}
+
+func (m *MemoryStore) GetUserByToken(ctx context.Context, token string, communityId string) (*model.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hash := hashToken(token)
+	for _, session := range m.sessions {
+		if session.tokenHash != hash || !time.Now().Before(session.session.ExpiresAt) {
+			continue
+		}
+		u, exists := m.users[session.battletag]
+		if !exists {
+			break
+		}
+		user := &model.User{
+			Battletag:    u.battletag,
+			AccessToken:  u.accessToken,
+			RefreshToken: u.refreshToken,
+			Expiry:       u.expiry,
+			Region:       u.region,
+			SessionId:    session.session.Id,
+		}
+
+		var membership *memoryMembership
+		for _, ms := range m.userMemberships(u.battletag) {
+			if communityId == "" || ms.communityId == communityId {
+				membership = ms
+				break
+			}
+		}
+		if membership == nil {
+			return user, nil
+		}
+		user.Char = membership.char
+		user.Note = membership.note
+		user.CommunityRank = membership.communityRank
+		user.Departed = membership.departed
+		if c, exists := m.communities[membership.communityId]; exists {
+			user.Community = model.UserCommunity{
+				Id:                 c.id,
+				Name:               c.name,
+				OfficerRank:        c.settings.OfficerRank,
+				Status:             c.status,
+				Realm:              c.realm,
+				Region:             c.region,
+				PreferenceDeadline: c.settings.PreferenceDeadline,
+			}
+		}
+		return user, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MemoryStore) RegisterUser(ctx context.Context, battletag string, region model.Region, token *oauth2.Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, exists := m.users[battletag]
+	if !exists {
+		u = &memoryUser{battletag: battletag, region: model.DEFAULT_REGION}
+		m.users[battletag] = u
+	}
+	u.accessToken = token.AccessToken
+	u.refreshToken = token.RefreshToken
+	u.expiry = token.Expiry
+	u.region = region
+	return nil
+}
+
+func (m *MemoryStore) UpdateUserToken(ctx context.Context, battletag string, token *oauth2.Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if u, exists := m.users[battletag]; exists {
+		u.accessToken = token.AccessToken
+		u.expiry = token.Expiry
+		if token.RefreshToken != "" {
+			u.refreshToken = token.RefreshToken
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) ClearRefreshToken(ctx context.Context, battletag string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if u, exists := m.users[battletag]; exists {
+		u.refreshToken = ""
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetRefreshableUsers(ctx context.Context, expiringBefore time.Time) ([]model.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	users := []model.User{}
+	for _, u := range m.users {
+		if u.refreshToken != "" && u.expiry.Before(expiringBefore) {
+			users = append(users, model.User{
+				Battletag:    u.battletag,
+				Region:       u.region,
+				RefreshToken: u.refreshToken,
+				Expiry:       u.expiry,
+			})
+		}
+	}
+	return users, nil
+}
+
+func (m *MemoryStore) CreateSession(ctx context.Context, battletag string, userAgent string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.users[battletag]; !exists {
+		return "", fmt.Errorf("unknown user %s", battletag)
+	}
+
+	now := time.Now()
+	for id, session := range m.sessions {
+		if session.battletag == battletag && !now.Before(session.session.ExpiresAt) {
+			delete(m.sessions, id)
+		}
+	}
+
+	sessionToken := uuid.New().String()
+	m.nextSession++
+	m.sessions[m.nextSession] = &memorySession{
+		session: model.Session{
+			Id:         m.nextSession,
+			UserAgent:  userAgent,
+			CreatedAt:  now,
+			LastSeenAt: now,
+			ExpiresAt:  now.Add(model.SESSION_IDLE_TTL),
+		},
+		tokenHash: hashToken(sessionToken),
+		battletag: battletag,
+	}
+	return sessionToken, nil
+}
+
+func (m *MemoryStore) TouchSession(ctx context.Context, sessionId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, exists := m.sessions[sessionId]
+	now := time.Now()
+	if !exists || now.Sub(session.session.LastSeenAt) < model.SESSION_RENEW_INTERVAL {
+		return nil
+	}
+	session.session.LastSeenAt = now
+	session.session.ExpiresAt = now.Add(model.SESSION_IDLE_TTL)
+	if maxExpiry := session.session.CreatedAt.Add(model.SESSION_MAX_AGE); maxExpiry.Before(session.session.ExpiresAt) {
+		session.session.ExpiresAt = maxExpiry
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetSessions(ctx context.Context, battletag string) ([]model.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sessions := []model.Session{}
+	now := time.Now()
+	for _, session := range m.sessions {
+		if session.battletag == battletag && now.Before(session.session.ExpiresAt) {
+			sessions = append(sessions, session.session)
+		}
+	}
+	slices.SortFunc(sessions, func(a, b model.Session) int { return b.LastSeenAt.Compare(a.LastSeenAt) })
+	return sessions, nil
+}
+
+func (m *MemoryStore) DeleteSession(ctx context.Context, battletag string, sessionId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if session, exists := m.sessions[sessionId]; exists && session.battletag == battletag {
+		delete(m.sessions, sessionId)
+	}
+	return nil
+}
+
+func (m *MemoryStore) DeleteSessions(ctx context.Context, battletag string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, session := range m.sessions {
+		if session.battletag == battletag {
+			delete(m.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) RegisterManualUsers(ctx context.Context, assignments []model.Assignment, communityID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.registerManualUsers(assignments, communityID)
+}
+
+func (m *MemoryStore) registerManualUsers(assignments []model.Assignment, communityID string) error {
+	c, exists := m.communities[communityID]
+	if !exists {
+		return fmt.Errorf("failed to fetch community member_rank: %w", ErrNotFound)
+	}
+	for _, a := range assignments {
+		if a.Battletag == "" {
+			continue
+		}
+		if _, exists := m.users[a.Battletag]; !exists {
+			m.users[a.Battletag] = &memoryUser{
+				battletag:   a.Battletag,
+				accessToken: uuid.New().String(),
+				expiry:      time.Now().Add(24 * time.Hour),
+				region:      model.DEFAULT_REGION,
+			}
+		}
+		key := memberKey{battletag: a.Battletag, communityId: communityID}
+		if _, exists := m.memberships[key]; !exists {
+			m.nextJoin++
+			m.memberships[key] = &memoryMembership{
+				memberKey:     key,
+				char:          a.Character,
+				communityRank: c.settings.MemberRank,
+				joined:        m.nextJoin,
+				manual:        true,
+			}
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) SetNote(ctx context.Context, user *model.User, note string, event *model.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if ms, exists := m.memberships[memberKey{user.Battletag, user.Community.Id}]; exists {
+		ms.note = note
+	}
+	m.recordAuditEvent(user.Community.Id, event)
+	return nil
+}
+
+func (m *MemoryStore) GetMemberships(ctx context.Context, battletag string) ([]model.Membership, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	memberships := []model.Membership{}
+	for _, ms := range m.userMemberships(battletag) {
+		c, exists := m.communities[ms.communityId]
+		if !exists {
+			continue
+		}
+		memberships = append(memberships, model.Membership{
+			Community: model.ValidatedCommunity{
+				Id:          c.id,
+				Name:        c.name,
+				Realm:       c.realm,
+				Region:      c.region,
+				Status:      c.status,
+				Preferences: model.NewPreferenceWindow(c.settings.PreferenceDeadline, time.Now()),
+			},
+			Char:     ms.char,
+			IsAdmin:  !ms.departed && ms.communityRank <= c.settings.OfficerRank,
+			Departed: ms.departed,
+		})
+	}
+	return memberships, nil
+}
+
+func (m *MemoryStore) GetCharacters(ctx context.Context, battletag string, communityId string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	characters := []string{}
+	if ms, exists := m.memberships[memberKey{battletag, communityId}]; exists {
+		for _, c := range ms.characters {
+			if !slices.Contains(characters, c.Name) {
+				characters = append(characters, c.Name)
+			}
+		}
+	}
+	slices.Sort(characters)
+	return characters, nil
+}
+
+func (m *MemoryStore) SetCharacter(ctx context.Context, user *model.User, char string, event *model.AuditEvent) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := memberKey{user.Battletag, user.Community.Id}
+	ms, exists := m.memberships[key]
+	if !exists {
+		return "", fmt.Errorf("%s is not a member of this community", user.Battletag)
+	}
+	idx := slices.IndexFunc(ms.characters, func(c model.Character) bool { return strings.EqualFold(c.Name, strings.TrimSpace(char)) })
+	if idx < 0 {
+		return "", fmt.Errorf("%s is not one of your characters in this guild", char)
+	}
+	name := ms.characters[idx].Name
+
+	if a, exists := m.assignments[key]; exists {
+		if c, exists := m.communities[key.communityId]; exists && c.status.AssignmentsOpen() {
+			if err := m.claimVersion(ctx, key.communityId); err != nil {
+				return "", err
+			}
+			a.Character = name
+			m.assignments[key] = a
+		}
+	}
+	ms.char = name
+	event.After = model.AuditState(ms.char)
+	m.recordAuditEvent(key.communityId, event)
+	return ms.char, nil
+}
+
+func (m *MemoryStore) GetCommunity(ctx context.Context, communityId string) (*model.Community, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, exists := m.communities[communityId]
+	if !exists {
+		return nil, 0, fmt.Errorf("failed to get community info: %w", ErrNotFound)
+	}
+	return &model.Community{
+		Id:            c.id,
+		Name:          c.name,
+		Realm:         c.realm,
+		Region:        c.region,
+		Neighborhoods: c.settings.Neighborhoods,
+	}, c.settings.MemberRank, nil
+}
+
+func (m *MemoryStore) GetCommunitySize(ctx context.Context, communityId string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.members(communityId)), nil
+}
+
+func (m *MemoryStore) GetCommunityData(ctx context.Context, user *model.User) (*model.CommunityData, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, exists := m.communities[user.Community.Id]
+	if !exists {
+		return nil, fmt.Errorf("failed to get optimizer settings: %w", ErrNotFound)
+	}
+
+	members := []model.MemberData{}
+	for _, ms := range m.members(c.id) {
+		members = append(members, model.MemberData{
+			BattleTag: ms.battletag,
+			Character: ms.char,
+			Rank:      ms.communityRank,
+			PlotData:  m.plotData(ms.memberKey),
+			Neighbors: m.neighborWishes(ms.memberKey),
+		})
+	}
+
+	constraints := m.constraints(c.id)
+	return &model.CommunityData{
+		Id:             c.id,
+		Members:        members,
+		NeighborWeight: c.settings.NeighborWeight,
+		Neighborhoods:  c.settings.Neighborhoods,
+		MovePenalty:    c.settings.MovePenalty,
+		Objective:      c.settings.Objective,
+		RankWeighting:  c.settings.RankWeighting,
+		Plots:          m.catalog(),
+		Pins:           constraints.Pins,
+		Reserved:       constraints.Reserved,
+	}, nil
+}
+
+func (m *MemoryStore) GetFullCommunityData(ctx context.Context, user *model.User) (*model.FullCommunityData, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	members := []model.FullMemberData{}
+	for _, ms := range m.members(user.Community.Id) {
+		member := model.FullMemberData{
+			Assignment: model.Assignment{
+				Battletag: ms.battletag,
+				Character: ms.char,
+			},
+			Note:      ms.note,
+			Rank:      ms.communityRank,
+			PlotData:  m.plotData(ms.memberKey),
+			Neighbors: m.neighborWishes(ms.memberKey),
+		}
+		if a, exists := m.assignments[ms.memberKey]; exists {
+			member.Assignment.Neighborhood = a.Neighborhood
+			member.Assignment.Plot = a.Plot
+			member.Assignment.Score = a.Score
+		}
+		members = append(members, member)
+	}
+
+	return &model.FullCommunityData{
+		Id:      user.Community.Id,
+		Members: members,
+	}, nil
+}
+
+func (m *MemoryStore) GetCommunitySettings(ctx context.Context, communityId string) (*model.Settings, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, exists := m.communities[communityId]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	settings := c.settings
+	return &settings, nil
+}
+
+func (m *MemoryStore) InsertGuilds(ctx context.Context, guilds []model.Community) ([]model.Community, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, g := range guilds {
+		if m.communityByKey(g.Region, g.Realm, g.Name) != nil {
+			continue
+		}
+		id := uuid.New().String()
+		m.communities[id] = &memoryCommunity{
+			id:      id,
+			name:    g.Name,
+			realm:   g.Realm,
+			region:  g.Region,
+			status:  model.STATUS_COLLECTING,
+			version: 1,
+			settings: model.Settings{
+				OfficerRank:   0,
+				MemberRank:    1,
+				Neighborhoods: 1,
+				Objective:     model.OBJECTIVE_SUM,
+				RankWeighting: model.RankWeighting{Mode: model.RANK_WEIGHTING_NONE},
+			},
+		}
+	}
+
+	var saved []model.Community
+	for _, g := range guilds {
+		c := m.communityByKey(g.Region, g.Realm, g.Name)
+		if c == nil || slices.ContainsFunc(saved, func(s model.Community) bool { return s.Id == c.id }) {
+			continue
+		}
+		saved = append(saved, model.Community{Id: c.id, Name: c.name, Realm: c.realm, Region: c.region, Status: c.status})
+	}
+	return saved, nil
+}
+
+func (m *MemoryStore) JoinCommunity(
+	ctx context.Context,
+	user *model.User,
+	requiredRank int,
+	communityId string,
+	profile *model.WowProfile,
+	roster *model.Roster,
+	event *model.AuditEvent,
+) (*model.JoinedCommunity, error) {
+	characters := guildCharacters(profile, roster)
+	eligible := len(characters) > 0 && requiredRank >= characters[0].Rank
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.communities[communityId]; !exists {
+		return nil, fmt.Errorf("Information could not be persisted.")
+	}
+	key := memberKey{user.Battletag, communityId}
+	if blockedUntil, exists := m.tombstones[key]; exists && time.Now().Before(blockedUntil) {
+		return nil, fmt.Errorf("You were removed from this community and can join again after %s.", blockedUntil.Format(time.DateOnly))
+	}
+	if _, exists := m.users[user.Battletag]; !exists {
+		return nil, fmt.Errorf("Information could not be persisted.")
+	}
+	approvedBy := ""
+	if !eligible {
+		req, exists := m.requests[key]
+		if !exists || req.request.Status != model.JOIN_REQUEST_APPROVED {
+			return nil, ErrRankRequirement
+		}
+		if len(characters) == 0 {
+			characters = profileCharacters(profile, requiredRank)
+		}
+		if len(characters) == 0 {
+			return nil, ErrRankRequirement
+		}
+		approvedBy = req.decidedBy
+	}
+	ms, exists := m.memberships[key]
+	if !exists {
+		m.nextJoin++
+		ms = &memoryMembership{memberKey: key, joined: m.nextJoin}
+		m.memberships[key] = ms
+	}
+	if approvedBy != "" {
+		ms.approvedBy = approvedBy
+	}
+	delete(m.requests, key)
+	ms.char = characters[0].Name
+	ms.communityRank = characters[0].Rank
+	ms.departed = false
+	ms.manual = false
+	ms.characters = slices.Clone(characters)
+
+	joined := &model.JoinedCommunity{Char: characters[0].Name, Characters: characters}
+	event.After = model.AuditState(joined)
+	m.recordAuditEvent(communityId, event)
+	return joined, nil
+}
+
+func (m *MemoryStore) GetCommunities(ctx context.Context) ([]model.Community, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	populated := make(map[string]bool)
+	for key := range m.memberships {
+		populated[key.communityId] = true
+	}
+
+	communities := []model.Community{}
+	for _, c := range m.communities {
+		if !populated[c.id] {
+			continue
+		}
+		communities = append(communities, model.Community{
+			Id:            c.id,
+			Name:          c.name,
+			Realm:         c.realm,
+			Region:        c.region,
+			Status:        c.status,
+			Neighborhoods: c.settings.Neighborhoods,
+		})
+	}
+	slices.SortFunc(communities, func(a, b model.Community) int {
+		return cmp.Or(cmp.Compare(a.Region, b.Region), cmp.Compare(a.Realm, b.Realm), cmp.Compare(a.Name, b.Name))
+	})
+	return communities, nil
+}
+
+func (m *MemoryStore) SyncRoster(ctx context.Context, communityId string, roster *model.Roster) ([]model.RosterChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	members := []model.RosterMember{}
+	for _, ms := range m.memberships {
+		if ms.communityId == communityId {
+			members = append(members, model.RosterMember{
+				Battletag:  ms.battletag,
+				Character:  ms.char,
+				Characters: ms.characters,
+				Rank:       ms.communityRank,
+				Departed:   ms.departed,
+				Guest:      ms.approvedBy != "",
+				Manual:     ms.manual,
+			})
+		}
+	}
+	slices.SortFunc(members, func(a, b model.RosterMember) int { return cmp.Compare(a.Battletag, b.Battletag) })
+
+	changes := roster.Diff(members)
+	if len(changes) > 0 {
+		if err := m.claimVersion(ctx, communityId); err != nil {
+			return nil, err
+		}
+	}
+	for _, change := range changes {
+		ms := m.memberships[memberKey{change.Battletag, communityId}]
+		ms.departed = change.Kind == model.ROSTER_DEPARTED
+		ms.communityRank = change.NewRank
+	}
+	return changes, nil
+}
+
+func (m *MemoryStore) SetOfficerRank(
+	ctx context.Context,
+	communityId string,
+	req *model.CommunityRankRequest,
+	event *model.AuditEvent,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, exists := m.communities[communityId]
+	if !exists {
+		return nil
+	}
+	if req.Neighborhoods != nil && (*req.Neighborhoods < 1 || *req.Neighborhoods > model.MAX_NEIGHBORHOODS) {
+		return fmt.Errorf("neighborhoods must be between 1 and %d", model.MAX_NEIGHBORHOODS)
+	}
+	if req.MovePenalty != nil && *req.MovePenalty < 0 {
+		return fmt.Errorf("move penalty must not be negative")
+	}
+
+	if err := m.claimVersion(ctx, communityId); err != nil {
+		return err
+	}
+	c.settings = c.settings.With(req)
+	m.recordAuditEvent(communityId, event)
+	return nil
+}
+
+func (m *MemoryStore) RequestJoin(
+	ctx context.Context,
+	user *model.User,
+	communityId string,
+	profile *model.WowProfile,
+	roster *model.Roster,
+	event *model.AuditEvent,
+) (*model.JoinRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.communities[communityId]; !exists {
+		return nil, fmt.Errorf("Information could not be persisted.")
+	}
+	key := memberKey{user.Battletag, communityId}
+	req, exists := m.requests[key]
+	if !exists {
+		req = &memoryJoinRequest{request: model.JoinRequest{
+			Battletag:   user.Battletag,
+			Status:      model.JOIN_REQUEST_PENDING,
+			RequestedAt: time.Now(),
+		}}
+		m.requests[key] = req
+	}
+	req.request.Character, req.request.Rank = "", nil
+	if characters := guildCharacters(profile, roster); len(characters) > 0 {
+		req.request.Character, req.request.Rank = characters[0].Name, &characters[0].Rank
+	}
+	request := req.request
+	if request.Status != model.JOIN_REQUEST_DENIED {
+		event.After = model.AuditState(request)
+		m.recordAuditEvent(communityId, event)
+	}
+	return &request, nil
+}
+
+func (m *MemoryStore) GetJoinRequests(ctx context.Context, communityId string) ([]model.JoinRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	requests := []model.JoinRequest{}
+	for key, req := range m.requests {
+		if key.communityId == communityId && req.request.Status == model.JOIN_REQUEST_PENDING {
+			requests = append(requests, req.request)
+		}
+	}
+	slices.SortFunc(requests, func(a, b model.JoinRequest) int { return a.RequestedAt.Compare(b.RequestedAt) })
+	return requests, nil
+}
+
+func (m *MemoryStore) DecideJoinRequest(ctx context.Context, communityId string, battletag string, event *model.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	req, exists := m.requests[memberKey{battletag, communityId}]
+	if !exists || req.request.Status != model.JOIN_REQUEST_PENDING {
+		return ErrNotFound
+	}
+	req.request.Status = model.JOIN_REQUEST_DENIED
+	if event.Action == model.AUDIT_JOIN_APPROVED {
+		if err := m.claimVersion(ctx, communityId); err != nil {
+			return err
+		}
+		req.request.Status = model.JOIN_REQUEST_APPROVED
+	}
+	req.decidedBy = event.Actor
+	m.recordAuditEvent(communityId, event)
+	return nil
+}
+
+func (m *MemoryStore) GetAuditEvents(ctx context.Context, communityId string, filter *model.AuditFilter) ([]model.AuditEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := []model.AuditEvent{}
+	for _, e := range slices.Backward(m.audit[communityId]) {
+		if len(events) == filter.Limit {
+			break
+		}
+		if filter.Matches(&e) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (m *MemoryStore) RemoveMember(
+	ctx context.Context,
+	communityId string,
+	battletag string,
+	event *model.AuditEvent,
+	origin *model.RevisionOrigin,
+	blockedUntil time.Time,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := memberKey{battletag, communityId}
+	ms, exists := m.memberships[key]
+	if !exists {
+		return ErrNotFound
+	}
+	if !strings.EqualFold(event.Actor, battletag) {
+		actor, exists := m.memberships[memberKey{event.Actor, communityId}]
+		if !exists || ms.communityRank <= actor.communityRank {
+			return ErrOutranked
+		}
+	}
+	if err := m.claimVersion(ctx, communityId); err != nil {
+		return err
+	}
+	_, released := m.assignments[key]
+	delete(m.memberships, key)
+	delete(m.mappings, key)
+	delete(m.wishes, key)
+	delete(m.assignments, key)
+	delete(m.pins[communityId], battletag)
+	for other, neighbors := range m.wishes {
+		if other.communityId == communityId {
+			m.wishes[other] = slices.DeleteFunc(neighbors, func(n string) bool { return n == battletag })
+		}
+	}
+	if released {
+		m.snapshotAssignments(communityId, origin)
+	}
+
+	if !blockedUntil.IsZero() {
+		m.tombstones[key] = blockedUntil
+	}
+	m.recordAuditEvent(communityId, event)
+
+	if len(m.userMemberships(battletag)) == 0 && !m.hasSession(battletag) {
+		delete(m.users, battletag)
+	}
+	return nil
+}
+
+func (m *MemoryStore) EnsureNeighborhoods(ctx context.Context, communityId string, count int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, exists := m.communities[communityId]
+	if !exists || c.settings.Neighborhoods >= count {
+		return nil
+	}
+	if err := m.claimVersion(ctx, communityId); err != nil {
+		return err
+	}
+	m.ensureNeighborhoods(communityId, count)
+	return nil
+}
+
+func (m *MemoryStore) ensureNeighborhoods(communityId string, count int) {
+	if c, exists := m.communities[communityId]; exists {
+		c.settings.Neighborhoods = max(c.settings.Neighborhoods, count)
+	}
+}
+
+func (m *MemoryStore) GetCommunityStatus(ctx context.Context, communityId string) (model.CommunityStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, exists := m.communities[communityId]
+	if !exists {
+		return "", ErrNotFound
+	}
+	return c.status, nil
+}
+
+func (m *MemoryStore) TransitionCommunity(ctx context.Context, communityId string, to model.CommunityStatus, event *model.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, exists := m.communities[communityId]
+	if !exists {
+		return ErrNotFound
+	}
+	if !c.status.CanBecome(to) {
+		return &model.StatusError{Status: c.status, To: to}
+	}
+	if err := m.claimVersion(ctx, communityId); err != nil {
+		return err
+	}
+	c.status = to
+	m.recordAuditEvent(communityId, event)
+	return nil
+}
+
+func (m *MemoryStore) GetCommunityVersion(ctx context.Context, communityId string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, exists := m.communities[communityId]
+	if !exists {
+		return 0, ErrNotFound
+	}
+	return c.version, nil
+}
+
+// claimVersion moves a community to its next version, the caller holds the lock and has
+// validated the change it is about to make.
+func (m *MemoryStore) claimVersion(ctx context.Context, communityId string) error {
+	c, exists := m.communities[communityId]
+	if !exists {
+		return ErrNotFound
+	}
+	claim := versionClaim(ctx)
+	moved := claim.Expected == nil || *claim.Expected == c.version
+	if moved {
+		c.version++
+	}
+	return claim.claim(communityId, c.version, moved)
+}
+
+func (m *MemoryStore) SetPreferenceDeadline(ctx context.Context, communityId string, deadline *time.Time, event *model.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, exists := m.communities[communityId]
+	if !exists {
+		return ErrNotFound
+	}
+	if err := m.claimVersion(ctx, communityId); err != nil {
+		return err
+	}
+	c.settings.PreferenceDeadline = deadline
+	c.deadlineLocked = false
+	m.recordAuditEvent(communityId, event)
+	return nil
+}
+
+func (m *MemoryStore) GetOverdueCommunities(ctx context.Context, now time.Time) ([]model.Community, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	overdue := []*memoryCommunity{}
+	for _, c := range m.communities {
+		deadline := c.settings.PreferenceDeadline
+		if deadline != nil && !deadline.After(now) && !c.deadlineLocked && c.status.AssignmentsOpen() {
+			overdue = append(overdue, c)
+		}
+	}
+	slices.SortFunc(overdue, func(a, b *memoryCommunity) int {
+		return a.settings.PreferenceDeadline.Compare(*b.settings.PreferenceDeadline)
+	})
+
+	communities := make([]model.Community, 0, len(overdue))
+	for _, c := range overdue {
+		communities = append(communities, model.Community{
+			Id:            c.id,
+			Name:          c.name,
+			Realm:         c.realm,
+			Region:        c.region,
+			Status:        c.status,
+			Neighborhoods: c.settings.Neighborhoods,
+		})
+	}
+	return communities, nil
+}
+
+func (m *MemoryStore) MarkDeadlineLocked(ctx context.Context, communityId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c, exists := m.communities[communityId]; exists {
+		c.deadlineLocked = true
+	}
+	return nil
+}
+
+func (m *MemoryStore) SavePlotMappings(ctx context.Context, user *model.User, mappings map[model.PlotKey]int, event *model.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, exists := m.communities[user.Community.Id]
+	if !exists {
+		return ErrNotFound
+	}
+	if !c.status.PreferencesOpen() {
+		return &model.StatusError{Status: c.status, Action: "change preferences"}
+	}
+	key := memberKey{user.Battletag, user.Community.Id}
+	if _, exists := m.memberships[key]; !exists {
+		return fmt.Errorf("%s is not a member of this community", user.Battletag)
+	}
+
+	priorities := make(map[int]bool, len(mappings))
+	saved := make(map[model.PlotKey]int, len(mappings))
+	for key, priority := range mappings {
+		if _, exists := m.plots[key.Plot]; !exists {
+			return fmt.Errorf("plot %d is not in the plot catalog", key.Plot)
+		}
+		if key.Neighborhood < 0 {
+			return fmt.Errorf("invalid neighborhood %d", key.Neighborhood)
+		}
+		if priority < 1 {
+			return fmt.Errorf("priority %d must be at least 1", priority)
+		}
+		if priorities[priority] {
+			return fmt.Errorf("priority %d is used more than once", priority)
+		}
+		priorities[priority] = true
+		saved[key] = priority
+	}
+	if err := m.claimVersion(ctx, user.Community.Id); err != nil {
+		return err
+	}
+
+	m.mappings[key] = saved
+	m.recordAuditEvent(user.Community.Id, event)
+	return nil
+}
+
+func (m *MemoryStore) SaveNeighborWishes(ctx context.Context, user *model.User, neighbors []string, event *model.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, exists := m.communities[user.Community.Id]
+	if !exists {
+		return ErrNotFound
+	}
+	if !c.status.PreferencesOpen() {
+		return &model.StatusError{Status: c.status, Action: "change neighbor wishes"}
+	}
+
+	// only members of the same community can be wished for
+	wishes := []string{}
+	for _, neighbor := range neighbors {
+		_, exists := m.memberships[memberKey{neighbor, user.Community.Id}]
+		if !exists || neighbor == user.Battletag || slices.Contains(wishes, neighbor) {
+			continue
+		}
+		wishes = append(wishes, neighbor)
+	}
+	slices.Sort(wishes)
+	if err := m.claimVersion(ctx, user.Community.Id); err != nil {
+		return err
+	}
+
+	m.wishes[memberKey{user.Battletag, user.Community.Id}] = wishes
+	m.recordAuditEvent(user.Community.Id, event)
+	return nil
+}
+
+func (m *MemoryStore) GetAssignments(ctx context.Context, communityId string) ([]model.Assignment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.communityAssignments(communityId), nil
+}
+
+func (m *MemoryStore) SetAssignment(
+	ctx context.Context,
+	req *model.SingleAssignmentRequest,
+	communityId string,
+	origin *model.RevisionOrigin,
+	event *model.AuditEvent,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	assignment := model.Assignment{
+		Character:    req.Char,
+		Battletag:    req.Battletag,
+		Neighborhood: req.Neighborhood,
+		Plot:         req.PlotId,
+		Score:        0,
+	}
+	if c, exists := m.communities[communityId]; exists && !c.status.AssignmentsOpen() {
+		return &model.StatusError{Status: c.status, Action: "change assignments"}
+	}
+	if err := m.checkSlot(req.Neighborhood, req.PlotId); err != nil {
+		return err
+	}
+	if err := m.claimVersion(ctx, communityId); err != nil {
+		return err
+	}
+	m.ensureNeighborhoods(communityId, req.Neighborhood)
+	if err := m.registerManualUsers([]model.Assignment{assignment}, communityId); err != nil {
+		return err
+	}
+
+	for key, a := range m.assignments {
+		if key.communityId == communityId && a.Neighborhood == req.Neighborhood && a.Plot == req.PlotId {
+			delete(m.assignments, key)
+		}
+	}
+	m.assignments[memberKey{req.Battletag, communityId}] = assignment
+	m.snapshotAssignments(communityId, origin)
+	m.recordAuditEvent(communityId, event)
+	return nil
+}
+
+func (m *MemoryStore) PersistAndLock(
+	ctx context.Context,
+	assignments []model.Assignment,
+	communityId string,
+	allowed func(model.CommunityStatus) bool,
+	origin *model.RevisionOrigin,
+	event *model.AuditEvent,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, exists := m.communities[communityId]
+	if !exists {
+		return fmt.Errorf("unknown community %s", communityId)
+	}
+	if !allowed(c.status) {
+		return &model.StatusError{Status: c.status, Action: "change assignments"}
+	}
+
+	taken := make(map[model.PlotKey]string, len(assignments))
+	for _, a := range assignments {
+		if err := m.checkSlot(a.Neighborhood, a.Plot); err != nil {
+			return err
+		}
+		key := model.PlotKey{Neighborhood: a.Neighborhood, Plot: a.Plot}
+		if other, exists := taken[key]; exists && other != a.Battletag {
+			return fmt.Errorf("plot %d:%d is assigned to both %s and %s", a.Neighborhood, a.Plot, other, a.Battletag)
+		}
+		taken[key] = a.Battletag
+	}
+	if err := m.claimVersion(ctx, communityId); err != nil {
+		return err
+	}
+	if err := m.registerManualUsers(assignments, communityId); err != nil {
+		return err
+	}
+	for _, a := range assignments {
+		m.ensureNeighborhoods(communityId, a.Neighborhood)
+	}
+
+	for key := range m.assignments {
+		if key.communityId == communityId {
+			delete(m.assignments, key)
+		}
+	}
+	for _, a := range assignments {
+		m.assignments[memberKey{a.Battletag, communityId}] = model.Assignment{
+			Character:    a.Character,
+			Battletag:    a.Battletag,
+			Neighborhood: a.Neighborhood,
+			Plot:         a.Plot,
+			Score:        a.Score,
+		}
+	}
+	c.status = model.STATUS_LOCKED
+	m.snapshotAssignments(communityId, origin)
+	m.recordAuditEvent(communityId, event)
+	return nil
+}
+
+func (m *MemoryStore) GetRevisions(ctx context.Context, communityId string) ([]model.AssignmentRevision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	revisions := []model.AssignmentRevision{}
+	for _, r := range slices.Backward(m.revisions[communityId]) {
+		r.Assignments = nil
+		revisions = append(revisions, r)
+	}
+	return revisions, nil
+}
+
+func (m *MemoryStore) GetRevision(ctx context.Context, communityId string, revision int) (*model.AssignmentRevision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	revisions := m.revisions[communityId]
+	if revision < 1 || revision > len(revisions) {
+		return nil, ErrNotFound
+	}
+	r := revisions[revision-1]
+	r.Assignments = slices.Clone(r.Assignments)
+	return &r, nil
+}
+
+func (m *MemoryStore) GetPlotConstraints(ctx context.Context, communityId string) (*model.PlotConstraints, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.constraints(communityId), nil
+}
+
+func (m *MemoryStore) SetPin(ctx context.Context, communityId string, pin *model.Pin, event *model.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkSlot(pin.Neighborhood, pin.Plot); err != nil {
+		return err
+	}
+	if _, exists := m.memberships[memberKey{pin.Battletag, communityId}]; !exists {
+		return fmt.Errorf("%s is not a member of this community", pin.Battletag)
+	}
+
+	if err := m.claimVersion(ctx, communityId); err != nil {
+		return err
+	}
+	m.ensureNeighborhoods(communityId, pin.Neighborhood)
+	pins, exists := m.pins[communityId]
+	if !exists {
+		pins = make(map[string]model.PlotKey)
+		m.pins[communityId] = pins
+	}
+	// a plot can only hold one pin, so whoever was pinned there before is released
+	key := model.PlotKey{Neighborhood: pin.Neighborhood, Plot: pin.Plot}
+	for btag, slot := range pins {
+		if slot == key {
+			delete(pins, btag)
+		}
+	}
+	pins[pin.Battletag] = key
+	m.recordAuditEvent(communityId, event)
+	return nil
+}
+
+func (m *MemoryStore) RemovePin(ctx context.Context, communityId string, battletag string, event *model.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.claimVersion(ctx, communityId); err != nil {
+		return err
+	}
+	delete(m.pins[communityId], battletag)
+	m.recordAuditEvent(communityId, event)
+	return nil
+}
+
+func (m *MemoryStore) ReservePlot(ctx context.Context, communityId string, reserved *model.ReservedPlot, event *model.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.communities[communityId]; !exists {
+		return fmt.Errorf("unknown community %s", communityId)
+	}
+	if err := m.checkSlot(reserved.Neighborhood, reserved.Plot); err != nil {
+		return err
+	}
+	if reserved.Status != model.PLOT_RESERVED && reserved.Status != model.PLOT_UNAVAILABLE {
+		return fmt.Errorf("invalid plot status %q", reserved.Status)
+	}
+
+	if err := m.claimVersion(ctx, communityId); err != nil {
+		return err
+	}
+	plots, exists := m.reserved[communityId]
+	if !exists {
+		plots = make(map[model.PlotKey]model.ReservedPlot)
+		m.reserved[communityId] = plots
+	}
+	plots[model.PlotKey{Neighborhood: reserved.Neighborhood, Plot: reserved.Plot}] = *reserved
+	m.recordAuditEvent(communityId, event)
+	return nil
+}
+
+func (m *MemoryStore) ReleasePlot(ctx context.Context, communityId string, neighborhood, plot int, event *model.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.claimVersion(ctx, communityId); err != nil {
+		return err
+	}
+	delete(m.reserved[communityId], model.PlotKey{Neighborhood: neighborhood, Plot: plot})
+	m.recordAuditEvent(communityId, event)
+	return nil
+}
+
+func (m *MemoryStore) GetPlots(ctx context.Context) ([]model.Plot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.catalog(), nil
+}
+
+func (m *MemoryStore) RecordOptimizationRun(ctx context.Context, communityId string, run *model.OptimizationRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.communities[communityId]; !exists {
+		return fmt.Errorf("unknown community %s", communityId)
+	}
+	m.nextRunId++
+	recorded := *run
+	recorded.Id = m.nextRunId
+	recorded.CreatedAt = time.Now()
+	m.runs[communityId] = append(m.runs[communityId], recorded)
+	return nil
+}
+
+func (m *MemoryStore) GetOptimizationRuns(ctx context.Context, communityId string, limit int) ([]model.OptimizationRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	runs := []model.OptimizationRun{}
+	recorded := m.runs[communityId]
+	for i := len(recorded) - 1; i >= 0 && len(runs) < limit; i-- {
+		runs = append(runs, recorded[i])
+	}
+	return runs, nil
+}
+
+func (m *MemoryStore) communityByKey(region model.Region, realm, name string) *memoryCommunity {
+	for _, c := range m.communities {
+		if c.region == region && c.realm == realm && c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// members lists the community's members that are still in the guild.
+func (m *MemoryStore) members(communityId string) []*memoryMembership {
+	members := []*memoryMembership{}
+	for _, ms := range m.memberships {
+		if ms.communityId == communityId && !ms.departed {
+			members = append(members, ms)
+		}
+	}
+	slices.SortFunc(members, func(a, b *memoryMembership) int { return cmp.Compare(a.battletag, b.battletag) })
+	return members
+}
+
+// userMemberships lists the user's memberships, most recently joined first.
+func (m *MemoryStore) userMemberships(battletag string) []*memoryMembership {
+	memberships := []*memoryMembership{}
+	for _, ms := range m.memberships {
+		if ms.battletag == battletag {
+			memberships = append(memberships, ms)
+		}
+	}
+	slices.SortFunc(memberships, func(a, b *memoryMembership) int { return cmp.Compare(b.joined, a.joined) })
+	return memberships
+}
+
+func (m *MemoryStore) snapshotAssignments(communityId string, origin *model.RevisionOrigin) {
+	m.revisions[communityId] = append(m.revisions[communityId], model.AssignmentRevision{
+		Revision:       len(m.revisions[communityId]) + 1,
+		RevisionOrigin: *origin,
+		CreatedAt:      time.Now(),
+		Assignments:    m.communityAssignments(communityId),
+	})
+}
+
+func (m *MemoryStore) hasSession(battletag string) bool {
+	for _, s := range m.sessions {
+		if s.battletag == battletag {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemoryStore) recordAuditEvent(communityId string, event *model.AuditEvent) {
+	m.nextAuditId++
+	recorded := *event
+	recorded.Id = m.nextAuditId
+	recorded.CreatedAt = time.Now()
+	m.audit[communityId] = append(m.audit[communityId], recorded)
+}
+
+func (m *MemoryStore) plotData(key memberKey) map[model.PlotKey]int {
+	plotData := make(map[model.PlotKey]int, len(m.mappings[key]))
+	for plot, priority := range m.mappings[key] {
+		plotData[plot] = priority
+	}
+	return plotData
+}
+
+func (m *MemoryStore) neighborWishes(key memberKey) []string {
+	if len(m.wishes[key]) == 0 {
+		return nil
+	}
+	return slices.Clone(m.wishes[key])
+}
+
+func (m *MemoryStore) catalog() []model.Plot {
+	plots := make([]model.Plot, 0, len(m.plots))
+	for _, p := range m.plots {
+		plots = append(plots, p)
+	}
+	slices.SortFunc(plots, func(a, b model.Plot) int { return cmp.Compare(a.Id, b.Id) })
+	return plots
+}
+
+func (m *MemoryStore) communityAssignments(communityId string) []model.Assignment {
+	assignments := []model.Assignment{}
+	for key, a := range m.assignments {
+		if key.communityId == communityId {
+			assignments = append(assignments, a)
+		}
+	}
+	slices.SortFunc(assignments, func(a, b model.Assignment) int {
+		return cmp.Or(cmp.Compare(a.Neighborhood, b.Neighborhood), cmp.Compare(a.Plot, b.Plot))
+	})
+	return assignments
+}
+
+func (m *MemoryStore) constraints(communityId string) *model.PlotConstraints {
+	constraints := &model.PlotConstraints{Pins: []model.Pin{}, Reserved: []model.ReservedPlot{}}
+	for btag, slot := range m.pins[communityId] {
+		ms, exists := m.memberships[memberKey{btag, communityId}]
+		if !exists {
+			continue
+		}
+		constraints.Pins = append(constraints.Pins, model.Pin{
+			Battletag:    btag,
+			Character:    ms.char,
+			Neighborhood: slot.Neighborhood,
+			Plot:         slot.Plot,
+		})
+	}
+	for _, r := range m.reserved[communityId] {
+		constraints.Reserved = append(constraints.Reserved, r)
+	}
+
+	slices.SortFunc(constraints.Pins, func(a, b model.Pin) int {
+		return cmp.Or(cmp.Compare(a.Neighborhood, b.Neighborhood), cmp.Compare(a.Plot, b.Plot))
+	})
+	slices.SortFunc(constraints.Reserved, func(a, b model.ReservedPlot) int {
+		return cmp.Or(cmp.Compare(a.Neighborhood, b.Neighborhood), cmp.Compare(a.Plot, b.Plot))
+	})
+	return constraints
+}
+
+// checkSlot mirrors the neighborhood check and plot catalog foreign key of the schema.
+func (m *MemoryStore) checkSlot(neighborhood, plot int) error {
+	if neighborhood < 1 {
+		return fmt.Errorf("invalid neighborhood %d", neighborhood)
+	}
+	if _, exists := m.plots[plot]; !exists {
+		return fmt.Errorf("plot %d is not in the plot catalog", plot)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/sbraitsch/plotter/internal/model"
+)
+
+func (s *StorageClient) GetPlotConstraints(ctx context.Context, communityId string) (*model.PlotConstraints, error) {
+	constraints := &model.PlotConstraints{Pins: []model.Pin{}, Reserved: []model.ReservedPlot{}}
+
+	pinRows, err := s.db.Query(ctx, `
+		SELECT p.battletag, COALESCE(m.char, ''), p.neighborhood, p.plot_id
+		FROM plot_pins p
+		JOIN memberships m ON m.battletag = p.battletag AND m.community_id = p.community_id
+		WHERE p.community_id = $1
+		ORDER BY p.neighborhood, p.plot_id
+	`, communityId)
+	if err != nil {
+		log.Printf("Pin query failed: %v", err)
+		return nil, err
+	}
+	defer pinRows.Close()
+
+	for pinRows.Next() {
+		var p model.Pin
+		if err := pinRows.Scan(&p.Battletag, &p.Character, &p.Neighborhood, &p.Plot); err != nil {
+			return nil, err
+		}
+		constraints.Pins = append(constraints.Pins, p)
+	}
+	if err := pinRows.Err(); err != nil {
+		return nil, err
+	}
+
+	reservedRows, err := s.db.Query(ctx, `
+		SELECT neighborhood, plot_id, status, note
+		FROM reserved_plots
+		WHERE community_id = $1
+		ORDER BY neighborhood, plot_id
+	`, communityId)
+	if err != nil {
+		log.Printf("Reserved plot query failed: %v", err)
+		return nil, err
+	}
+	defer reservedRows.Close()
+
+	for reservedRows.Next() {
+		var r model.ReservedPlot
+		var note sql.NullString
+		if err := reservedRows.Scan(&r.Neighborhood, &r.Plot, &r.Status, &note); err != nil {
+			return nil, err
+		}
+		r.Note = note.String
+		constraints.Reserved = append(constraints.Reserved, r)
+	}
+	if err := reservedRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return constraints, nil
+}
+
+func (s *StorageClient) SetPin(ctx context.Context, communityId string, pin *model.Pin, event *model.AuditEvent) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin pin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// a plot can only hold one pin, so whoever was pinned there before is released
+	_, err = tx.Exec(ctx, `
+		DELETE FROM plot_pins
+		WHERE community_id = $1 AND neighborhood = $2 AND plot_id = $3
+	`, communityId, pin.Neighborhood, pin.Plot)
+	if err != nil {
+		log.Printf("Failed to release plot %d for pin: %v", pin.Plot, err)
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO plot_pins (community_id, battletag, neighborhood, plot_id)
+		SELECT $1, battletag, $3, $4
+		FROM memberships
+		WHERE battletag = $2 AND community_id = $1
+		ON CONFLICT (community_id, battletag)
+		DO UPDATE SET
+			neighborhood = EXCLUDED.neighborhood,
+			plot_id = EXCLUDED.plot_id
+	`, communityId, pin.Battletag, pin.Neighborhood, pin.Plot)
+	if err != nil {
+		log.Printf("Failed to pin %s to plot %d: %v", pin.Battletag, pin.Plot, err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s is not a member of this community", pin.Battletag)
+	}
+	if err := claimVersion(ctx, tx, communityId); err != nil {
+		return err
+	}
+	if err := ensureNeighborhoods(ctx, tx, communityId, pin.Neighborhood); err != nil {
+		return err
+	}
+	if err := recordAuditEvent(ctx, tx, communityId, event); err != nil {
+		log.Printf("Failed to record pin of %s: %v", pin.Battletag, err)
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit pin transaction: %v", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *StorageClient) RemovePin(ctx context.Context, communityId string, battletag string, event *model.AuditEvent) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin pin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM plot_pins
+		WHERE community_id = $1 AND battletag = $2
+	`, communityId, battletag)
+	if err != nil {
+		log.Printf("Failed to remove pin for %s: %v", battletag, err)
+		return err
+	}
+	if err := claimVersion(ctx, tx, communityId); err != nil {
+		return err
+	}
+	if err := recordAuditEvent(ctx, tx, communityId, event); err != nil {
+		log.Printf("Failed to record unpinning %s: %v", battletag, err)
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit pin transaction: %v", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *StorageClient) ReservePlot(ctx context.Context, communityId string, reserved *model.ReservedPlot, event *model.AuditEvent) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin reservation transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO reserved_plots (community_id, neighborhood, plot_id, status, note)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		ON CONFLICT (community_id, neighborhood, plot_id)
+		DO UPDATE SET
+			status = EXCLUDED.status,
+			note = EXCLUDED.note
+	`, communityId, reserved.Neighborhood, reserved.Plot, reserved.Status, reserved.Note)
+	if err != nil {
+		log.Printf("Failed to reserve plot %d: %v", reserved.Plot, err)
+		return err
+	}
+	if err := claimVersion(ctx, tx, communityId); err != nil {
+		return err
+	}
+	if err := recordAuditEvent(ctx, tx, communityId, event); err != nil {
+		log.Printf("Failed to record reservation of plot %d: %v", reserved.Plot, err)
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit reservation transaction: %v", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *StorageClient) ReleasePlot(ctx context.Context, communityId string, neighborhood, plot int, event *model.AuditEvent) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin reservation transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM reserved_plots
+		WHERE community_id = $1 AND neighborhood = $2 AND plot_id = $3
+	`, communityId, neighborhood, plot)
+	if err != nil {
+		log.Printf("Failed to release plot %d: %v", plot, err)
+		return err
+	}
+	if err := claimVersion(ctx, tx, communityId); err != nil {
+		return err
+	}
+	if err := recordAuditEvent(ctx, tx, communityId, event); err != nil {
+		log.Printf("Failed to record release of plot %d: %v", plot, err)
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit reservation transaction: %v", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+	"log"
+
+	"github.com/sbraitsch/plotter/internal/model"
+)
+
+func (s *StorageClient) GetPlots(ctx context.Context) ([]model.Plot, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, label, x_coord, y_coord, size, plot_type, tags
+		FROM plots
+		ORDER BY id
+	`)
+	if err != nil {
+		log.Printf("Plot catalog query failed: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	plots := []model.Plot{}
+
+	for rows.Next() {
+		var p model.Plot
+		if err := rows.Scan(&p.Id, &p.Label, &p.X, &p.Y, &p.Size, &p.Type, &p.Tags); err != nil {
+			return nil, err
+		}
+		plots = append(plots, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error reading plot catalog from database: %v", err)
+		return nil, err
+	}
+
+	return plots, nil
+}
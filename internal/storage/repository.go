@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sbraitsch/plotter/internal/model"
+	"golang.org/x/oauth2"
+)
+
+// ErrNotFound is returned when a lookup matches no row.
+var ErrNotFound = pgx.ErrNoRows
+
+// ErrRankRequirement is returned when none of the user's characters has the rank to join.
+var ErrRankRequirement = errors.New("Rank requirement not fulfilled.")
+
+// ErrOutranked is returned when an officer tries to remove a member of the same or a higher rank.
+var ErrOutranked = errors.New("Only members of a lower rank can be removed.")
+
+// ErrVersionConflict is returned when a community changed since the version an edit was based on.
+var ErrVersionConflict = errors.New("The community was changed in the meantime.")
+
+type UserRepository interface {
+	GetUserByToken(ctx context.Context, token string, communityId string) (*model.User, error)
+	GetMemberships(ctx context.Context, battletag string) ([]model.Membership, error)
+	RegisterUser(ctx context.Context, battletag string, region model.Region, token *oauth2.Token) error
+	RegisterManualUsers(ctx context.Context, assignments []model.Assignment, communityID string) error
+	SetNote(ctx context.Context, user *model.User, note string, event *model.AuditEvent) error
+	GetCharacters(ctx context.Context, battletag string, communityId string) ([]string, error)
+	SetCharacter(ctx context.Context, user *model.User, char string, event *model.AuditEvent) (string, error)
+	UpdateUserToken(ctx context.Context, battletag string, token *oauth2.Token) error
+	GetRefreshableUsers(ctx context.Context, expiringBefore time.Time) ([]model.User, error)
+	ClearRefreshToken(ctx context.Context, battletag string) error
+}
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, battletag string, userAgent string) (string, error)
+	TouchSession(ctx context.Context, sessionId int) error
+	GetSessions(ctx context.Context, battletag string) ([]model.Session, error)
+	DeleteSession(ctx context.Context, battletag string, sessionId int) error
+	DeleteSessions(ctx context.Context, battletag string) error
+}
+
+type CommunityRepository interface {
+	GetCommunity(ctx context.Context, communityId string) (*model.Community, int, error)
+	GetCommunitySize(ctx context.Context, communityId string) (int, error)
+	GetCommunityData(ctx context.Context, user *model.User) (*model.CommunityData, error)
+	GetFullCommunityData(ctx context.Context, user *model.User) (*model.FullCommunityData, error)
+	GetCommunitySettings(ctx context.Context, communityId string) (*model.Settings, error)
+	InsertGuilds(ctx context.Context, guilds []model.Community) ([]model.Community, error)
+	GetCommunities(ctx context.Context) ([]model.Community, error)
+	JoinCommunity(
+		ctx context.Context,
+		user *model.User,
+		requiredRank int,
+		communityId string,
+		profile *model.WowProfile,
+		roster *model.Roster,
+		event *model.AuditEvent,
+	) (*model.JoinedCommunity, error)
+	SetOfficerRank(ctx context.Context, communityId string, req *model.CommunityRankRequest, event *model.AuditEvent) error
+	EnsureNeighborhoods(ctx context.Context, communityId string, count int) error
+	GetCommunityStatus(ctx context.Context, communityId string) (model.CommunityStatus, error)
+	TransitionCommunity(ctx context.Context, communityId string, to model.CommunityStatus, event *model.AuditEvent) error
+	GetCommunityVersion(ctx context.Context, communityId string) (int64, error)
+	SetPreferenceDeadline(ctx context.Context, communityId string, deadline *time.Time, event *model.AuditEvent) error
+	GetOverdueCommunities(ctx context.Context, now time.Time) ([]model.Community, error)
+	MarkDeadlineLocked(ctx context.Context, communityId string) error
+	SyncRoster(ctx context.Context, communityId string, roster *model.Roster) ([]model.RosterChange, error)
+	RemoveMember(
+		ctx context.Context,
+		communityId string,
+		battletag string,
+		event *model.AuditEvent,
+		origin *model.RevisionOrigin,
+		blockedUntil time.Time,
+	) error
+}
+
+type JoinRequestRepository interface {
+	RequestJoin(
+		ctx context.Context,
+		user *model.User,
+		communityId string,
+		profile *model.WowProfile,
+		roster *model.Roster,
+		event *model.AuditEvent,
+	) (*model.JoinRequest, error)
+	GetJoinRequests(ctx context.Context, communityId string) ([]model.JoinRequest, error)
+	DecideJoinRequest(ctx context.Context, communityId string, battletag string, event *model.AuditEvent) error
+}
+
+type AuditRepository interface {
+	GetAuditEvents(ctx context.Context, communityId string, filter *model.AuditFilter) ([]model.AuditEvent, error)
+}
+
+type MappingRepository interface {
+	SavePlotMappings(ctx context.Context, user *model.User, mappings map[model.PlotKey]int, event *model.AuditEvent) error
+	SaveNeighborWishes(ctx context.Context, user *model.User, neighbors []string, event *model.AuditEvent) error
+}
+
+type AssignmentRepository interface {
+	GetAssignments(ctx context.Context, communityId string) ([]model.Assignment, error)
+	SetAssignment(
+		ctx context.Context,
+		req *model.SingleAssignmentRequest,
+		communityId string,
+		origin *model.RevisionOrigin,
+		event *model.AuditEvent,
+	) error
+	PersistAndLock(
+		ctx context.Context,
+		assignments []model.Assignment,
+		communityId string,
+		allowed func(model.CommunityStatus) bool,
+		origin *model.RevisionOrigin,
+		event *model.AuditEvent,
+	) error
+	GetRevisions(ctx context.Context, communityId string) ([]model.AssignmentRevision, error)
+	GetRevision(ctx context.Context, communityId string, revision int) (*model.AssignmentRevision, error)
+}
+
+type ConstraintRepository interface {
+	GetPlotConstraints(ctx context.Context, communityId string) (*model.PlotConstraints, error)
+	SetPin(ctx context.Context, communityId string, pin *model.Pin, event *model.AuditEvent) error
+	RemovePin(ctx context.Context, communityId string, battletag string, event *model.AuditEvent) error
+	ReservePlot(ctx context.Context, communityId string, reserved *model.ReservedPlot, event *model.AuditEvent) error
+	ReleasePlot(ctx context.Context, communityId string, neighborhood, plot int, event *model.AuditEvent) error
+}
+
+type PlotRepository interface {
+	GetPlots(ctx context.Context) ([]model.Plot, error)
+}
+
+type RunRepository interface {
+	RecordOptimizationRun(ctx context.Context, communityId string, run *model.OptimizationRun) error
+	GetOptimizationRuns(ctx context.Context, communityId string, limit int) ([]model.OptimizationRun, error)
+}
+
+// Store is everything the services need from persistence.
+// StorageClient implements it on Postgres, MemoryStore in memory.
+type Store interface {
+	UserRepository
+	SessionRepository
+	CommunityRepository
+	JoinRequestRepository
+	AuditRepository
+	MappingRepository
+	AssignmentRepository
+	ConstraintRepository
+	PlotRepository
+	RunRepository
+}
+
+var (
+	_ Store = (*StorageClient)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sbraitsch/plotter/internal/model"
+)
+
+// snapshotAssignments stores the assignments of a community as its next revision.
+// It runs inside the transaction that changed them, so no change goes unrecorded.
+func snapshotAssignments(ctx context.Context, tx pgx.Tx, communityId string, origin *model.RevisionOrigin) error {
+	// serializes revision numbers per community
+	_, err := tx.Exec(ctx, `SELECT 1 FROM communities WHERE id = $1 FOR UPDATE`, communityId)
+	if err != nil {
+		return fmt.Errorf("failed to lock community for revision: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO assignment_revisions (community_id, revision, source, created_by, restored_from, assignments)
+		SELECT
+			$1,
+			COALESCE((SELECT MAX(revision) FROM assignment_revisions WHERE community_id = $1), 0) + 1,
+			$2,
+			NULLIF($3, ''),
+			NULLIF($4, 0),
+			COALESCE((
+				SELECT jsonb_agg(jsonb_build_object(
+					'char', char,
+					'btag', battletag,
+					'neighborhood', neighborhood,
+					'plot', plot_id,
+					'score', plot_score
+				) ORDER BY neighborhood, plot_id)
+				FROM assignments
+				WHERE community_id = $1
+			), '[]')
+	`, communityId, origin.Source, origin.CreatedBy, origin.RestoredFrom)
+	if err != nil {
+		return fmt.Errorf("failed to store assignment revision: %w", err)
+	}
+	return nil
+}
+
+// GetRevisions lists the revisions of a community, newest first, without their assignments.
+func (s *StorageClient) GetRevisions(ctx context.Context, communityId string) ([]model.AssignmentRevision, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT revision, source, COALESCE(created_by, ''), COALESCE(restored_from, 0), created_at
+		FROM assignment_revisions
+		WHERE community_id = $1
+		ORDER BY revision DESC
+	`, communityId)
+	if err != nil {
+		log.Printf("Revision query failed: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []model.AssignmentRevision{}
+
+	for rows.Next() {
+		var r model.AssignmentRevision
+		if err := rows.Scan(&r.Revision, &r.Source, &r.CreatedBy, &r.RestoredFrom, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error reading revisions from database: %v", err)
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (s *StorageClient) GetRevision(ctx context.Context, communityId string, revision int) (*model.AssignmentRevision, error) {
+	r := &model.AssignmentRevision{}
+	var assignments []byte
+	err := s.db.QueryRow(ctx, `
+		SELECT revision, source, COALESCE(created_by, ''), COALESCE(restored_from, 0), created_at, assignments
+		FROM assignment_revisions
+		WHERE community_id = $1 AND revision = $2
+	`, communityId, revision).Scan(&r.Revision, &r.Source, &r.CreatedBy, &r.RestoredFrom, &r.CreatedAt, &assignments)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(assignments, &r.Assignments); err != nil {
+		return nil, fmt.Errorf("failed to decode revision %d: %w", revision, err)
+	}
+	return r, nil
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"log"
+
+	"github.com/sbraitsch/plotter/internal/model"
+)
+
+func (s *StorageClient) RecordOptimizationRun(ctx context.Context, communityId string, run *model.OptimizationRun) error {
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO optimization_runs (community_id, objective, stable, persisted, stats, created_by)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+	`, communityId, run.Objective, run.Stable, run.Persisted, run.Stats, run.CreatedBy)
+	if err != nil {
+		log.Printf("Failed to record optimization run for community %s: %v", communityId, err)
+		return err
+	}
+	return nil
+}
+
+func (s *StorageClient) GetOptimizationRuns(ctx context.Context, communityId string, limit int) ([]model.OptimizationRun, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, objective, stable, persisted, stats, COALESCE(created_by, ''), created_at
+		FROM optimization_runs
+		WHERE community_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, communityId, limit)
+	if err != nil {
+		log.Printf("Optimization run query failed: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []model.OptimizationRun{}
+
+	for rows.Next() {
+		var run model.OptimizationRun
+		if err := rows.Scan(&run.Id, &run.Objective, &run.Stable, &run.Persisted, &run.Stats, &run.CreatedBy, &run.CreatedAt); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error reading optimization runs from database: %v", err)
+		return nil, err
+	}
+
+	return runs, nil
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/sbraitsch/plotter/internal/model"
+)
+
+// hashToken is how session tokens are stored, so a leaked table can't be replayed.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *StorageClient) CreateSession(ctx context.Context, battletag string, userAgent string) (string, error) {
+	// sessions that ran out are only kept until the next login
+	_, err := s.db.Exec(ctx, `DELETE FROM sessions WHERE battletag = $1 AND expires_at <= NOW()`, battletag)
+	if err != nil {
+		log.Printf("Failed to clean up expired sessions of %s: %v", battletag, err)
+		return "", err
+	}
+
+	sessionToken := uuid.New().String()
+	_, err = s.db.Exec(ctx, `
+		INSERT INTO sessions (token_hash, battletag, user_agent, expires_at)
+		VALUES ($1, $2, $3, NOW() + make_interval(secs => $4))
+	`, hashToken(sessionToken), battletag, userAgent, model.SESSION_IDLE_TTL.Seconds())
+	if err != nil {
+		log.Printf("Failed to create session for %s: %v", battletag, err)
+		return "", err
+	}
+	return sessionToken, nil
+}
+
+// TouchSession slides the expiry of a session that is in use, up to its maximum age.
+func (s *StorageClient) TouchSession(ctx context.Context, sessionId int) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE sessions
+		SET last_seen_at = NOW(),
+			expires_at = LEAST(NOW() + make_interval(secs => $2), created_at + make_interval(secs => $3))
+		WHERE id = $1 AND last_seen_at < NOW() - make_interval(secs => $4)
+	`, sessionId, model.SESSION_IDLE_TTL.Seconds(), model.SESSION_MAX_AGE.Seconds(), model.SESSION_RENEW_INTERVAL.Seconds())
+	if err != nil {
+		log.Printf("Failed to renew session %d: %v", sessionId, err)
+		return err
+	}
+	return nil
+}
+
+func (s *StorageClient) GetSessions(ctx context.Context, battletag string) ([]model.Session, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id, user_agent, created_at, last_seen_at, expires_at
+		FROM sessions
+		WHERE battletag = $1 AND expires_at > NOW()
+		ORDER BY last_seen_at DESC
+	`, battletag)
+	if err != nil {
+		log.Printf("Session query failed: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []model.Session{}
+
+	for rows.Next() {
+		var session model.Session
+		if err := rows.Scan(&session.Id, &session.UserAgent, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Error reading sessions from database: %v", err)
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (s *StorageClient) DeleteSession(ctx context.Context, battletag string, sessionId int) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM sessions WHERE id = $1 AND battletag = $2`, sessionId, battletag)
+	if err != nil {
+		log.Printf("Failed to delete session %d: %v", sessionId, err)
+		return err
+	}
+	return nil
+}
+
+// DeleteSessions logs the user out everywhere.
+func (s *StorageClient) DeleteSessions(ctx context.Context, battletag string) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM sessions WHERE battletag = $1`, battletag)
+	if err != nil {
+		log.Printf("Failed to delete sessions of %s: %v", battletag, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sbraitsch/plotter/internal/model"
+)
+
+// lockCommunityStatus reads the status of a community and holds its row until the transaction
+// ends, so the status can't change under a write that depends on it.
+func lockCommunityStatus(ctx context.Context, tx pgx.Tx, communityId string) (model.CommunityStatus, error) {
+	var status model.CommunityStatus
+	err := tx.QueryRow(ctx, `SELECT status FROM communities WHERE id = $1 FOR UPDATE`, communityId).Scan(&status)
+	if err != nil {
+		log.Printf("Failed to read status of community %s: %v", communityId, err)
+		return "", err
+	}
+	return status, nil
+}
+
+func (s *StorageClient) GetCommunityStatus(ctx context.Context, communityId string) (model.CommunityStatus, error) {
+	var status model.CommunityStatus
+	err := s.db.QueryRow(ctx, `SELECT status FROM communities WHERE id = $1`, communityId).Scan(&status)
+	if err != nil {
+		log.Printf("Failed to read status of community %s: %v", communityId, err)
+		return "", err
+	}
+	return status, nil
+}
+
+// TransitionCommunity moves a community to another status, if its lifecycle allows it.
+func (s *StorageClient) TransitionCommunity(ctx context.Context, communityId string, to model.CommunityStatus, event *model.AuditEvent) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin status transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	status, err := lockCommunityStatus(ctx, tx, communityId)
+	if err != nil {
+		return err
+	}
+	if !status.CanBecome(to) {
+		return &model.StatusError{Status: status, To: to}
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE communities SET status = $2 WHERE id = $1`, communityId, to)
+	if err != nil {
+		log.Printf("Failed to move community %s to %s: %v", communityId, to, err)
+		return err
+	}
+	if err := claimVersion(ctx, tx, communityId); err != nil {
+		return err
+	}
+	if err := recordAuditEvent(ctx, tx, communityId, event); err != nil {
+		log.Printf("Failed to record status of community %s: %v", communityId, err)
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit status transaction: %w", err)
+	}
+	return nil
+}
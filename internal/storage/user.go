@@ -5,40 +5,59 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"strings"
+	"time"
 
-	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/sbraitsch/plotter/internal/model"
 	"golang.org/x/oauth2"
 )
 
-func (s *StorageClient) GetUserByToken(ctx context.Context, token string) (*model.User, error) {
+// GetUserByToken resolves a session into its user, acting in the given community.
+// Without a community the most recently joined one is used.
+func (s *StorageClient) GetUserByToken(ctx context.Context, token string, communityId string) (*model.User, error) {
 	var (
 		battletag, char, note, communityName, communityID, realm, accessToken sql.NullString
+		region, communityRegion, refreshToken, status                         sql.NullString
 		officerRank, communityRank                                            sql.NullInt32
-		locked, finalized                                                     sql.NullBool
+		sessionId                                                             int
+		departed                                                              bool
 		expiry                                                                sql.NullTime
+		deadline                                                              *time.Time
 	)
 
 	err := s.db.QueryRow(ctx,
 		`SELECT
 			u.battletag,
-			u.char,
-			u.note,
-			u.community_id,
+			m.char,
+			m.note,
+			m.community_id,
 			c.name AS community_name,
 			c.officer_rank,
-			c.locked,
-			c.finalized,
+			c.status,
+			c.preference_deadline,
 			c.realm,
-			u.community_rank,
+			m.community_rank,
 			u.access_token,
-			u.expiry
-		FROM users u
+			u.refresh_token,
+			u.expiry,
+			u.region,
+			c.region AS community_region,
+			s.id,
+			m.departed_at IS NOT NULL AS departed
+		FROM sessions s
+		JOIN users u
+			ON u.battletag = s.battletag
+		LEFT JOIN LATERAL (
+			SELECT *
+			FROM memberships
+			WHERE battletag = u.battletag AND ($2 = '' OR community_id::text = $2)
+			ORDER BY joined_at DESC
+			LIMIT 1
+		) m ON TRUE
 		LEFT JOIN communities c
-			ON u.community_id = c.id
-		WHERE u.session_id = $1`,
-		token,
+			ON m.community_id = c.id
+		WHERE s.token_hash = $1 AND s.expires_at > NOW()`,
+		hashToken(token), communityId,
 	).Scan(
 		&battletag,
 		&char,
@@ -46,12 +65,17 @@ func (s *StorageClient) GetUserByToken(ctx context.Context, token string) (*mode
 		&communityID,
 		&communityName,
 		&officerRank,
-		&locked,
-		&finalized,
+		&status,
+		&deadline,
 		&realm,
 		&communityRank,
 		&accessToken,
+		&refreshToken,
 		&expiry,
+		&region,
+		&communityRegion,
+		&sessionId,
+		&departed,
 	)
 
 	if err != nil {
@@ -63,41 +87,159 @@ func (s *StorageClient) GetUserByToken(ctx context.Context, token string) (*mode
 		Char:      char.String,
 		Note:      note.String,
 		Community: model.UserCommunity{
-			Id:          communityID.String,
-			Name:        communityName.String,
-			OfficerRank: int(officerRank.Int32),
-			Locked:      locked.Bool,
-			Realm:       realm.String,
-			Finalized:   finalized.Bool,
+			Id:                 communityID.String,
+			Name:               communityName.String,
+			OfficerRank:        int(officerRank.Int32),
+			Status:             model.CommunityStatus(status.String),
+			Realm:              realm.String,
+			Region:             model.Region(communityRegion.String),
+			PreferenceDeadline: deadline,
 		},
 		CommunityRank: int(communityRank.Int32),
 		AccessToken:   accessToken.String,
+		RefreshToken:  refreshToken.String,
 		Expiry:        expiry.Time,
+		Region:        model.Region(region.String),
+		SessionId:     sessionId,
+		Departed:      departed,
 	}
 
 	return user, nil
 }
 
-func (s *StorageClient) RegisterUser(ctx context.Context, battletag string, token *oauth2.Token) (string, error) {
-	sessionToken := uuid.New().String()
-	_, err := s.db.Exec(ctx, `INSERT INTO users(battletag, session_id, access_token, expiry)
-                      VALUES($1, $2, $3, $4)
+func (s *StorageClient) RegisterUser(ctx context.Context, battletag string, region model.Region, token *oauth2.Token) error {
+	_, err := s.db.Exec(ctx, `INSERT INTO users(battletag, access_token, refresh_token, expiry, region)
+                      VALUES($1, $2, NULLIF($3, ''), $4, $5)
                       ON CONFLICT(battletag) DO UPDATE
-                      SET session_id=$2, access_token=$3, expiry=$4`,
-		battletag, sessionToken, token.AccessToken, token.Expiry)
+                      SET access_token=$2, refresh_token=NULLIF($3, ''), expiry=$4, region=$5`,
+		battletag, token.AccessToken, token.RefreshToken, token.Expiry, region)
 
 	if err != nil {
 		log.Printf("Failed to insert new user: %v", err)
-		return "", err
+		return err
+	}
+	return nil
+}
+
+// UpdateUserToken stores a refreshed token. The refresh token is kept unless a new one was issued.
+func (s *StorageClient) UpdateUserToken(ctx context.Context, battletag string, token *oauth2.Token) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE users
+		SET access_token = $2,
+			refresh_token = COALESCE(NULLIF($3, ''), refresh_token),
+			expiry = $4
+		WHERE battletag = $1
+	`, battletag, token.AccessToken, token.RefreshToken, token.Expiry)
+	if err != nil {
+		log.Printf("Failed to update token of %s: %v", battletag, err)
+		return err
 	}
-	return sessionToken, nil
+	return nil
+}
+
+// ClearRefreshToken drops a refresh token Battle.net no longer accepts.
+func (s *StorageClient) ClearRefreshToken(ctx context.Context, battletag string) error {
+	_, err := s.db.Exec(ctx, `UPDATE users SET refresh_token = NULL WHERE battletag = $1`, battletag)
+	if err != nil {
+		log.Printf("Failed to clear refresh token of %s: %v", battletag, err)
+		return err
+	}
+	return nil
+}
+
+// GetRefreshableUsers lists users holding a refresh token whose access token expires before the given time.
+func (s *StorageClient) GetRefreshableUsers(ctx context.Context, expiringBefore time.Time) ([]model.User, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT battletag, region, refresh_token, expiry
+		FROM users
+		WHERE refresh_token IS NOT NULL AND expiry < $1
+	`, expiringBefore)
+	if err != nil {
+		log.Printf("Refreshable user query failed: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []model.User{}
+
+	for rows.Next() {
+		var u model.User
+		if err := rows.Scan(&u.Battletag, &u.Region, &u.RefreshToken, &u.Expiry); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// GetMemberships lists every community the user belongs to, most recently joined first.
+func (s *StorageClient) GetMemberships(ctx context.Context, battletag string) ([]model.Membership, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT
+			c.id, c.name, c.realm, c.region, c.status,
+			c.preference_deadline,
+			COALESCE(m.char, ''),
+			COALESCE(m.community_rank, 100) <= c.officer_rank AND m.departed_at IS NULL,
+			m.departed_at IS NOT NULL
+		FROM memberships m
+		JOIN communities c ON c.id = m.community_id
+		WHERE m.battletag = $1
+		ORDER BY m.joined_at DESC
+	`, battletag)
+	if err != nil {
+		log.Printf("Membership query failed: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := []model.Membership{}
+	now := time.Now()
+
+	for rows.Next() {
+		var m model.Membership
+		var deadline *time.Time
+		c := &m.Community
+		if err := rows.Scan(&c.Id, &c.Name, &c.Realm, &c.Region, &c.Status, &deadline, &m.Char, &m.IsAdmin, &m.Departed); err != nil {
+			return nil, err
+		}
+		c.Preferences = model.NewPreferenceWindow(deadline, now)
+		memberships = append(memberships, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return memberships, nil
 }
 
 func (s *StorageClient) RegisterManualUsers(ctx context.Context, assignments []model.Assignment, communityID string) error {
-	sessionToken := uuid.New().String()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
 
+	if err := registerManualUsers(ctx, tx, assignments, communityID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit transaction: %v", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// registerManualUsers adds accounts and memberships for assigned members the community
+// doesn't know yet. It runs inside the caller's transaction, which may hold the community
+// row: inserting memberships waits on that lock from any other connection.
+func registerManualUsers(ctx context.Context, tx pgx.Tx, assignments []model.Assignment, communityID string) error {
 	var memberRank int
-	err := s.db.QueryRow(ctx, `SELECT member_rank FROM communities WHERE id = $1`, communityID).Scan(&memberRank)
+	err := tx.QueryRow(ctx, `SELECT member_rank FROM communities WHERE id = $1`, communityID).Scan(&memberRank)
 	if err != nil {
 		return fmt.Errorf("failed to fetch community member_rank: %w", err)
 	}
@@ -105,11 +247,18 @@ func (s *StorageClient) RegisterManualUsers(ctx context.Context, assignments []m
 		if a.Battletag == "" {
 			continue
 		}
-		_, err := s.db.Exec(ctx, `
-			INSERT INTO users (battletag, char, community_id, community_rank, access_token, expiry, session_id)
-			VALUES ($1, $2, $3, $4, gen_random_uuid()::text, NOW() + INTERVAL '24 hours', $5)
+		_, err := tx.Exec(ctx, `
+			INSERT INTO users (battletag, access_token, expiry)
+			VALUES ($1, gen_random_uuid()::text, NOW() + INTERVAL '24 hours')
 			ON CONFLICT (battletag) DO NOTHING
-		`, a.Battletag, a.Character, communityID, memberRank, sessionToken)
+		`, a.Battletag)
+		if err == nil {
+			_, err = tx.Exec(ctx, `
+				INSERT INTO memberships (battletag, community_id, char, community_rank, manual)
+				VALUES ($1, $2, $3, $4, true)
+				ON CONFLICT (battletag, community_id) DO NOTHING
+			`, a.Battletag, communityID, a.Character, memberRank)
+		}
 
 		if err != nil {
 			log.Printf("Failed to insert user %s: %v", a.Battletag, err)
@@ -119,65 +268,118 @@ func (s *StorageClient) RegisterManualUsers(ctx context.Context, assignments []m
 	return nil
 }
 
-func (s *StorageClient) SetNote(ctx context.Context, user *model.User, note string) error {
-	_, err := s.db.Exec(ctx, `UPDATE users SET note=$1 WHERE battletag=$2`, note, user.Battletag)
+func (s *StorageClient) SetNote(ctx context.Context, user *model.User, note string, event *model.AuditEvent) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `UPDATE memberships SET note=$1 WHERE battletag=$2 AND community_id=$3`,
+		note, user.Battletag, user.Community.Id)
 	if err != nil {
 		log.Printf("failed to set user note: %v", err)
 		return err
 	}
+	if err := recordAuditEvent(ctx, tx, user.Community.Id, event); err != nil {
+		log.Printf("failed to record note of %s: %v", user.Battletag, err)
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit transaction: %v", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
-func (s *StorageClient) SavePlotMappings(ctx context.Context, user *model.User, mappings map[int]int) error {
+func (s *StorageClient) SavePlotMappings(ctx context.Context, user *model.User, mappings map[model.PlotKey]int, event *model.AuditEvent) error {
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	plotIDs := make([]any, 0, len(mappings))
-	for plotID := range mappings {
-		plotIDs = append(plotIDs, plotID)
+	status, err := lockCommunityStatus(ctx, tx, user.Community.Id)
+	if err != nil {
+		return err
+	}
+	if !status.PreferencesOpen() {
+		return &model.StatusError{Status: status, Action: "change preferences"}
 	}
 
-	if len(plotIDs) > 0 {
-		placeholders := make([]string, 0, len(mappings))
-		args := []any{user.Battletag}
-		idx := 2
-		for plotID := range mappings {
-			placeholders = append(placeholders, fmt.Sprintf("$%d", idx))
-			args = append(args, plotID)
-			idx++
-		}
-
-		query := fmt.Sprintf(
-			`DELETE FROM plot_mappings WHERE battletag=$1 AND plot_id NOT IN (%s)`,
-			strings.Join(placeholders, ","),
-		)
-		_, err = tx.Exec(ctx, query, args...)
-		if err != nil {
-			log.Printf("failed to remove mappings: %v", err)
-			return err
-		}
-	} else {
-		_, err := tx.Exec(ctx, `DELETE FROM plot_mappings WHERE battletag=$1`, user.Battletag)
-		if err != nil {
-			return err
-		}
+	// replace the whole set so reordered priorities never collide on (battletag, priority)
+	_, err = tx.Exec(ctx, `DELETE FROM plot_mappings WHERE battletag=$1 AND community_id=$2`, user.Battletag, user.Community.Id)
+	if err != nil {
+		log.Printf("failed to remove mappings: %v", err)
+		return err
 	}
 
-	for plotId, priority := range mappings {
+	for key, priority := range mappings {
 		_, err = tx.Exec(ctx, `
-			INSERT INTO plot_mappings (battletag, plot_id, priority)
-			VALUES ($1, $2, $3)
-			ON CONFLICT (battletag, plot_id)
-			DO UPDATE SET priority = EXCLUDED.priority
-		`, user.Battletag, plotId, priority)
+			INSERT INTO plot_mappings (battletag, community_id, neighborhood, plot_id, priority)
+			VALUES ($1, $2, $3, $4, $5)
+		`, user.Battletag, user.Community.Id, key.Neighborhood, key.Plot, priority)
 		if err != nil {
-			log.Printf("failed to save mapping %d → %d: %v", plotId, priority, err)
+			log.Printf("failed to save mapping %d:%d → %d: %v", key.Neighborhood, key.Plot, priority, err)
 			return err
 		}
 	}
+	// the next lock is optimized on the preferences, officers have to see it was based on them
+	if err := claimVersion(ctx, tx, user.Community.Id); err != nil {
+		return err
+	}
+	if err := recordAuditEvent(ctx, tx, user.Community.Id, event); err != nil {
+		log.Printf("failed to record preferences of %s: %v", user.Battletag, err)
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit transaction: %v", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *StorageClient) SaveNeighborWishes(ctx context.Context, user *model.User, neighbors []string, event *model.AuditEvent) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	status, err := lockCommunityStatus(ctx, tx, user.Community.Id)
+	if err != nil {
+		return err
+	}
+	if !status.PreferencesOpen() {
+		return &model.StatusError{Status: status, Action: "change neighbor wishes"}
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM neighbor_wishes WHERE battletag=$1 AND community_id=$2`, user.Battletag, user.Community.Id)
+	if err != nil {
+		log.Printf("failed to remove neighbor wishes: %v", err)
+		return err
+	}
+
+	// only members of the same community can be wished for
+	_, err = tx.Exec(ctx, `
+		INSERT INTO neighbor_wishes (battletag, community_id, neighbor)
+		SELECT $1, $3, battletag
+		FROM memberships
+		WHERE battletag = ANY($2) AND community_id = $3 AND battletag <> $1
+	`, user.Battletag, neighbors, user.Community.Id)
+	if err != nil {
+		log.Printf("failed to save neighbor wishes: %v", err)
+		return err
+	}
+	if err := claimVersion(ctx, tx, user.Community.Id); err != nil {
+		return err
+	}
+	if err := recordAuditEvent(ctx, tx, user.Community.Id, event); err != nil {
+		log.Printf("failed to record neighbor wishes of %s: %v", user.Battletag, err)
+		return err
+	}
 
 	if err := tx.Commit(ctx); err != nil {
 		log.Printf("failed to commit transaction: %v", err)
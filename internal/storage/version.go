@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *StorageClient) GetCommunityVersion(ctx context.Context, communityId string) (int64, error) {
+	var version int64
+	err := s.db.QueryRow(ctx, `SELECT version FROM communities WHERE id = $1`, communityId).Scan(&version)
+	if err != nil {
+		log.Printf("Failed to read version of community %s: %v", communityId, err)
+		return 0, err
+	}
+	return version, nil
+}
+
+type versionClaimKey struct{}
+
+// VersionClaim is the version an edit was based on, carried into the transactions that make it.
+// Version is what the edit moved the community to, zero while it changed nothing.
+type VersionClaim struct {
+	Expected   *int64
+	Version    int64
+	Conflicted bool
+}
+
+// WithVersionClaim lets the changes made with the returned context check the version they
+// were based on. Without an expected version they move the community on regardless.
+func WithVersionClaim(ctx context.Context, expected *int64) (context.Context, *VersionClaim) {
+	claim := &VersionClaim{Expected: expected}
+	return context.WithValue(ctx, versionClaimKey{}, claim), claim
+}
+
+// claim checks a change against the version its edit was based on and records the version it
+// moved the community to. Later changes of the same edit are based on that one.
+func (c *VersionClaim) claim(communityId string, version int64, moved bool) error {
+	if !moved {
+		if c.Expected == nil {
+			return ErrNotFound
+		}
+		c.Conflicted = true
+		log.Printf("Rejected edit of community %s based on version %d.", communityId, *c.Expected)
+		return ErrVersionConflict
+	}
+	c.Version = version
+	if c.Expected != nil {
+		c.Expected = &version
+	}
+	return nil
+}
+
+func versionClaim(ctx context.Context) *VersionClaim {
+	claim, _ := ctx.Value(versionClaimKey{}).(*VersionClaim)
+	if claim == nil {
+		return &VersionClaim{}
+	}
+	return claim
+}
+
+// claimVersion moves a community to its next version inside the transaction of a change, so a
+// change that fails never moves it. An edit with a VersionClaim only goes through at the version
+// it expects, every other change moves the version regardless.
+func claimVersion(ctx context.Context, tx pgx.Tx, communityId string) error {
+	claim := versionClaim(ctx)
+	var version int64
+	err := tx.QueryRow(ctx, `
+		UPDATE communities SET version = version + 1
+		WHERE id = $1 AND ($2::BIGINT IS NULL OR version = $2)
+		RETURNING version
+	`, communityId, claim.Expected).Scan(&version)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("Failed to move version of community %s: %v", communityId, err)
+		return err
+	}
+	return claim.claim(communityId, version, err == nil)
+}
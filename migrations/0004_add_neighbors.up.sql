@@ -0,0 +1,9 @@
+ALTER TABLE communities
+ADD COLUMN neighbor_weight INT NOT NULL DEFAULT 0;
+
+CREATE TABLE neighbor_wishes (
+    battletag VARCHAR(50) NOT NULL REFERENCES users(battletag) ON DELETE CASCADE,
+    neighbor  VARCHAR(50) NOT NULL REFERENCES users(battletag) ON DELETE CASCADE,
+    PRIMARY KEY (battletag, neighbor),
+    CHECK (battletag <> neighbor)
+);
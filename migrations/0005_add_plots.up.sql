@@ -0,0 +1,74 @@
+CREATE TABLE plots (
+    id INT PRIMARY KEY CHECK (id > 0),
+    label VARCHAR(50) NOT NULL,
+    x_coord INT NOT NULL,
+    y_coord INT NOT NULL,
+    size VARCHAR(20) NOT NULL DEFAULT 'medium',
+    plot_type VARCHAR(20) NOT NULL DEFAULT 'standard',
+    tags TEXT[] NOT NULL DEFAULT '{}'
+);
+
+INSERT INTO plots (id, label, x_coord, y_coord) VALUES
+    (1, 'Plot 1', 1258, 1898),
+    (2, 'Plot 2', 1242, 1782),
+    (3, 'Plot 3', 1244, 1680),
+    (4, 'Plot 4', 1098, 1762),
+    (5, 'Plot 5', 1042, 1698),
+    (6, 'Plot 6', 1017, 1619),
+    (7, 'Plot 7', 987, 1532),
+    (8, 'Plot 8', 1283, 1542),
+    (9, 'Plot 9', 1392, 1583),
+    (10, 'Plot 10', 1355, 1496),
+    (11, 'Plot 11', 1606, 1714),
+    (12, 'Plot 12', 1542, 1624),
+    (13, 'Plot 13', 1622, 1608),
+    (14, 'Plot 14', 1711, 1548),
+    (15, 'Plot 15', 1753, 1716),
+    (16, 'Plot 16', 1824, 1794),
+    (17, 'Plot 17', 1915, 1783),
+    (18, 'Plot 18', 1922, 1714),
+    (19, 'Plot 19', 1840, 1676),
+    (20, 'Plot 20', 1827, 1448),
+    (21, 'Plot 21', 1906, 1421),
+    (22, 'Plot 22', 1800, 1372),
+    (23, 'Plot 23', 1473, 1329),
+    (24, 'Plot 24', 1527, 1285),
+    (25, 'Plot 25', 1443, 1191),
+    (26, 'Plot 26', 1326, 1133),
+    (27, 'Plot 27', 1256, 1132),
+    (28, 'Plot 28', 1375, 1047),
+    (29, 'Plot 29', 1407, 928),
+    (30, 'Plot 30', 1496, 1067),
+    (31, 'Plot 31', 1634, 1096),
+    (32, 'Plot 32', 1654, 1018),
+    (33, 'Plot 33', 1718, 940),
+    (34, 'Plot 34', 1873, 902),
+    (35, 'Plot 35', 1968, 991),
+    (36, 'Plot 36', 2068, 834),
+    (37, 'Plot 37', 2140, 955),
+    (38, 'Plot 38', 2246, 770),
+    (39, 'Plot 39', 2342, 710),
+    (40, 'Plot 40', 2304, 903),
+    (41, 'Plot 41', 2304, 998),
+    (42, 'Plot 42', 2391, 1093),
+    (43, 'Plot 43', 2545, 1041),
+    (44, 'Plot 44', 1970, 1144),
+    (45, 'Plot 45', 2028, 1243),
+    (46, 'Plot 46', 2030, 1306),
+    (47, 'Plot 47', 2254, 1296),
+    (48, 'Plot 48', 2339, 1290),
+    (49, 'Plot 49', 2497, 1336),
+    (50, 'Plot 50', 2552, 1429),
+    (51, 'Plot 51', 2437, 1443),
+    (52, 'Plot 52', 2371, 1541),
+    (53, 'Plot 53', 2522, 1550)
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE plot_mappings
+DROP CONSTRAINT IF EXISTS plot_mappings_plot_id_check,
+DROP CONSTRAINT IF EXISTS plot_mappings_priority_check,
+ADD CONSTRAINT plot_mappings_plot_id_fkey FOREIGN KEY (plot_id) REFERENCES plots(id),
+ADD CONSTRAINT plot_mappings_priority_check CHECK (priority >= 1);
+
+ALTER TABLE assignments
+ADD CONSTRAINT assignments_plot_id_fkey FOREIGN KEY (plot_id) REFERENCES plots(id);
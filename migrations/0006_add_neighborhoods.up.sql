@@ -0,0 +1,12 @@
+ALTER TABLE communities
+ADD COLUMN neighborhoods INT NOT NULL DEFAULT 1 CHECK (neighborhoods >= 1);
+
+-- neighborhood 0 means the preference applies to the plot in every neighborhood
+ALTER TABLE plot_mappings
+ADD COLUMN neighborhood INT NOT NULL DEFAULT 0 CHECK (neighborhood >= 0),
+DROP CONSTRAINT IF EXISTS plot_mappings_battletag_plot_id_key,
+ADD CONSTRAINT plot_mappings_battletag_neighborhood_plot_id_key UNIQUE (battletag, neighborhood, plot_id);
+
+ALTER TABLE assignments
+ADD COLUMN neighborhood INT NOT NULL DEFAULT 1 CHECK (neighborhood >= 1),
+ADD CONSTRAINT assignments_community_id_neighborhood_plot_id_key UNIQUE (community_id, neighborhood, plot_id);
@@ -0,0 +1,17 @@
+CREATE TABLE plot_pins (
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    battletag VARCHAR(50) NOT NULL REFERENCES users(battletag) ON DELETE CASCADE,
+    neighborhood INT NOT NULL DEFAULT 1 CHECK (neighborhood >= 1),
+    plot_id INT NOT NULL REFERENCES plots(id),
+    PRIMARY KEY (community_id, battletag),
+    UNIQUE (community_id, neighborhood, plot_id)
+);
+
+CREATE TABLE reserved_plots (
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    neighborhood INT NOT NULL DEFAULT 1 CHECK (neighborhood >= 1),
+    plot_id INT NOT NULL REFERENCES plots(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'reserved' CHECK (status IN ('reserved', 'unavailable')),
+    note TEXT,
+    PRIMARY KEY (community_id, neighborhood, plot_id)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE communities
+ADD COLUMN move_penalty INT NOT NULL DEFAULT 0 CHECK (move_penalty >= 0);
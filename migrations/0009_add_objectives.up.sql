@@ -0,0 +1,15 @@
+ALTER TABLE communities
+ADD COLUMN objective VARCHAR(20) NOT NULL DEFAULT 'sum';
+
+CREATE TABLE optimization_runs (
+    id SERIAL PRIMARY KEY,
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    objective VARCHAR(20) NOT NULL,
+    stable BOOLEAN NOT NULL DEFAULT FALSE,
+    persisted BOOLEAN NOT NULL DEFAULT FALSE,
+    stats JSONB NOT NULL,
+    created_by VARCHAR(50),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX optimization_runs_community_idx ON optimization_runs (community_id, created_at DESC);
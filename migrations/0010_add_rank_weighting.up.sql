@@ -0,0 +1,2 @@
+ALTER TABLE communities
+ADD COLUMN rank_weighting JSONB NOT NULL DEFAULT '{"mode": "none"}';
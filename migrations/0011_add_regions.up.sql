@@ -0,0 +1,8 @@
+ALTER TABLE communities
+ADD COLUMN region VARCHAR(2) NOT NULL DEFAULT 'eu' CHECK (region IN ('eu', 'us', 'kr', 'tw')),
+DROP CONSTRAINT IF EXISTS communities_name_key,
+ADD CONSTRAINT communities_region_realm_name_key UNIQUE (region, realm, name);
+
+-- the region the user's current session was authorized against
+ALTER TABLE users
+ADD COLUMN region VARCHAR(2) NOT NULL DEFAULT 'eu' CHECK (region IN ('eu', 'us', 'kr', 'tw'));
@@ -0,0 +1,21 @@
+-- only a hash of the session token is stored, the token itself stays with the client
+CREATE TABLE sessions (
+    id SERIAL PRIMARY KEY,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    battletag VARCHAR(50) NOT NULL REFERENCES users(battletag) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX sessions_battletag_idx ON sessions (battletag);
+
+-- keep everyone who logged in before logged in
+INSERT INTO sessions (token_hash, battletag, expires_at)
+SELECT encode(digest(session_id::text, 'sha256'), 'hex'), battletag, NOW() + INTERVAL '14 days'
+FROM users
+WHERE session_id IS NOT NULL;
+
+ALTER TABLE users
+DROP COLUMN session_id;
@@ -0,0 +1,2 @@
+ALTER TABLE users
+ADD COLUMN departed_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,10 @@
+CREATE TABLE characters (
+    battletag VARCHAR(50) NOT NULL REFERENCES users(battletag) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    PRIMARY KEY (battletag, name)
+);
+
+INSERT INTO characters (battletag, name)
+SELECT battletag, char
+FROM users
+WHERE community_id IS NOT NULL AND char IS NOT NULL AND char <> '';
@@ -0,0 +1,66 @@
+CREATE TABLE memberships (
+    battletag VARCHAR(50) NOT NULL REFERENCES users(battletag) ON DELETE CASCADE,
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    char VARCHAR(50),
+    community_rank INT DEFAULT 100,
+    note TEXT,
+    departed_at TIMESTAMP WITH TIME ZONE,
+    joined_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (battletag, community_id)
+);
+
+INSERT INTO memberships (battletag, community_id, char, community_rank, note, departed_at, joined_at)
+SELECT battletag, community_id, char, community_rank, note, departed_at, COALESCE(updated_at, NOW())
+FROM users
+WHERE community_id IS NOT NULL;
+
+-- everything a member states or holds belongs to one membership, not to the account
+ALTER TABLE characters
+ADD COLUMN community_id UUID;
+UPDATE characters ch SET community_id = u.community_id FROM users u WHERE u.battletag = ch.battletag;
+DELETE FROM characters WHERE community_id IS NULL;
+ALTER TABLE characters
+ALTER COLUMN community_id SET NOT NULL,
+DROP CONSTRAINT characters_pkey,
+ADD PRIMARY KEY (battletag, community_id, name),
+ADD CONSTRAINT characters_membership_fkey FOREIGN KEY (battletag, community_id)
+    REFERENCES memberships(battletag, community_id) ON DELETE CASCADE;
+
+ALTER TABLE plot_mappings
+ADD COLUMN community_id UUID;
+UPDATE plot_mappings pm SET community_id = u.community_id FROM users u WHERE u.battletag = pm.battletag;
+DELETE FROM plot_mappings WHERE community_id IS NULL;
+ALTER TABLE plot_mappings
+ALTER COLUMN community_id SET NOT NULL,
+DROP CONSTRAINT IF EXISTS plot_mappings_battletag_neighborhood_plot_id_key,
+DROP CONSTRAINT IF EXISTS plot_mappings_battletag_priority_key,
+ADD CONSTRAINT plot_mappings_membership_neighborhood_plot_id_key UNIQUE (battletag, community_id, neighborhood, plot_id),
+ADD CONSTRAINT plot_mappings_membership_priority_key UNIQUE (battletag, community_id, priority),
+ADD CONSTRAINT plot_mappings_membership_fkey FOREIGN KEY (battletag, community_id)
+    REFERENCES memberships(battletag, community_id) ON DELETE CASCADE;
+
+ALTER TABLE neighbor_wishes
+ADD COLUMN community_id UUID;
+UPDATE neighbor_wishes nw SET community_id = u.community_id FROM users u WHERE u.battletag = nw.battletag;
+DELETE FROM neighbor_wishes WHERE community_id IS NULL;
+ALTER TABLE neighbor_wishes
+ALTER COLUMN community_id SET NOT NULL,
+DROP CONSTRAINT neighbor_wishes_pkey,
+ADD PRIMARY KEY (battletag, community_id, neighbor),
+ADD CONSTRAINT neighbor_wishes_membership_fkey FOREIGN KEY (battletag, community_id)
+    REFERENCES memberships(battletag, community_id) ON DELETE CASCADE,
+ADD CONSTRAINT neighbor_wishes_neighbor_membership_fkey FOREIGN KEY (neighbor, community_id)
+    REFERENCES memberships(battletag, community_id) ON DELETE CASCADE;
+
+DELETE FROM assignments WHERE community_id IS NULL;
+ALTER TABLE assignments
+ALTER COLUMN community_id SET NOT NULL,
+DROP CONSTRAINT IF EXISTS assignments_battletag_key,
+ADD CONSTRAINT assignments_community_id_battletag_key UNIQUE (community_id, battletag);
+
+ALTER TABLE users
+DROP COLUMN community_id,
+DROP COLUMN char,
+DROP COLUMN community_rank,
+DROP COLUMN note,
+DROP COLUMN departed_at;
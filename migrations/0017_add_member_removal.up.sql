@@ -0,0 +1,20 @@
+CREATE TABLE audit_events (
+    id SERIAL PRIMARY KEY,
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    actor VARCHAR(50) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    target VARCHAR(50),
+    detail TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX audit_events_community_idx ON audit_events (community_id, created_at);
+
+-- a removed member can't join again before the tombstone expires, it outlives the account on purpose
+CREATE TABLE membership_tombstones (
+    battletag VARCHAR(50) NOT NULL,
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    removed_by VARCHAR(50) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (battletag, community_id)
+);
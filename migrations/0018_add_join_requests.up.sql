@@ -0,0 +1,18 @@
+ALTER TABLE communities
+ADD COLUMN join_requests BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE join_requests (
+    battletag VARCHAR(50) NOT NULL REFERENCES users(battletag) ON DELETE CASCADE,
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    char VARCHAR(50),
+    community_rank INT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'denied')),
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    decided_by VARCHAR(50),
+    decided_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (battletag, community_id)
+);
+
+-- members an officer let in stay members when they are missing from the roster
+ALTER TABLE memberships
+ADD COLUMN approved_by VARCHAR(50);
@@ -0,0 +1,6 @@
+ALTER TABLE audit_events
+ADD COLUMN before JSONB,
+ADD COLUMN after JSONB;
+
+CREATE INDEX audit_events_actor_idx ON audit_events (community_id, actor);
+CREATE INDEX audit_events_target_idx ON audit_events (community_id, target);
@@ -0,0 +1,23 @@
+-- every lock, upload, edit and restore keeps the assignments it produced
+CREATE TABLE assignment_revisions (
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    revision INT NOT NULL,
+    source VARCHAR(20) NOT NULL CHECK (source IN ('lock', 'upload', 'edit', 'restore')),
+    created_by VARCHAR(50),
+    restored_from INT,
+    assignments JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (community_id, revision)
+);
+
+-- whatever is assigned today becomes the first revision
+INSERT INTO assignment_revisions (community_id, revision, source, assignments)
+SELECT community_id, 1, 'lock', jsonb_agg(jsonb_build_object(
+    'char', char,
+    'btag', battletag,
+    'neighborhood', neighborhood,
+    'plot', plot_id,
+    'score', plot_score
+) ORDER BY neighborhood, plot_id)
+FROM assignments
+GROUP BY community_id;
@@ -0,0 +1,7 @@
+ALTER TABLE communities
+ADD COLUMN preference_deadline TIMESTAMP WITH TIME ZONE,
+-- set once the scheduler handled the deadline, cleared when a new deadline is set
+ADD COLUMN deadline_locked_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX communities_preference_deadline_idx ON communities (preference_deadline)
+WHERE preference_deadline IS NOT NULL AND deadline_locked_at IS NULL;
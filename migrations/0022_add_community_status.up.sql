@@ -0,0 +1,15 @@
+ALTER TABLE communities
+ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'collecting'
+    CHECK (status IN ('collecting', 'locked', 'finalized', 'archived'));
+
+-- finalized was nullable and toggled on its own, a finalized community always counts as locked
+UPDATE communities
+SET status = CASE
+    WHEN COALESCE(finalized, false) THEN 'finalized'
+    WHEN COALESCE(locked, false) THEN 'locked'
+    ELSE 'collecting'
+END;
+
+ALTER TABLE communities
+DROP COLUMN locked,
+DROP COLUMN finalized;
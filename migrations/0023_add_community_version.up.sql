@@ -0,0 +1,2 @@
+ALTER TABLE communities
+ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,16 @@
+-- character names are only unique within a realm
+ALTER TABLE characters
+ADD COLUMN realm VARCHAR(50) NOT NULL DEFAULT '';
+UPDATE characters ch SET realm = c.realm FROM communities c WHERE c.id = ch.community_id;
+ALTER TABLE characters
+ALTER COLUMN realm DROP DEFAULT,
+DROP CONSTRAINT characters_pkey,
+ADD PRIMARY KEY (battletag, community_id, name, realm);
+
+-- members added by an officer or an upload never joined from the roster
+ALTER TABLE memberships
+ADD COLUMN manual BOOLEAN NOT NULL DEFAULT false;
+UPDATE memberships m SET manual = true
+WHERE NOT EXISTS (
+    SELECT 1 FROM characters ch WHERE ch.battletag = m.battletag AND ch.community_id = m.community_id
+);
@@ -0,0 +1,4 @@
+-- removing a member releases their assignment, which is a revision of its own
+ALTER TABLE assignment_revisions
+DROP CONSTRAINT assignment_revisions_source_check,
+ADD CONSTRAINT assignment_revisions_source_check CHECK (source IN ('lock', 'upload', 'edit', 'restore', 'removal'));